@@ -8,6 +8,7 @@ require (
 	github.com/likexian/whois v1.15.6
 	github.com/mark3labs/mcp-go v0.38.0
 	github.com/miekg/dns v1.1.68
+	github.com/quic-go/quic-go v0.54.1
 	github.com/shynome/doh-client v1.2.0
 	github.com/stretchr/testify v1.10.0
 	golang.org/x/net v0.43.0
@@ -25,8 +26,11 @@ require (
 	github.com/spf13/cast v1.7.1 // indirect
 	github.com/wk8/go-ordered-map/v2 v2.1.8 // indirect
 	github.com/yosida95/uritemplate/v3 v3.0.2 // indirect
-	golang.org/x/mod v0.24.0 // indirect
+	go.uber.org/mock v0.5.0 // indirect
+	golang.org/x/crypto v0.41.0 // indirect
+	golang.org/x/mod v0.26.0 // indirect
 	golang.org/x/sys v0.35.0 // indirect
-	golang.org/x/tools v0.33.0 // indirect
+	golang.org/x/text v0.28.0 // indirect
+	golang.org/x/tools v0.35.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
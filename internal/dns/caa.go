@@ -0,0 +1,422 @@
+package dns
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/miekg/dns"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/domainname"
+	resp "github.com/patrickdappollonio/mcp-domaintools/internal/response"
+	internaltls "github.com/patrickdappollonio/mcp-domaintools/internal/tls"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/utils"
+)
+
+// CAA property tags defined by RFC 8659.
+const (
+	caaTagIssue     = "issue"
+	caaTagIssueWild = "issuewild"
+	caaTagIodef     = "iodef"
+)
+
+// caaCriticalFlag is the issuer critical flag: a CA must not issue when it
+// finds a property with this flag and a tag it doesn't understand.
+const caaCriticalFlag = 128
+
+// Outcomes of checking a certificate issuer against the CAA policy.
+const (
+	caaIssuerAllowed    = "allowed"
+	caaIssuerNotAllowed = "not_allowed"
+	caaIssuerUnknown    = "unknown_ca"
+)
+
+// caaKnownTags are the property tags understood when evaluating a critical
+// flag: the RFC 8659 tags plus the ones registered later.
+var caaKnownTags = []string{caaTagIssue, caaTagIssueWild, caaTagIodef, "issuemail", "issuevmc", "contactemail", "contactphone"}
+
+// caaIssuerDomains maps the exact organization of a certificate issuer to
+// the domains that CA recognizes in CAA records. Several CAs accept more than
+// one domain, usually those of the brands they acquired. Only mappings taken
+// from each CA's own documentation are listed, as of 2026-10: resellers and
+// CDNs such as Cloudflare issue through CAs that change over time, so their
+// certificates are reported as unknown rather than guessed.
+var caaIssuerDomains = []struct {
+	Organization string
+	Domains      []string
+}{
+	{"Let's Encrypt", []string{"letsencrypt.org"}},
+	{"Google Trust Services", []string{"pki.goog"}},
+	{"Google Trust Services LLC", []string{"pki.goog"}},
+	{"DigiCert Inc", []string{"digicert.com", "www.digicert.com", "symantec.com", "thawte.com", "geotrust.com", "rapidssl.com"}},
+	{"Sectigo Limited", []string{"sectigo.com", "comodoca.com", "comodo.com", "usertrust.com", "trust-provider.com"}},
+	{"COMODO CA Limited", []string{"sectigo.com", "comodoca.com", "comodo.com", "usertrust.com", "trust-provider.com"}},
+	{"Amazon", []string{"amazon.com", "amazontrust.com", "awstrust.com", "amazonaws.com"}},
+	{"GlobalSign nv-sa", []string{"globalsign.com"}},
+	{"GoDaddy.com, Inc.", []string{"godaddy.com", "starfieldtech.com"}},
+	{"Starfield Technologies, Inc.", []string{"godaddy.com", "starfieldtech.com"}},
+	{"SSL Corporation", []string{"ssl.com"}},
+	{"Certainly", []string{"certainly.com"}},
+	{"IdenTrust", []string{"identrust.com"}},
+}
+
+// caaCheckParams represents the parameters for CAA checks.
+type caaCheckParams struct {
+	Domain           string `json:"domain"`
+	CheckCertificate bool   `json:"check_certificate"`
+	Port             int    `json:"port"`
+}
+
+// CAALookup represents the CAA query for one name of the tree walk.
+type CAALookup struct {
+	Name    string `json:"name"`
+	Rcode   string `json:"rcode,omitempty"`
+	Records int    `json:"records"`
+	Alias   string `json:"alias,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// CAAProperty represents a parsed CAA record.
+type CAAProperty struct {
+	Flag       uint8             `json:"flag"`
+	Critical   bool              `json:"critical"`
+	Tag        string            `json:"tag"`
+	Value      string            `json:"value"`
+	Issuer     string            `json:"issuer,omitempty"`
+	Parameters map[string]string `json:"parameters,omitempty"`
+	Issues     []string          `json:"issues,omitempty"`
+}
+
+// CAACertificateCheck represents the check of the certificate currently
+// served against the CAA policy.
+type CAACertificateCheck struct {
+	Host       string   `json:"host"`
+	Port       int      `json:"port"`
+	Subject    string   `json:"subject,omitempty"`
+	Issuer     string   `json:"issuer,omitempty"`
+	DNSNames   []string `json:"dns_names,omitempty"`
+	Wildcard   bool     `json:"wildcard"`
+	CAADomains []string `json:"caa_domains,omitempty"`
+	Status     string   `json:"status,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// CAACheckResponse represents the complete CAA check response.
+type CAACheckResponse struct {
+	Domain                 string               `json:"domain"`
+	DomainName             *domainname.Name     `json:"domain_name,omitempty"`
+	Wildcard               bool                 `json:"wildcard"`
+	RelevantName           string               `json:"relevant_name,omitempty"`
+	Lookups                []CAALookup          `json:"lookups"`
+	Properties             []CAAProperty        `json:"properties"`
+	AnyCAMayIssue          bool                 `json:"any_ca_may_issue"`
+	AllowedIssuers         []string             `json:"allowed_issuers"`
+	AnyCAMayIssueWild      bool                 `json:"any_ca_may_issue_wildcard"`
+	AllowedWildcardIssuers []string             `json:"allowed_wildcard_issuers"`
+	IodefURLs              []string             `json:"iodef_urls,omitempty"`
+	Certificate            *CAACertificateCheck `json:"certificate,omitempty"`
+	Issues                 []string             `json:"issues,omitempty"`
+	Timestamp              string               `json:"timestamp"`
+}
+
+// HandleCAACheck walks the CAA tree for a name the way a CA must before
+// issuing, reports which CAs may issue, and optionally checks that the issuer
+// of the certificate currently served is one of them.
+func HandleCAACheck(ctx context.Context, request mcp.CallToolRequest, config *QueryConfig) (*mcp.CallToolResult, error) {
+	var params caaCheckParams
+	if err := request.BindArguments(&params); err != nil {
+		return nil, fmt.Errorf("failed to parse tool input: %w", utils.ParseJSONUnmarshalError(err))
+	}
+
+	// Validate required parameters
+	if params.Domain == "" {
+		return nil, fmt.Errorf("parameter \"domain\" is required")
+	}
+
+	// Validate and normalize the domain name
+	name, err := domainname.Parse(params.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	// A wildcard name is checked at its parent, with the wildcard rules
+	domain, wildcard := strings.CutPrefix(name.ASCII, "*.")
+
+	port := params.Port
+	if port == 0 {
+		port = 443
+	}
+
+	// Get DNS servers once, rather than once per name
+	servers, err := getSystemDNSServers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get DNS servers: %w", err)
+	}
+
+	result := evaluateCAA(ctx, dns.Fqdn(domain), wildcard, servers, config.Timeout)
+	result.DomainName = name
+
+	if params.CheckCertificate {
+		check := &CAACertificateCheck{Host: domain, Port: port}
+
+		certificates, err := internaltls.FetchCertificates(ctx, domain, port, domain, config.Timeout)
+		if err != nil {
+			check.Error = err.Error()
+		} else {
+			checkCertificateIssuer(result, check, certificates[0])
+		}
+		result.Certificate = check
+	}
+
+	return resp.JSON(result)
+}
+
+// evaluateCAA finds the relevant CAA RRset, which is the one of the closest
+// name, starting at the domain and climbing to its parents (RFC 8659 section
+// 3), and evaluates the properties it contains.
+func evaluateCAA(ctx context.Context, domain string, wildcard bool, servers []string, timeout time.Duration) *CAACheckResponse {
+	result := &CAACheckResponse{
+		Domain:                 domain,
+		Wildcard:               wildcard,
+		Lookups:                make([]CAALookup, 0),
+		Properties:             make([]CAAProperty, 0),
+		AllowedIssuers:         make([]string, 0),
+		AllowedWildcardIssuers: make([]string, 0),
+		Timestamp:              time.Now().Format(time.RFC3339),
+	}
+
+	var records []*dns.CAA
+	labels := dns.SplitDomainName(domain)
+	for i := range labels {
+		name := dns.Fqdn(strings.Join(labels[i:], "."))
+		lookup := CAALookup{Name: name}
+
+		m := new(dns.Msg)
+		m.SetQuestion(name, dns.TypeCAA)
+		m.RecursionDesired = true
+		m.SetEdns0(4096, false)
+
+		answered, err := queryServers(ctx, m, servers, timeout)
+		if err != nil {
+			lookup.Error = err.Error()
+			result.Lookups = append(result.Lookups, lookup)
+			result.Issues = append(result.Issues, fmt.Sprintf("CAA lookup for %s failed, and CAs must not issue when the lookup fails: %s", name, err))
+			return result
+		}
+
+		lookup.Rcode = dns.RcodeToString[answered.Response.Rcode]
+		if answered.Response.Rcode != dns.RcodeSuccess && answered.Response.Rcode != dns.RcodeNameError {
+			result.Lookups = append(result.Lookups, lookup)
+			result.Issues = append(result.Issues, fmt.Sprintf("CAA lookup for %s returned %s, and CAs must not issue when the lookup fails", name, lookup.Rcode))
+			return result
+		}
+
+		// Aliases are followed by the resolver, so the CAA records may be
+		// owned by the target of a CNAME
+		for _, rr := range answered.Response.Answer {
+			switch rec := rr.(type) {
+			case *dns.CNAME:
+				lookup.Alias = rec.Target
+			case *dns.CAA:
+				records = append(records, rec)
+			}
+		}
+
+		lookup.Records = len(records)
+		result.Lookups = append(result.Lookups, lookup)
+
+		if len(records) > 0 {
+			result.RelevantName = name
+			break
+		}
+	}
+
+	evaluateCAAProperties(result, records)
+	return result
+}
+
+// evaluateCAAProperties parses the properties of the relevant CAA RRset and
+// determines which CAs may issue regular and wildcard certificates.
+func evaluateCAAProperties(result *CAACheckResponse, records []*dns.CAA) {
+	if len(records) == 0 {
+		result.AnyCAMayIssue = true
+		result.AnyCAMayIssueWild = true
+		result.Issues = append(result.Issues, "no CAA records found for the name or its parents, so any CA may issue certificates")
+		return
+	}
+
+	var issue, issueWild []CAAProperty
+	criticalUnknown := false
+
+	for _, record := range records {
+		property := parseCAAProperty(record)
+		result.Properties = append(result.Properties, property)
+
+		switch property.Tag {
+		case caaTagIssue:
+			issue = append(issue, property)
+		case caaTagIssueWild:
+			issueWild = append(issueWild, property)
+		case caaTagIodef:
+			result.IodefURLs = append(result.IodefURLs, property.Value)
+		default:
+			if property.Critical && !slices.Contains(caaKnownTags, property.Tag) {
+				criticalUnknown = true
+			}
+		}
+	}
+
+	if criticalUnknown {
+		result.Issues = append(result.Issues, "a property with an unknown tag is marked critical, so no CA may issue certificates")
+		return
+	}
+
+	// Wildcard certificates use the issuewild properties when there are
+	// any, and the issue properties otherwise (RFC 8659 section 4.3)
+	if len(issueWild) == 0 {
+		issueWild = issue
+	}
+
+	result.AnyCAMayIssue = len(issue) == 0
+	result.AllowedIssuers = caaIssuers(issue)
+	result.AnyCAMayIssueWild = len(issueWild) == 0
+	result.AllowedWildcardIssuers = caaIssuers(issueWild)
+
+	if !result.AnyCAMayIssue && len(result.AllowedIssuers) == 0 {
+		result.Issues = append(result.Issues, "the CAA records forbid issuing certificates for this name")
+	}
+
+	if !result.AnyCAMayIssueWild && len(result.AllowedWildcardIssuers) == 0 {
+		result.Issues = append(result.Issues, "the CAA records forbid issuing wildcard certificates for this name")
+	}
+}
+
+// parseCAAProperty parses a CAA record. The value of the issue and issuewild
+// properties is an issuer domain, which may be empty to forbid issuance,
+// followed by semicolon-separated "key=value" parameters.
+func parseCAAProperty(record *dns.CAA) CAAProperty {
+	property := CAAProperty{
+		Flag:     record.Flag,
+		Critical: record.Flag&caaCriticalFlag != 0,
+		Tag:      strings.ToLower(record.Tag),
+		Value:    record.Value,
+	}
+
+	switch property.Tag {
+	case caaTagIssue, caaTagIssueWild:
+		issuer, parameters, _ := strings.Cut(record.Value, ";")
+		property.Issuer = strings.ToLower(strings.TrimSpace(issuer))
+
+		for _, parameter := range strings.Split(parameters, ";") {
+			parameter = strings.TrimSpace(parameter)
+			if parameter == "" {
+				continue
+			}
+
+			key, value, ok := strings.Cut(parameter, "=")
+			if !ok || strings.TrimSpace(key) == "" {
+				property.Issues = append(property.Issues, fmt.Sprintf("malformed parameter %q, expected key=value", parameter))
+				continue
+			}
+
+			if property.Parameters == nil {
+				property.Parameters = make(map[string]string)
+			}
+			property.Parameters[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+
+		if property.Issuer != "" {
+			if _, ok := dns.IsDomainName(property.Issuer); !ok || strings.ContainsAny(property.Issuer, " \t") {
+				property.Issues = append(property.Issues, fmt.Sprintf("issuer %q is not a valid domain name", property.Issuer))
+			}
+		}
+
+	case caaTagIodef:
+		lower := strings.ToLower(record.Value)
+		if !strings.HasPrefix(lower, "mailto:") && !strings.HasPrefix(lower, "https://") && !strings.HasPrefix(lower, "http://") {
+			property.Issues = append(property.Issues, "iodef value should be a mailto:, http:// or https:// URL")
+		}
+	}
+
+	return property
+}
+
+// caaIssuers returns the sorted, unique issuer domains of the properties,
+// leaving out the empty ones that forbid issuance.
+func caaIssuers(properties []CAAProperty) []string {
+	issuers := make([]string, 0, len(properties))
+	for _, property := range properties {
+		if property.Issuer != "" {
+			issuers = append(issuers, property.Issuer)
+		}
+	}
+	slices.Sort(issuers)
+	return slices.Compact(issuers)
+}
+
+// checkCertificateIssuer checks whether the CA that issued the certificate
+// is allowed by the CAA policy, using the wildcard rules when the certificate
+// only covers the domain through a wildcard name.
+func checkCertificateIssuer(result *CAACheckResponse, check *CAACertificateCheck, certificate *x509.Certificate) {
+	check.Subject = certificate.Subject.String()
+	check.Issuer = certificate.Issuer.String()
+	check.DNSNames = certificate.DNSNames
+	check.Wildcard = result.Wildcard || coveredByWildcard(certificate.DNSNames, result.Domain)
+	check.CAADomains = caaDomainsForIssuer(certificate.Issuer.Organization)
+
+	anyCA, allowed := result.AnyCAMayIssue, result.AllowedIssuers
+	if check.Wildcard {
+		anyCA, allowed = result.AnyCAMayIssueWild, result.AllowedWildcardIssuers
+	}
+
+	switch {
+	case anyCA:
+		check.Status = caaIssuerAllowed
+	case len(check.CAADomains) == 0:
+		check.Status = caaIssuerUnknown
+		result.Issues = append(result.Issues, fmt.Sprintf("the CAA domain of the certificate issuer %q is not known, so it can't be checked against %s", check.Issuer, strings.Join(allowed, ", ")))
+	case slices.ContainsFunc(check.CAADomains, func(domain string) bool { return slices.Contains(allowed, domain) }):
+		check.Status = caaIssuerAllowed
+	default:
+		check.Status = caaIssuerNotAllowed
+		result.Issues = append(result.Issues, fmt.Sprintf("the current certificate was issued by %s (%s), which the CAA records don't allow; renewing it with the same CA will fail", check.Issuer, strings.Join(check.CAADomains, ", ")))
+	}
+}
+
+// coveredByWildcard reports whether the certificate names only cover the
+// domain through a wildcard.
+func coveredByWildcard(names []string, domain string) bool {
+	domain = strings.TrimSuffix(domain, ".")
+	wildcard := false
+
+	for _, name := range names {
+		if strings.EqualFold(name, domain) {
+			return false
+		}
+
+		if parent, ok := strings.CutPrefix(name, "*."); ok {
+			if _, rest, found := strings.Cut(domain, "."); found && strings.EqualFold(parent, rest) {
+				wildcard = true
+			}
+		}
+	}
+
+	return wildcard
+}
+
+// caaDomainsForIssuer returns the CAA domains recognized by the CA with the
+// given organization names. Names must match exactly, since a partial match
+// could map an unrelated CA and report a wrong not_allowed status.
+func caaDomainsForIssuer(organizations []string) []string {
+	for _, organization := range organizations {
+		for _, ca := range caaIssuerDomains {
+			if strings.TrimSpace(organization) == ca.Organization {
+				return ca.Domains
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package dns
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCAAProperty(t *testing.T) {
+	records := mustRRs(t,
+		`example.com. 300 IN CAA 0 issue "letsencrypt.org; validationmethods=dns-01; accounturi=https://acme-v02.api.letsencrypt.org/acct/1"`,
+		`example.com. 300 IN CAA 0 issuewild ";"`,
+		`example.com. 300 IN CAA 128 ISSUE "pki.goog; broken"`,
+		`example.com. 300 IN CAA 0 iodef "security@example.com"`,
+	)
+
+	property := parseCAAProperty(records[0].(*dns.CAA))
+	assert.Equal(t, caaTagIssue, property.Tag)
+	assert.Equal(t, "letsencrypt.org", property.Issuer)
+	assert.Equal(t, map[string]string{
+		"validationmethods": "dns-01",
+		"accounturi":        "https://acme-v02.api.letsencrypt.org/acct/1",
+	}, property.Parameters)
+	assert.Empty(t, property.Issues)
+
+	property = parseCAAProperty(records[1].(*dns.CAA))
+	assert.Equal(t, caaTagIssueWild, property.Tag)
+	assert.Empty(t, property.Issuer)
+	assert.Empty(t, property.Parameters)
+
+	property = parseCAAProperty(records[2].(*dns.CAA))
+	assert.True(t, property.Critical)
+	assert.Equal(t, caaTagIssue, property.Tag)
+	assert.Equal(t, "pki.goog", property.Issuer)
+	assert.Equal(t, []string{`malformed parameter "broken", expected key=value`}, property.Issues)
+
+	property = parseCAAProperty(records[3].(*dns.CAA))
+	assert.Equal(t, []string{"iodef value should be a mailto:, http:// or https:// URL"}, property.Issues)
+}
+
+func TestEvaluateCAA(t *testing.T) {
+	servers := []string{startZoneServer(t, mustRRs(t,
+		`example.com. 300 IN CAA 0 issue "letsencrypt.org"`,
+		`example.com. 300 IN CAA 0 issue "pki.goog; validationmethods=dns-01"`,
+		`example.com. 300 IN CAA 0 issuewild ";"`,
+		`example.com. 300 IN CAA 0 iodef "mailto:security@example.com"`,
+		"shop.example.com. 300 IN CNAME shops.example.net.",
+		`shops.example.net. 300 IN CAA 0 issue "digicert.com"`,
+		`locked.example.com. 300 IN CAA 128 tbs "unknown"`,
+		"api.example.com. 300 IN A 192.0.2.1",
+	))}
+
+	t.Run("inherited from the parent", func(t *testing.T) {
+		result := evaluateCAA(context.Background(), "www.api.example.com.", false, servers, 2*time.Second)
+
+		require.Len(t, result.Lookups, 3)
+		assert.Equal(t, "NXDOMAIN", result.Lookups[0].Rcode)
+		assert.Equal(t, "NOERROR", result.Lookups[1].Rcode)
+		assert.Equal(t, 4, result.Lookups[2].Records)
+		assert.Equal(t, "example.com.", result.RelevantName)
+
+		assert.False(t, result.AnyCAMayIssue)
+		assert.Equal(t, []string{"letsencrypt.org", "pki.goog"}, result.AllowedIssuers)
+		assert.False(t, result.AnyCAMayIssueWild)
+		assert.Empty(t, result.AllowedWildcardIssuers)
+		assert.Equal(t, []string{"mailto:security@example.com"}, result.IodefURLs)
+		assert.Equal(t, []string{"the CAA records forbid issuing wildcard certificates for this name"}, result.Issues)
+	})
+
+	t.Run("followed through an alias", func(t *testing.T) {
+		result := evaluateCAA(context.Background(), "shop.example.com.", false, servers, 2*time.Second)
+
+		require.Len(t, result.Lookups, 1)
+		assert.Equal(t, "shops.example.net.", result.Lookups[0].Alias)
+		assert.Equal(t, []string{"digicert.com"}, result.AllowedIssuers)
+
+		// Without issuewild properties, wildcards follow the issue properties
+		assert.Equal(t, []string{"digicert.com"}, result.AllowedWildcardIssuers)
+	})
+
+	t.Run("unknown critical property", func(t *testing.T) {
+		result := evaluateCAA(context.Background(), "locked.example.com.", false, servers, 2*time.Second)
+		assert.False(t, result.AnyCAMayIssue)
+		assert.Empty(t, result.AllowedIssuers)
+		assert.Contains(t, result.Issues, "a property with an unknown tag is marked critical, so no CA may issue certificates")
+	})
+
+	t.Run("no records", func(t *testing.T) {
+		result := evaluateCAA(context.Background(), "example.org.", false, servers, 2*time.Second)
+		assert.Len(t, result.Lookups, 2)
+		assert.Empty(t, result.RelevantName)
+		assert.True(t, result.AnyCAMayIssue)
+		assert.True(t, result.AnyCAMayIssueWild)
+	})
+}
+
+func TestCheckCertificateIssuer(t *testing.T) {
+	certificate := func(organization string, names ...string) *x509.Certificate {
+		return &x509.Certificate{
+			Subject:  pkix.Name{CommonName: names[0]},
+			Issuer:   pkix.Name{CommonName: "Test CA", Organization: []string{organization}},
+			DNSNames: names,
+		}
+	}
+
+	policy := func() *CAACheckResponse {
+		return &CAACheckResponse{
+			Domain:                 "www.example.com.",
+			AllowedIssuers:         []string{"letsencrypt.org"},
+			AllowedWildcardIssuers: []string{"pki.goog"},
+		}
+	}
+
+	t.Run("allowed", func(t *testing.T) {
+		result, check := policy(), &CAACertificateCheck{}
+		checkCertificateIssuer(result, check, certificate("Let's Encrypt", "www.example.com"))
+		assert.Equal(t, caaIssuerAllowed, check.Status)
+		assert.Equal(t, []string{"letsencrypt.org"}, check.CAADomains)
+		assert.False(t, check.Wildcard)
+		assert.Empty(t, result.Issues)
+	})
+
+	t.Run("wildcard rules apply", func(t *testing.T) {
+		result, check := policy(), &CAACertificateCheck{}
+		checkCertificateIssuer(result, check, certificate("Let's Encrypt", "*.example.com", "example.com"))
+		assert.True(t, check.Wildcard)
+		assert.Equal(t, caaIssuerNotAllowed, check.Status)
+		assert.Len(t, result.Issues, 1)
+	})
+
+	t.Run("not allowed", func(t *testing.T) {
+		result, check := policy(), &CAACertificateCheck{}
+		checkCertificateIssuer(result, check, certificate("DigiCert Inc", "www.example.com"))
+		assert.Equal(t, caaIssuerNotAllowed, check.Status)
+		assert.Contains(t, result.Issues[0], "renewing it with the same CA will fail")
+	})
+
+	t.Run("unknown ca", func(t *testing.T) {
+		result, check := policy(), &CAACertificateCheck{}
+		checkCertificateIssuer(result, check, certificate("Example Private CA", "www.example.com"))
+		assert.Equal(t, caaIssuerUnknown, check.Status)
+	})
+
+	t.Run("uncertain mappings are unknown", func(t *testing.T) {
+		for _, organization := range []string{"Cloudflare, Inc.", "Let's Encrypt Staging", "Not DigiCert Inc"} {
+			result, check := policy(), &CAACertificateCheck{}
+			checkCertificateIssuer(result, check, certificate(organization, "www.example.com"))
+			assert.Equal(t, caaIssuerUnknown, check.Status, organization)
+			assert.Empty(t, check.CAADomains, organization)
+			require.Len(t, result.Issues, 1, organization)
+			assert.Contains(t, result.Issues[0], "is not known", organization)
+		}
+	})
+}
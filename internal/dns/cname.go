@@ -0,0 +1,316 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/miekg/dns"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/domainname"
+	resp "github.com/patrickdappollonio/mcp-domaintools/internal/response"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/utils"
+)
+
+// maxCNAMEHops limits how many aliases a chain may have before it's reported
+// as too long. Resolvers give up on long chains, often at 8 to 16 hops.
+const maxCNAMEHops = 8
+
+// cnameConflictTypes are the record types checked at a CNAME owner, since a
+// CNAME must not coexist with any other data (RFC 1034 section 3.6.2).
+var cnameConflictTypes = []uint16{dns.TypeSOA, dns.TypeNS, dns.TypeMX, dns.TypeTXT}
+
+// cnameChainParams represents the parameters for CNAME chain resolution.
+type cnameChainParams struct {
+	Domain string `json:"domain"`
+}
+
+// CNAMEHop represents a single alias in a CNAME chain. The hop is answered by
+// a recursive resolver, so its TTL is the resolver's cached, remaining TTL,
+// which is at most the one set by the zone.
+type CNAMEHop struct {
+	Name     string   `json:"name"`
+	Type     string   `json:"type"`
+	Owner    string   `json:"owner,omitempty"`
+	Target   string   `json:"target"`
+	TTL      uint32   `json:"ttl"`
+	Resolver string   `json:"resolver"`
+	RTT      float64  `json:"rtt_ms"`
+	Issues   []string `json:"issues,omitempty"`
+}
+
+// CNAMEAddress represents an address record at the end of a CNAME chain, with
+// the TTL cached by the resolver that answered.
+type CNAMEAddress struct {
+	Type     string `json:"type"`
+	Address  string `json:"address"`
+	TTL      uint32 `json:"ttl"`
+	Resolver string `json:"resolver"`
+}
+
+// CNAMEChainResponse represents the complete CNAME chain response.
+type CNAMEChainResponse struct {
+	Domain       string           `json:"domain"`
+	DomainName   *domainname.Name `json:"domain_name,omitempty"`
+	FinalName    string           `json:"final_name"`
+	Rcode        string           `json:"rcode,omitempty"`
+	Hops         []CNAMEHop       `json:"hops"`
+	Addresses    []CNAMEAddress   `json:"addresses"`
+	Chain        string           `json:"chain"`
+	LimitingTTL  uint32           `json:"limiting_ttl"`
+	LimitingName string           `json:"limiting_name,omitempty"`
+	Loop         bool             `json:"loop"`
+	TooLong      bool             `json:"too_long"`
+	Issues       []string         `json:"issues,omitempty"`
+	Timestamp    string           `json:"timestamp"`
+}
+
+// HandleCNAMEChain follows a name through every CNAME and DNAME alias to its
+// final A and AAAA records, querying each hop separately.
+func HandleCNAMEChain(ctx context.Context, request mcp.CallToolRequest, config *QueryConfig) (*mcp.CallToolResult, error) {
+	var params cnameChainParams
+	if err := request.BindArguments(&params); err != nil {
+		return nil, fmt.Errorf("failed to parse tool input: %w", utils.ParseJSONUnmarshalError(err))
+	}
+
+	// Validate required parameters
+	if params.Domain == "" {
+		return nil, fmt.Errorf("parameter \"domain\" is required")
+	}
+
+	// Validate and normalize the domain name
+	name, err := domainname.Parse(params.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get DNS servers once, rather than once per hop
+	servers, err := getSystemDNSServers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get DNS servers: %w", err)
+	}
+
+	result := resolveCNAMEChain(ctx, name.FQDN(), servers, config.Timeout)
+	result.DomainName = name
+	return resp.JSON(result)
+}
+
+// resolveCNAMEChain walks the aliases of a name one hop at a time, querying
+// the CNAME type so the resolver doesn't follow the chain itself, and then
+// resolves the addresses of the final name.
+func resolveCNAMEChain(ctx context.Context, domain string, servers []string, timeout time.Duration) *CNAMEChainResponse {
+	result := &CNAMEChainResponse{
+		Domain:    domain,
+		Hops:      make([]CNAMEHop, 0),
+		Addresses: make([]CNAMEAddress, 0),
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	query := func(name string, qtype uint16) (*serverResponse, error) {
+		m := new(dns.Msg)
+		m.SetQuestion(name, qtype)
+		m.RecursionDesired = true
+		m.SetEdns0(4096, false)
+		return queryServers(ctx, m, servers, timeout)
+	}
+
+	name := domain
+	visited := map[string]bool{}
+	for {
+		if visited[dns.CanonicalName(name)] {
+			result.Loop = true
+			result.Issues = append(result.Issues, fmt.Sprintf("alias loop detected: %s is reached again", name))
+			break
+		}
+		visited[dns.CanonicalName(name)] = true
+
+		if len(result.Hops) >= maxCNAMEHops {
+			result.TooLong = true
+			result.Issues = append(result.Issues, fmt.Sprintf("chain is longer than %d hops; many resolvers give up before reaching the end", maxCNAMEHops))
+			break
+		}
+
+		answered, err := query(name, dns.TypeCNAME)
+		if err != nil {
+			result.Issues = append(result.Issues, fmt.Sprintf("query for %s failed: %s", name, err))
+			break
+		}
+
+		hop, ok := findAlias(name, answered.Response.Answer)
+		if !ok {
+			result.FinalName = name
+			result.Rcode = dns.RcodeToString[answered.Response.Rcode]
+			break
+		}
+
+		hop.Resolver = answered.Server
+		hop.RTT = float64(answered.RTT.Microseconds()) / 1000
+
+		if hop.Type == "CNAME" {
+			hop.Issues = checkCNAMEOwner(name, query)
+		}
+
+		result.Hops = append(result.Hops, hop)
+		name = hop.Target
+	}
+
+	if result.FinalName != "" {
+		resolveFinalAddresses(result, query)
+	}
+
+	summarizeCNAMEChain(result)
+	return result
+}
+
+// findAlias returns the alias for a name found in an answer section: a DNAME
+// owned by one of its ancestors, which takes precedence over the CNAME
+// synthesized from it, or a CNAME owned by the name itself.
+func findAlias(name string, answer []dns.RR) (CNAMEHop, bool) {
+	for _, rr := range answer {
+		dname, ok := rr.(*dns.DNAME)
+		if !ok || strings.EqualFold(dname.Hdr.Name, name) || !dns.IsSubDomain(dname.Hdr.Name, name) {
+			continue
+		}
+
+		// Replace the DNAME owner suffix with its target (RFC 6672 section 2.2)
+		prefix := name[:len(name)-len(dname.Hdr.Name)]
+		return CNAMEHop{
+			Name:   name,
+			Type:   "DNAME",
+			Owner:  dname.Hdr.Name,
+			Target: dns.Fqdn(prefix + dname.Target),
+			TTL:    dname.Hdr.Ttl,
+		}, true
+	}
+
+	for _, rr := range answer {
+		if cname, ok := rr.(*dns.CNAME); ok && strings.EqualFold(cname.Hdr.Name, name) {
+			return CNAMEHop{
+				Name:   name,
+				Type:   "CNAME",
+				Target: cname.Target,
+				TTL:    cname.Hdr.Ttl,
+			}, true
+		}
+	}
+
+	return CNAMEHop{}, false
+}
+
+// checkCNAMEOwner looks for data that coexists with a CNAME at its owner
+// name, which includes the SOA and NS records of a zone apex.
+func checkCNAMEOwner(name string, query func(string, uint16) (*serverResponse, error)) []string {
+	var issues []string
+
+	apex := false
+	for _, qtype := range cnameConflictTypes {
+		answered, err := query(name, qtype)
+		if err != nil {
+			continue
+		}
+
+		// The SOA query also finds the zone containing the name, which starts
+		// at the name itself when it's a zone apex
+		if qtype == dns.TypeSOA {
+			if zone, ok := zoneApexFromResponse(answered.Response, name); ok && strings.EqualFold(zone, name) {
+				apex = true
+			}
+		}
+
+		for _, rr := range answered.Response.Answer {
+			if rr.Header().Rrtype != qtype || !strings.EqualFold(rr.Header().Name, name) {
+				continue
+			}
+
+			if qtype == dns.TypeSOA || qtype == dns.TypeNS {
+				apex = true
+			}
+
+			issues = append(issues, fmt.Sprintf("CNAME coexists with %s data at %s, which is not allowed", dns.TypeToString[qtype], name))
+			break
+		}
+	}
+
+	if apex {
+		issues = append([]string{fmt.Sprintf("CNAME at the zone apex %s conflicts with the zone's SOA and NS records", name)}, issues...)
+	}
+
+	return issues
+}
+
+// resolveFinalAddresses looks up the A and AAAA records owned by the final
+// name of the chain.
+func resolveFinalAddresses(result *CNAMEChainResponse, query func(string, uint16) (*serverResponse, error)) {
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		answered, err := query(result.FinalName, qtype)
+		if err != nil {
+			result.Issues = append(result.Issues, fmt.Sprintf("%s query for %s failed: %s", dns.TypeToString[qtype], result.FinalName, err))
+			continue
+		}
+
+		for _, rr := range answered.Response.Answer {
+			if !strings.EqualFold(rr.Header().Name, result.FinalName) {
+				continue
+			}
+
+			address := CNAMEAddress{TTL: rr.Header().Ttl, Resolver: answered.Server}
+			switch rec := rr.(type) {
+			case *dns.A:
+				address.Type, address.Address = "A", rec.A.String()
+			case *dns.AAAA:
+				address.Type, address.Address = "AAAA", rec.AAAA.String()
+			default:
+				continue
+			}
+			result.Addresses = append(result.Addresses, address)
+		}
+	}
+
+	switch {
+	case result.Rcode == dns.RcodeToString[dns.RcodeNameError] && len(result.Hops) > 0:
+		result.Issues = append(result.Issues, fmt.Sprintf("chain ends at %s, which does not exist (dangling CNAME)", result.FinalName))
+	case result.Rcode == dns.RcodeToString[dns.RcodeNameError]:
+		result.Issues = append(result.Issues, fmt.Sprintf("%s does not exist", result.FinalName))
+	case len(result.Addresses) == 0:
+		result.Issues = append(result.Issues, fmt.Sprintf("final name %s has no A or AAAA records", result.FinalName))
+	}
+}
+
+// summarizeCNAMEChain finds the lowest TTL along the chain, which limits how
+// long the whole answer can be cached, and renders the chain as text.
+func summarizeCNAMEChain(result *CNAMEChainResponse) {
+	first := true
+	limit := func(ttl uint32, name string) {
+		if first || ttl < result.LimitingTTL {
+			result.LimitingTTL, result.LimitingName = ttl, name
+			first = false
+		}
+	}
+
+	parts := make([]string, 0, len(result.Hops)+1)
+	for _, hop := range result.Hops {
+		limit(hop.TTL, hop.Name)
+		parts = append(parts, fmt.Sprintf("%s (%s, %ds)", hop.Name, hop.Type, hop.TTL))
+	}
+
+	if result.FinalName != "" {
+		addresses := make([]string, 0, len(result.Addresses))
+		for _, address := range result.Addresses {
+			limit(address.TTL, result.FinalName)
+			addresses = append(addresses, fmt.Sprintf("%s (%ds)", address.Address, address.TTL))
+		}
+
+		final := result.FinalName
+		if len(addresses) > 0 {
+			final += " [" + strings.Join(addresses, ", ") + "]"
+		} else if result.Rcode != "" {
+			final += " [" + result.Rcode + "]"
+		}
+		parts = append(parts, final)
+	} else if len(result.Hops) > 0 {
+		parts = append(parts, result.Hops[len(result.Hops)-1].Target+" [unresolved]")
+	}
+
+	result.Chain = strings.Join(parts, " -> ")
+}
@@ -0,0 +1,124 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindAlias(t *testing.T) {
+	t.Run("cname", func(t *testing.T) {
+		hop, ok := findAlias("www.example.com.", mustRRs(t,
+			"www.example.com. 300 IN CNAME cdn.example.net.",
+			"cdn.example.net. 60 IN CNAME edge.example.org.",
+		))
+		require.True(t, ok)
+		assert.Equal(t, "CNAME", hop.Type)
+		assert.Equal(t, "cdn.example.net.", hop.Target)
+		assert.Equal(t, uint32(300), hop.TTL)
+	})
+
+	t.Run("dname takes precedence over the synthesized cname", func(t *testing.T) {
+		hop, ok := findAlias("www.old.example.", mustRRs(t,
+			"old.example. 3600 IN DNAME new.example.",
+			"www.old.example. 3600 IN CNAME www.new.example.",
+		))
+		require.True(t, ok)
+		assert.Equal(t, "DNAME", hop.Type)
+		assert.Equal(t, "old.example.", hop.Owner)
+		assert.Equal(t, "www.new.example.", hop.Target)
+	})
+
+	t.Run("no alias", func(t *testing.T) {
+		_, ok := findAlias("example.com.", mustRRs(t, "example.com. 300 IN A 192.0.2.1"))
+		assert.False(t, ok)
+	})
+}
+
+func TestResolveCNAMEChain(t *testing.T) {
+	records := []string{
+		"www.example.com. 300 IN CNAME a.cdn-one.net.",
+		"a.cdn-one.net. 60 IN CNAME b.cdn-two.net.",
+		"b.cdn-two.net. 20 IN A 192.0.2.10",
+		"b.cdn-two.net. 120 IN AAAA 2001:db8::10",
+		"loop1.example.com. 300 IN CNAME loop2.example.com.",
+		"loop2.example.com. 300 IN CNAME loop1.example.com.",
+		"dangling.example.com. 300 IN CNAME gone.example.org.",
+		"example.net. 300 IN CNAME www.example.com.",
+		"example.net. 300 IN SOA ns1.example.net. hostmaster.example.net. 1 7200 3600 1209600 300",
+		`example.net. 300 IN TXT "coexisting data"`,
+		"sub.example.com. 300 IN CNAME www.example.com.",
+		"sub.example.com. 300 IN SOA ns1.example.com. hostmaster.example.com. 1 7200 3600 1209600 300",
+		"example.org. 300 IN CNAME www.example.com.",
+	}
+
+	// A chain longer than the hop limit
+	for i := range maxCNAMEHops + 1 {
+		records = append(records, fmt.Sprintf("h%d.example.com. 300 IN CNAME h%d.example.com.", i, i+1))
+	}
+	records = append(records, fmt.Sprintf("h%d.example.com. 300 IN A 192.0.2.99", maxCNAMEHops+1))
+
+	servers := []string{startZoneServer(t, mustRRs(t, records...))}
+
+	t.Run("cdn chain", func(t *testing.T) {
+		result := resolveCNAMEChain(context.Background(), "www.example.com.", servers, 2*time.Second)
+
+		require.Len(t, result.Hops, 2)
+		assert.Equal(t, "a.cdn-one.net.", result.Hops[0].Target)
+		assert.Equal(t, servers[0], result.Hops[0].Resolver)
+		assert.Empty(t, result.Hops[0].Issues)
+		assert.Equal(t, "b.cdn-two.net.", result.FinalName)
+		require.Len(t, result.Addresses, 2)
+
+		assert.Equal(t, uint32(20), result.LimitingTTL)
+		assert.Equal(t, "b.cdn-two.net.", result.LimitingName)
+		assert.Equal(t, "www.example.com. (CNAME, 300s) -> a.cdn-one.net. (CNAME, 60s) -> b.cdn-two.net. [192.0.2.10 (20s), 2001:db8::10 (120s)]", result.Chain)
+		assert.Empty(t, result.Issues)
+	})
+
+	t.Run("loop", func(t *testing.T) {
+		result := resolveCNAMEChain(context.Background(), "loop1.example.com.", servers, 2*time.Second)
+		assert.True(t, result.Loop)
+		assert.Len(t, result.Hops, 2)
+		assert.Empty(t, result.FinalName)
+	})
+
+	t.Run("too long", func(t *testing.T) {
+		result := resolveCNAMEChain(context.Background(), "h0.example.com.", servers, 2*time.Second)
+		assert.True(t, result.TooLong)
+		assert.Len(t, result.Hops, maxCNAMEHops)
+	})
+
+	t.Run("dangling", func(t *testing.T) {
+		result := resolveCNAMEChain(context.Background(), "dangling.example.com.", servers, 2*time.Second)
+		assert.Equal(t, "NXDOMAIN", result.Rcode)
+		require.Len(t, result.Issues, 1)
+		assert.Contains(t, result.Issues[0], "dangling CNAME")
+	})
+
+	t.Run("apex cname with other data", func(t *testing.T) {
+		result := resolveCNAMEChain(context.Background(), "example.net.", servers, 2*time.Second)
+		require.Len(t, result.Hops, 3)
+		require.Len(t, result.Hops[0].Issues, 3)
+		assert.Contains(t, result.Hops[0].Issues[0], "zone apex")
+		assert.Contains(t, result.Hops[0].Issues[1], "SOA")
+		assert.Contains(t, result.Hops[0].Issues[2], "TXT")
+	})
+
+	t.Run("apex found through the SOA query", func(t *testing.T) {
+		// A delegated subzone is an apex, though it's below the registrable domain
+		result := resolveCNAMEChain(context.Background(), "sub.example.com.", servers, 2*time.Second)
+		require.NotEmpty(t, result.Hops)
+		require.NotEmpty(t, result.Hops[0].Issues)
+		assert.Contains(t, result.Hops[0].Issues[0], "zone apex sub.example.com.")
+
+		// A registrable domain isn't an apex unless a zone starts there
+		result = resolveCNAMEChain(context.Background(), "example.org.", servers, 2*time.Second)
+		require.NotEmpty(t, result.Hops)
+		assert.Empty(t, result.Hops[0].Issues)
+	})
+}
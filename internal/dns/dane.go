@@ -0,0 +1,379 @@
+package dns
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/miekg/dns"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/domainname"
+	resp "github.com/patrickdappollonio/mcp-domaintools/internal/response"
+	internaltls "github.com/patrickdappollonio/mcp-domaintools/internal/tls"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/utils"
+)
+
+// STARTTLS modes for DANE checks.
+const (
+	startTLSAuto = "auto"
+	startTLSSMTP = "smtp"
+	startTLSNone = "none"
+)
+
+// TLSA certificate usages (RFC 6698 section 2.1.1).
+const (
+	tlsaUsagePKIXTA = 0
+	tlsaUsagePKIXEE = 1
+	tlsaUsageDANETA = 2
+	tlsaUsageDANEEE = 3
+)
+
+// smtpStartTLSPorts are the ports where SMTP servers expect STARTTLS rather
+// than a TLS handshake right away.
+var smtpStartTLSPorts = []int{25, 587}
+
+// tlsaUsageNames are the mnemonics of the TLSA certificate usages (RFC 7218).
+var tlsaUsageNames = map[uint8]string{
+	tlsaUsagePKIXTA: "PKIX-TA",
+	tlsaUsagePKIXEE: "PKIX-EE",
+	tlsaUsageDANETA: "DANE-TA",
+	tlsaUsageDANEEE: "DANE-EE",
+}
+
+// tlsaSelectorNames are the mnemonics of the TLSA selectors (RFC 7218).
+var tlsaSelectorNames = map[uint8]string{
+	0: "Cert",
+	1: "SPKI",
+}
+
+// tlsaMatchingTypeNames are the mnemonics of the TLSA matching types (RFC 7218).
+var tlsaMatchingTypeNames = map[uint8]string{
+	0: "Full",
+	1: "SHA2-256",
+	2: "SHA2-512",
+}
+
+// daneCheckParams represents the parameters for DANE checks.
+type daneCheckParams struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	StartTLS string `json:"starttls"`
+}
+
+// DANECertificate represents a certificate of the chain presented by the server.
+type DANECertificate struct {
+	Depth      int       `json:"depth"`
+	Subject    string    `json:"subject"`
+	Issuer     string    `json:"issuer"`
+	NotAfter   time.Time `json:"not_after"`
+	SHA256     string    `json:"sha256"`
+	SPKISHA256 string    `json:"spki_sha256"`
+}
+
+// DANERecord represents a TLSA record and whether it matches the chain.
+type DANERecord struct {
+	Usage            uint8    `json:"usage"`
+	UsageName        string   `json:"usage_name"`
+	Selector         uint8    `json:"selector"`
+	SelectorName     string   `json:"selector_name"`
+	MatchingType     uint8    `json:"matching_type"`
+	MatchingTypeName string   `json:"matching_type_name"`
+	Data             string   `json:"data"`
+	Matched          bool     `json:"matched"`
+	MatchedDepth     *int     `json:"matched_depth,omitempty"`
+	MatchedSubject   string   `json:"matched_subject,omitempty"`
+	Usable           bool     `json:"usable"`
+	Issues           []string `json:"issues,omitempty"`
+}
+
+// DANECheckResponse represents the complete DANE check response.
+type DANECheckResponse struct {
+	Host             string            `json:"host"`
+	DomainName       *domainname.Name  `json:"domain_name,omitempty"`
+	Port             int               `json:"port"`
+	TLSAName         string            `json:"tlsa_name"`
+	StartTLS         string            `json:"starttls"`
+	DNSSECStatus     string            `json:"dnssec_status,omitempty"`
+	DNSSECFailure    string            `json:"dnssec_failure,omitempty"`
+	Records          []DANERecord      `json:"records"`
+	Certificates     []DANECertificate `json:"certificates"`
+	ConnectionError  string            `json:"connection_error,omitempty"`
+	PKIXValid        bool              `json:"pkix_valid"`
+	PKIXError        string            `json:"pkix_error,omitempty"`
+	Valid            bool              `json:"valid"`
+	SuggestedRecords []string          `json:"suggested_records,omitempty"`
+	Issues           []string          `json:"issues,omitempty"`
+	Timestamp        string            `json:"timestamp"`
+}
+
+// HandleDANECheck fetches the TLSA records of a service, connects to it and
+// checks every TLSA record against the certificate chain the server
+// presents, along with the DNSSEC status of the TLSA records.
+func HandleDANECheck(ctx context.Context, request mcp.CallToolRequest, config *QueryConfig) (*mcp.CallToolResult, error) {
+	var params daneCheckParams
+	if err := request.BindArguments(&params); err != nil {
+		return nil, fmt.Errorf("failed to parse tool input: %w", utils.ParseJSONUnmarshalError(err))
+	}
+
+	// Validate required parameters
+	if params.Host == "" {
+		return nil, fmt.Errorf("parameter \"host\" is required")
+	}
+
+	// Validate and normalize the host name
+	name, err := domainname.Parse(params.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	port := params.Port
+	if port == 0 {
+		port = 443
+	}
+	if port < 1 || port > 65535 {
+		return nil, fmt.Errorf("invalid port %d", port)
+	}
+
+	startTLS := strings.ToLower(params.StartTLS)
+	switch startTLS {
+	case "", startTLSAuto:
+		startTLS = startTLSNone
+		if slices.Contains(smtpStartTLSPorts, port) {
+			startTLS = startTLSSMTP
+		}
+	case startTLSSMTP, startTLSNone:
+	default:
+		return nil, fmt.Errorf("unsupported starttls mode %q: must be auto, smtp or none", params.StartTLS)
+	}
+
+	host := name.ASCII
+	result := &DANECheckResponse{
+		Host:         host,
+		DomainName:   name,
+		Port:         port,
+		TLSAName:     "_" + strconv.Itoa(port) + "._tcp." + dns.Fqdn(host),
+		StartTLS:     startTLS,
+		Records:      make([]DANERecord, 0),
+		Certificates: make([]DANECertificate, 0),
+		Timestamp:    time.Now().Format(time.RFC3339),
+	}
+
+	records, err := lookupTLSA(ctx, result.TLSAName, config)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) > 0 {
+		validation := validateChainOfTrust(ctx, result.TLSAName, dns.TypeTLSA, config)
+		result.DNSSECStatus = validation.Status
+		result.DNSSECFailure = validation.FailedLink
+	}
+
+	fetch := internaltls.FetchCertificates
+	if startTLS == startTLSSMTP {
+		fetch = internaltls.FetchSMTPCertificates
+	}
+
+	chain, err := fetch(ctx, host, port, host, config.Timeout)
+	if err != nil {
+		result.ConnectionError = err.Error()
+	}
+
+	checkDANE(result, records, chain, time.Now())
+	return resp.JSON(result)
+}
+
+// lookupTLSA returns the TLSA records of a name. An alias is followed by the
+// resolver, so the records may be owned by the target of a CNAME.
+func lookupTLSA(ctx context.Context, name string, config *QueryConfig) ([]*dns.TLSA, error) {
+	response, err := queryDNSSEC(ctx, name, dns.TypeTLSA, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up TLSA records for %s: %w", name, err)
+	}
+
+	var records []*dns.TLSA
+	for _, rr := range response.Answer {
+		if tlsa, ok := rr.(*dns.TLSA); ok {
+			records = append(records, tlsa)
+		}
+	}
+
+	return records, nil
+}
+
+// checkDANE matches the TLSA records against the presented chain and
+// summarizes whether the service can be authenticated with DANE.
+func checkDANE(result *DANECheckResponse, records []*dns.TLSA, chain []*x509.Certificate, now time.Time) {
+	var pkixErr error
+	if len(chain) > 0 {
+		pkixErr = verifyPKIX(chain, result.Host, now)
+		result.PKIXValid = pkixErr == nil
+		if pkixErr != nil {
+			result.PKIXError = pkixErr.Error()
+		}
+	}
+
+	for depth, certificate := range chain {
+		certHash, _ := dns.CertificateToDANE(0, 1, certificate)
+		spki, _ := dns.CertificateToDANE(1, 1, certificate)
+		result.Certificates = append(result.Certificates, DANECertificate{
+			Depth:      depth,
+			Subject:    certificate.Subject.String(),
+			Issuer:     certificate.Issuer.String(),
+			NotAfter:   certificate.NotAfter,
+			SHA256:     certHash,
+			SPKISHA256: spki,
+		})
+	}
+
+	usable := false
+	for _, record := range records {
+		matched := matchTLSARecord(record, chain, result.Host, now, pkixErr)
+		if matched.Usable {
+			usable = true
+		}
+
+		if slices.Contains(smtpStartTLSPorts, result.Port) && (record.Usage == tlsaUsagePKIXTA || record.Usage == tlsaUsagePKIXEE) {
+			matched.Issues = append(matched.Issues, "SMTP clients ignore PKIX-TA and PKIX-EE records (RFC 7672 section 3.1.3); use DANE-TA or DANE-EE")
+		}
+
+		result.Records = append(result.Records, matched)
+	}
+
+	secure := result.DNSSECStatus == dnssecSecure
+	result.Valid = usable && secure
+
+	switch {
+	case len(records) == 0:
+		result.Issues = append(result.Issues, fmt.Sprintf("no TLSA records found at %s", result.TLSAName))
+	case !secure:
+		result.Issues = append(result.Issues, fmt.Sprintf("the TLSA records are not DNSSEC-validated (%s), so clients must not use them", result.DNSSECStatus))
+	}
+
+	if result.ConnectionError != "" {
+		result.Issues = append(result.Issues, fmt.Sprintf("could not get the certificate chain: %s", result.ConnectionError))
+		return
+	}
+
+	if len(records) > 0 && !usable {
+		result.Issues = append(result.Issues, "no TLSA record matches the certificate chain the server presents; if the certificate was rotated, publish one of the suggested records")
+	}
+
+	// Suggest records for the server key and, when present, its issuer
+	if len(result.Certificates) > 0 {
+		result.SuggestedRecords = append(result.SuggestedRecords, fmt.Sprintf("%s IN TLSA 3 1 1 %s", result.TLSAName, result.Certificates[0].SPKISHA256))
+	}
+	if len(result.Certificates) > 1 {
+		result.SuggestedRecords = append(result.SuggestedRecords, fmt.Sprintf("%s IN TLSA 2 1 1 %s", result.TLSAName, result.Certificates[1].SPKISHA256))
+	}
+}
+
+// matchTLSARecord checks a TLSA record against the chain. End entity usages
+// match the server certificate, and trust anchor usages match the other
+// certificates of the chain. The PKIX usages also need the chain to pass
+// regular certificate validation, and DANE-TA needs the server certificate
+// to chain to the matched anchor with a name valid for the host.
+func matchTLSARecord(record *dns.TLSA, chain []*x509.Certificate, host string, now time.Time, pkixErr error) DANERecord {
+	matched := DANERecord{
+		Usage:            record.Usage,
+		UsageName:        mnemonic(tlsaUsageNames, record.Usage),
+		Selector:         record.Selector,
+		SelectorName:     mnemonic(tlsaSelectorNames, record.Selector),
+		MatchingType:     record.MatchingType,
+		MatchingTypeName: mnemonic(tlsaMatchingTypeNames, record.MatchingType),
+		Data:             strings.ToLower(record.Certificate),
+	}
+
+	if _, ok := tlsaUsageNames[record.Usage]; !ok {
+		matched.Issues = append(matched.Issues, fmt.Sprintf("unknown certificate usage %d", record.Usage))
+		return matched
+	}
+
+	var depths []int
+	for depth := range chain {
+		endEntity := record.Usage == tlsaUsagePKIXEE || record.Usage == tlsaUsageDANEEE
+		if (depth == 0) == endEntity {
+			depths = append(depths, depth)
+		}
+	}
+
+	for _, depth := range depths {
+		data, err := dns.CertificateToDANE(record.Selector, record.MatchingType, chain[depth])
+		if err != nil {
+			matched.Issues = append(matched.Issues, fmt.Sprintf("unsupported selector %d or matching type %d", record.Selector, record.MatchingType))
+			return matched
+		}
+
+		if strings.EqualFold(data, record.Certificate) {
+			matched.Matched = true
+			matched.MatchedDepth = &depth
+			matched.MatchedSubject = chain[depth].Subject.String()
+			break
+		}
+	}
+
+	switch {
+	case !matched.Matched:
+	case (record.Usage == tlsaUsagePKIXTA || record.Usage == tlsaUsagePKIXEE) && pkixErr != nil:
+		matched.Issues = append(matched.Issues, fmt.Sprintf("the record matches, but %s also requires a chain that passes certificate validation: %s", matched.UsageName, pkixErr))
+	case record.Usage == tlsaUsageDANETA:
+		if err := verifyDANETA(chain, *matched.MatchedDepth, host, now); err != nil {
+			matched.Issues = append(matched.Issues, fmt.Sprintf("the record matches, but the server certificate doesn't validate with it as the trust anchor: %s", err))
+			break
+		}
+		matched.Usable = true
+	default:
+		matched.Usable = true
+	}
+
+	return matched
+}
+
+// verifyPKIX validates the chain against the system roots for the host.
+func verifyPKIX(chain []*x509.Certificate, host string, now time.Time) error {
+	intermediates := x509.NewCertPool()
+	for _, certificate := range chain[1:] {
+		intermediates.AddCert(certificate)
+	}
+
+	_, err := chain[0].Verify(x509.VerifyOptions{
+		DNSName:       host,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+	})
+	return err
+}
+
+// verifyDANETA validates the server certificate for the host using the
+// certificate at the given depth as the only trust anchor, and the
+// certificates between them as intermediates (RFC 7671 section 5.2.2).
+func verifyDANETA(chain []*x509.Certificate, depth int, host string, now time.Time) error {
+	roots := x509.NewCertPool()
+	roots.AddCert(chain[depth])
+
+	intermediates := x509.NewCertPool()
+	for _, certificate := range chain[1:depth] {
+		intermediates.AddCert(certificate)
+	}
+
+	_, err := chain[0].Verify(x509.VerifyOptions{
+		DNSName:       host,
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+	})
+	return err
+}
+
+// mnemonic returns the name of a TLSA field value, or the number itself
+// when the value is unassigned.
+func mnemonic(names map[uint8]string, value uint8) string {
+	if name, ok := names[value]; ok {
+		return name
+	}
+	return strconv.Itoa(int(value))
+}
@@ -0,0 +1,208 @@
+package dns
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testChain returns a server certificate for mail.example.com and the CA
+// certificate that issued it.
+func testChain(t *testing.T) []*x509.Certificate {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	leafTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "mail.example.com"},
+		DNSNames:     []string{"mail.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTemplate, ca, &leafKey.PublicKey, caKey)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(leafDER)
+	require.NoError(t, err)
+
+	return []*x509.Certificate{leaf, ca}
+}
+
+// tlsaRecord builds a TLSA record for the certificate.
+func tlsaRecord(t *testing.T, usage, selector, matchingType uint8, certificate *x509.Certificate) *dns.TLSA {
+	t.Helper()
+
+	data, err := dns.CertificateToDANE(selector, matchingType, certificate)
+	require.NoError(t, err)
+
+	return mustRRs(t, fmt.Sprintf("_25._tcp.mail.example.com. 300 IN TLSA %d %d %d %s", usage, selector, matchingType, strings.ToUpper(data)))[0].(*dns.TLSA)
+}
+
+func TestMatchTLSARecord(t *testing.T) {
+	chain := testChain(t)
+	leaf, ca := chain[0], chain[1]
+	pkixErr := fmt.Errorf("x509: certificate signed by unknown authority")
+	now := time.Now()
+
+	t.Run("dane-ee matches the server certificate", func(t *testing.T) {
+		matched := matchTLSARecord(tlsaRecord(t, 3, 1, 1, leaf), chain, "mail.example.com", now, pkixErr)
+		assert.True(t, matched.Matched)
+		assert.True(t, matched.Usable)
+		require.NotNil(t, matched.MatchedDepth)
+		assert.Equal(t, 0, *matched.MatchedDepth)
+		assert.Equal(t, "DANE-EE", matched.UsageName)
+		assert.Equal(t, "SPKI", matched.SelectorName)
+		assert.Equal(t, "SHA2-256", matched.MatchingTypeName)
+		assert.Equal(t, strings.ToLower(matched.Data), matched.Data)
+	})
+
+	t.Run("dane-ta matches the issuer", func(t *testing.T) {
+		matched := matchTLSARecord(tlsaRecord(t, 2, 0, 2, ca), chain, "mail.example.com", now, pkixErr)
+		assert.True(t, matched.Usable)
+		require.NotNil(t, matched.MatchedDepth)
+		assert.Equal(t, 1, *matched.MatchedDepth)
+		assert.Equal(t, "CN=Test CA", matched.MatchedSubject)
+	})
+
+	t.Run("dane-ta needs the server certificate to chain to the anchor", func(t *testing.T) {
+		// An unrelated CA certificate sent along with the server certificate
+		unrelated := testChain(t)[1]
+		matched := matchTLSARecord(tlsaRecord(t, 2, 1, 1, unrelated), []*x509.Certificate{leaf, unrelated}, "mail.example.com", now, pkixErr)
+		assert.True(t, matched.Matched)
+		assert.False(t, matched.Usable)
+		require.Len(t, matched.Issues, 1)
+		assert.Contains(t, matched.Issues[0], "doesn't validate with it as the trust anchor")
+	})
+
+	t.Run("dane-ta checks the host name", func(t *testing.T) {
+		matched := matchTLSARecord(tlsaRecord(t, 2, 0, 1, ca), chain, "smtp.example.net", now, pkixErr)
+		assert.True(t, matched.Matched)
+		assert.False(t, matched.Usable)
+		require.Len(t, matched.Issues, 1)
+		assert.Contains(t, matched.Issues[0], "smtp.example.net")
+
+		// DANE-EE records don't depend on the name
+		assert.True(t, matchTLSARecord(tlsaRecord(t, 3, 1, 1, leaf), chain, "smtp.example.net", now, pkixErr).Usable)
+	})
+
+	t.Run("dane-ee doesn't match the issuer", func(t *testing.T) {
+		matched := matchTLSARecord(tlsaRecord(t, 3, 1, 1, ca), chain, "mail.example.com", now, pkixErr)
+		assert.False(t, matched.Matched)
+		assert.False(t, matched.Usable)
+	})
+
+	t.Run("pkix-ee needs a valid chain", func(t *testing.T) {
+		matched := matchTLSARecord(tlsaRecord(t, 1, 0, 1, leaf), chain, "mail.example.com", now, pkixErr)
+		assert.True(t, matched.Matched)
+		assert.False(t, matched.Usable)
+		require.Len(t, matched.Issues, 1)
+		assert.Contains(t, matched.Issues[0], "PKIX-EE also requires a chain that passes certificate validation")
+
+		assert.True(t, matchTLSARecord(tlsaRecord(t, 1, 0, 1, leaf), chain, "mail.example.com", now, nil).Usable)
+	})
+
+	t.Run("unknown usage", func(t *testing.T) {
+		record := tlsaRecord(t, 3, 1, 1, leaf)
+		record.Usage = 9
+		matched := matchTLSARecord(record, chain, "mail.example.com", now, nil)
+		assert.False(t, matched.Matched)
+		assert.Equal(t, "9", matched.UsageName)
+		assert.Equal(t, []string{"unknown certificate usage 9"}, matched.Issues)
+	})
+}
+
+func TestCheckDANE(t *testing.T) {
+	chain := testChain(t)
+	leaf, ca := chain[0], chain[1]
+
+	newResult := func(status string) *DANECheckResponse {
+		return &DANECheckResponse{
+			Host:         "mail.example.com",
+			Port:         25,
+			TLSAName:     "_25._tcp.mail.example.com.",
+			DNSSECStatus: status,
+		}
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		result := newResult(dnssecSecure)
+		checkDANE(result, []*dns.TLSA{tlsaRecord(t, 3, 1, 1, leaf), tlsaRecord(t, 2, 1, 1, ca)}, chain, time.Now())
+
+		assert.True(t, result.Valid)
+		assert.False(t, result.PKIXValid)
+		assert.NotEmpty(t, result.PKIXError)
+		require.Len(t, result.Certificates, 2)
+		assert.Equal(t, result.Records[0].Data, result.Certificates[0].SPKISHA256)
+		assert.Empty(t, result.Issues)
+		assert.Equal(t, []string{
+			"_25._tcp.mail.example.com. IN TLSA 3 1 1 " + result.Certificates[0].SPKISHA256,
+			"_25._tcp.mail.example.com. IN TLSA 2 1 1 " + result.Certificates[1].SPKISHA256,
+		}, result.SuggestedRecords)
+	})
+
+	t.Run("rotated certificate", func(t *testing.T) {
+		result := newResult(dnssecSecure)
+		checkDANE(result, []*dns.TLSA{tlsaRecord(t, 3, 1, 1, testChain(t)[0])}, chain, time.Now())
+
+		assert.False(t, result.Valid)
+		assert.False(t, result.Records[0].Matched)
+		assert.Contains(t, result.Issues[0], "no TLSA record matches")
+	})
+
+	t.Run("not dnssec validated", func(t *testing.T) {
+		result := newResult(dnssecInsecure)
+		checkDANE(result, []*dns.TLSA{tlsaRecord(t, 3, 1, 1, leaf)}, chain, time.Now())
+
+		assert.False(t, result.Valid)
+		assert.True(t, result.Records[0].Usable)
+		assert.Equal(t, []string{"the TLSA records are not DNSSEC-validated (insecure), so clients must not use them"}, result.Issues)
+	})
+
+	t.Run("pkix usages on smtp", func(t *testing.T) {
+		result := newResult(dnssecSecure)
+		checkDANE(result, []*dns.TLSA{tlsaRecord(t, 1, 1, 1, leaf)}, chain, time.Now())
+		assert.Contains(t, result.Records[0].Issues, "SMTP clients ignore PKIX-TA and PKIX-EE records (RFC 7672 section 3.1.3); use DANE-TA or DANE-EE")
+	})
+
+	t.Run("connection failed", func(t *testing.T) {
+		result := newResult("")
+		result.ConnectionError = "failed to connect"
+		checkDANE(result, nil, nil, time.Now())
+
+		assert.False(t, result.Valid)
+		assert.Empty(t, result.SuggestedRecords)
+		assert.Equal(t, []string{
+			"no TLSA records found at _25._tcp.mail.example.com.",
+			"could not get the certificate chain: failed to connect",
+		}, result.Issues)
+	})
+}
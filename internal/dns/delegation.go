@@ -0,0 +1,401 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/miekg/dns"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/domainname"
+	resp "github.com/patrickdappollonio/mcp-domaintools/internal/response"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/utils"
+	"golang.org/x/sync/errgroup"
+)
+
+// delegationCheckParams represents the parameters for delegation checks.
+type delegationCheckParams struct {
+	Domain string `json:"domain"`
+}
+
+// DelegationServer represents the check of one address of a nameserver of
+// the child zone.
+type DelegationServer struct {
+	Nameserver    string   `json:"nameserver"`
+	Address       string   `json:"address"`
+	FromGlue      bool     `json:"from_glue"`
+	FromDNS       bool     `json:"from_dns"`
+	Reachable     bool     `json:"reachable"`
+	Authoritative bool     `json:"authoritative"`
+	Skipped       bool     `json:"skipped,omitempty"`
+	RTT           float64  `json:"rtt_ms"`
+	Rcode         string   `json:"rcode,omitempty"`
+	NameServers   []string `json:"nameservers,omitempty"`
+	Error         string   `json:"error,omitempty"`
+}
+
+// DelegationCheckResponse represents the complete delegation check response.
+type DelegationCheckResponse struct {
+	Zone              string             `json:"zone"`
+	DomainName        *domainname.Name   `json:"domain_name,omitempty"`
+	Alias             string             `json:"alias,omitempty"`
+	ParentZone        string             `json:"parent_zone"`
+	ParentServer      string             `json:"parent_server,omitempty"`
+	ParentNameServers []string           `json:"parent_nameservers"`
+	Glue              []TraceGlue        `json:"glue"`
+	ChildNameServers  []string           `json:"child_nameservers"`
+	Servers           []DelegationServer `json:"servers"`
+	Valid             bool               `json:"valid"`
+	Issues            []string           `json:"issues,omitempty"`
+	Timestamp         string             `json:"timestamp"`
+}
+
+// HandleDelegationCheck compares the delegation served by the parent zone's
+// servers, NS records and glue, with the NS records served by the child
+// zone's own authoritative servers.
+func HandleDelegationCheck(ctx context.Context, request mcp.CallToolRequest, config *QueryConfig) (*mcp.CallToolResult, error) {
+	var params delegationCheckParams
+	if err := request.BindArguments(&params); err != nil {
+		return nil, fmt.Errorf("failed to parse tool input: %w", utils.ParseJSONUnmarshalError(err))
+	}
+
+	// Validate required parameters
+	if params.Domain == "" {
+		return nil, fmt.Errorf("parameter \"domain\" is required")
+	}
+
+	// Validate and normalize the domain name
+	name, err := domainname.Parse(params.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := checkDelegation(ctx, name.FQDN(), config)
+	if err != nil {
+		return nil, fmt.Errorf("delegation check failed: %w", err)
+	}
+	result.DomainName = name
+
+	return resp.JSON(result)
+}
+
+// checkDelegation asks the parent zone's servers for the referral to the zone
+// and then queries every address of every nameserver, from the parent and the
+// child, for the zone's NS records.
+func checkDelegation(ctx context.Context, domain string, config *QueryConfig) (*DelegationCheckResponse, error) {
+	// A broken delegation can make the zone fail to resolve, in which case
+	// the name is checked as the zone itself. An alias is checked as part of
+	// the zone that holds it, not its target's zone.
+	zone, alias, err := findZoneApex(ctx, domain, config)
+	if err != nil {
+		zone = domain
+	}
+
+	if zone == "." {
+		return nil, fmt.Errorf("the root zone has no parent")
+	}
+
+	labels := dns.SplitDomainName(zone)
+	parentZone := "."
+	if len(labels) > 1 {
+		parentZone, _, err = findZoneApex(ctx, dns.Fqdn(strings.Join(labels[1:], ".")), config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find the parent zone: %w", err)
+		}
+	}
+
+	result := &DelegationCheckResponse{
+		Zone:              zone,
+		Alias:             alias,
+		ParentZone:        parentZone,
+		ParentNameServers: make([]string, 0),
+		Glue:              make([]TraceGlue, 0),
+		ChildNameServers:  make([]string, 0),
+		Servers:           make([]DelegationServer, 0),
+		Timestamp:         time.Now().Format(time.RFC3339),
+	}
+
+	// Ask the parent's servers for the referral, as a resolver would
+	parentServers := rootServers
+	if parentZone != "." {
+		names, err := lookupNameServers(ctx, parentZone, config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find the parent zone's nameservers: %w", err)
+		}
+
+		parentServers = make([]nameServer, 0, len(names))
+		for _, name := range names {
+			parentServers = append(parentServers, nameServer{Name: name})
+		}
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion(zone, dns.TypeNS)
+	m.RecursionDesired = false
+	m.SetEdns0(4096, false)
+
+	response, hop := exchangeWithNameServers(ctx, m, parentServers, config)
+	if response == nil {
+		return nil, fmt.Errorf("no nameserver for the parent zone %s responded: %s", parentZone, strings.Join(hop.Errors, "; "))
+	}
+	result.ParentServer = fmt.Sprintf("%s (%s)", hop.Server, hop.ServerAddress)
+
+	referral := findReferral(response, zone, parentZone)
+	if referral == nil || !strings.EqualFold(referral.Zone, zone) {
+		return nil, fmt.Errorf("parent server %s did not return a referral for %s (%s)", hop.Server, zone, hop.Rcode)
+	}
+
+	for _, ns := range referral.NameServers {
+		result.ParentNameServers = append(result.ParentNameServers, dns.CanonicalName(ns))
+	}
+	slices.Sort(result.ParentNameServers)
+	result.ParentNameServers = slices.Compact(result.ParentNameServers)
+	result.Glue = append(result.Glue, referral.Glue...)
+
+	// Resolve every parent nameserver through DNS, to compare with the glue
+	resolved := make(map[string][]string)
+	for _, ns := range result.ParentNameServers {
+		ipv4, ipv6 := lookupAddresses(ctx, ns, config)
+		resolved[ns] = append(ipv4, ipv6...)
+	}
+
+	// Without an IPv6 route every IPv6 address would look unreachable, so
+	// those addresses are skipped, as the trace only uses IPv4 root servers
+	ipv6 := hasIPv6Route()
+
+	result.Servers = delegationServers(result.ParentNameServers, result.Glue, resolved)
+	queryDelegationServers(ctx, zone, result.Servers, ipv6, config)
+
+	// Servers only listed by the child are checked as well
+	result.ChildNameServers = childNameServers(result.Servers)
+	var extra []string
+	for _, ns := range result.ChildNameServers {
+		if !slices.Contains(result.ParentNameServers, ns) {
+			ipv4, ipv6 := lookupAddresses(ctx, ns, config)
+			resolved[ns] = append(ipv4, ipv6...)
+			extra = append(extra, ns)
+		}
+	}
+
+	if len(extra) > 0 {
+		servers := delegationServers(extra, nil, resolved)
+		queryDelegationServers(ctx, zone, servers, ipv6, config)
+		result.Servers = append(result.Servers, servers...)
+	}
+
+	result.Issues = append(result.Issues, compareNameServerSets(result.ParentNameServers, result.ChildNameServers)...)
+	result.Issues = append(result.Issues, checkGlue(zone, result.ParentNameServers, result.Glue, resolved)...)
+	result.Issues = append(result.Issues, checkDelegationServers(zone, result.Servers)...)
+	result.Valid = len(result.Issues) == 0
+
+	return result, nil
+}
+
+// delegationServers lists every address of the given nameservers, from the
+// glue and from DNS, noting where each address came from.
+func delegationServers(nameservers []string, glue []TraceGlue, resolved map[string][]string) []DelegationServer {
+	var servers []DelegationServer
+
+	for _, ns := range nameservers {
+		start := len(servers)
+		find := func(address string) *DelegationServer {
+			for i := start; i < len(servers); i++ {
+				if servers[i].Address == address {
+					return &servers[i]
+				}
+			}
+			servers = append(servers, DelegationServer{Nameserver: ns, Address: address})
+			return &servers[len(servers)-1]
+		}
+
+		for _, g := range glue {
+			if strings.EqualFold(g.Name, ns) {
+				find(g.Address).FromGlue = true
+			}
+		}
+
+		for _, address := range resolved[ns] {
+			find(address).FromDNS = true
+		}
+
+		if len(servers) == start {
+			servers = append(servers, DelegationServer{Nameserver: ns, Error: "nameserver has no glue and does not resolve to any address"})
+		}
+	}
+
+	return servers
+}
+
+// queryDelegationServers sends a non-recursive NS query for the zone to every
+// server address and records the outcome. IPv6 addresses are skipped when
+// ipv6 is false.
+func queryDelegationServers(ctx context.Context, zone string, servers []DelegationServer, ipv6 bool, config *QueryConfig) {
+	var eg errgroup.Group
+	eg.SetLimit(auditConcurrency)
+	for i := range servers {
+		if servers[i].Address == "" {
+			continue
+		}
+
+		if !ipv6 && isIPv6Address(servers[i].Address) {
+			servers[i].Skipped = true
+			servers[i].Error = "not queried: this host has no IPv6 route"
+			continue
+		}
+
+		eg.Go(func() error {
+			server := &servers[i]
+
+			m := new(dns.Msg)
+			m.SetQuestion(zone, dns.TypeNS)
+			m.RecursionDesired = false
+			m.SetEdns0(4096, false)
+
+			answered, err := exchangeWithTCPFallback(ctx, m, serverAddress(server.Address), transportUDP, config.Timeout)
+			if err != nil {
+				server.Error = err.Error()
+				return nil
+			}
+
+			server.Reachable = true
+			server.Authoritative = answered.Response.Authoritative
+			server.RTT = float64(answered.RTT.Microseconds()) / 1000
+			server.Rcode = dns.RcodeToString[answered.Response.Rcode]
+			server.NameServers = nameServerTargets(answered.Response.Answer, zone)
+			return nil
+		})
+	}
+	_ = eg.Wait()
+}
+
+// hasIPv6Route reports whether this host can reach IPv6 addresses. Dialing
+// UDP sends nothing, but fails when there's no route to the address.
+func hasIPv6Route() bool {
+	conn, err := net.Dial("udp6", "[2001:500:2::c]:53")
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
+
+// isIPv6Address reports whether an address is an IPv6 address.
+func isIPv6Address(address string) bool {
+	ip := net.ParseIP(address)
+	return ip != nil && ip.To4() == nil
+}
+
+// childNameServers returns the NS set served by the child zone: the union of
+// the NS records returned by its authoritative servers.
+func childNameServers(servers []DelegationServer) []string {
+	nameservers := make([]string, 0)
+	for _, server := range servers {
+		if server.Authoritative {
+			nameservers = append(nameservers, server.NameServers...)
+		}
+	}
+	slices.Sort(nameservers)
+	return slices.Compact(nameservers)
+}
+
+// compareNameServerSets reports the nameservers listed only by the parent or
+// only by the child.
+func compareNameServerSets(parent, child []string) []string {
+	var issues []string
+
+	if len(child) == 0 {
+		return []string{"no authoritative server returned the zone's NS records"}
+	}
+
+	for _, ns := range parent {
+		if !slices.Contains(child, ns) {
+			issues = append(issues, fmt.Sprintf("nameserver %s is delegated by the parent but missing from the zone's own NS records", ns))
+		}
+	}
+
+	for _, ns := range child {
+		if !slices.Contains(parent, ns) {
+			issues = append(issues, fmt.Sprintf("nameserver %s is listed in the zone's NS records but not delegated by the parent", ns))
+		}
+	}
+
+	return issues
+}
+
+// checkGlue reports in-bailiwick nameservers without glue, glue addresses a
+// nameserver no longer resolves to, and addresses missing from the glue.
+func checkGlue(zone string, nameservers []string, glue []TraceGlue, resolved map[string][]string) []string {
+	var issues []string
+
+	for _, ns := range nameservers {
+		var glueAddresses []string
+		for _, g := range glue {
+			if strings.EqualFold(g.Name, ns) {
+				glueAddresses = append(glueAddresses, g.Address)
+			}
+		}
+
+		// Glue is only required for nameservers inside the delegated zone
+		inBailiwick := dns.IsSubDomain(zone, ns)
+		if inBailiwick && len(glueAddresses) == 0 {
+			issues = append(issues, fmt.Sprintf("in-bailiwick nameserver %s has no glue at the parent, so it cannot be reached", ns))
+			continue
+		}
+
+		addresses := resolved[ns]
+		if len(addresses) == 0 {
+			continue
+		}
+
+		for _, address := range glueAddresses {
+			if !slices.Contains(addresses, address) {
+				issues = append(issues, fmt.Sprintf("stale glue for %s: %s is not among the addresses it resolves to (%s)", ns, address, strings.Join(addresses, ", ")))
+			}
+		}
+
+		if inBailiwick {
+			for _, address := range addresses {
+				if !slices.Contains(glueAddresses, address) {
+					issues = append(issues, fmt.Sprintf("missing glue for %s: it resolves to %s, which the parent does not serve", ns, address))
+				}
+			}
+		}
+	}
+
+	return issues
+}
+
+// checkDelegationServers reports servers that don't respond, that don't answer
+// authoritatively for the zone, and child servers that disagree on the NS set.
+// Skipped servers are not reported.
+func checkDelegationServers(zone string, servers []DelegationServer) []string {
+	var issues []string
+	var sets []string
+
+	for _, server := range servers {
+		switch {
+		case server.Skipped:
+			continue
+		case server.Address == "":
+			issues = append(issues, fmt.Sprintf("nameserver %s has no address", server.Nameserver))
+		case !server.Reachable:
+			issues = append(issues, fmt.Sprintf("nameserver %s (%s) did not respond: %s", server.Nameserver, server.Address, server.Error))
+		case !server.Authoritative || server.Rcode != dns.RcodeToString[dns.RcodeSuccess]:
+			issues = append(issues, fmt.Sprintf("nameserver %s (%s) does not answer authoritatively for %s (lame delegation, %s)", server.Nameserver, server.Address, zone, server.Rcode))
+		default:
+			set := strings.Join(server.NameServers, " ")
+			if !slices.Contains(sets, set) {
+				sets = append(sets, set)
+			}
+		}
+	}
+
+	if len(sets) > 1 {
+		issues = append(issues, fmt.Sprintf("authoritative servers disagree on the NS set: %s", strings.Join(sets, " | ")))
+	}
+
+	return issues
+}
@@ -0,0 +1,96 @@
+package dns
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDelegationServers(t *testing.T) {
+	glue := []TraceGlue{
+		{Name: "NS1.example.com.", Type: "A", Address: "192.0.2.1"},
+		{Name: "ns1.example.com.", Type: "AAAA", Address: "2001:db8::1"},
+	}
+	resolved := map[string][]string{
+		"ns1.example.com.": {"192.0.2.1", "192.0.2.11"},
+		"ns.example.net.":  {"198.51.100.1"},
+	}
+
+	servers := delegationServers([]string{"ns1.example.com.", "ns.example.net.", "ns.missing.org."}, glue, resolved)
+	require.Len(t, servers, 5)
+
+	assert.Equal(t, DelegationServer{Nameserver: "ns1.example.com.", Address: "192.0.2.1", FromGlue: true, FromDNS: true}, servers[0])
+	assert.Equal(t, DelegationServer{Nameserver: "ns1.example.com.", Address: "2001:db8::1", FromGlue: true}, servers[1])
+	assert.Equal(t, DelegationServer{Nameserver: "ns1.example.com.", Address: "192.0.2.11", FromDNS: true}, servers[2])
+	assert.Equal(t, DelegationServer{Nameserver: "ns.example.net.", Address: "198.51.100.1", FromDNS: true}, servers[3])
+	assert.Empty(t, servers[4].Address)
+	assert.NotEmpty(t, servers[4].Error)
+}
+
+func TestCompareNameServerSets(t *testing.T) {
+	assert.Empty(t, compareNameServerSets([]string{"a.example.", "b.example."}, []string{"a.example.", "b.example."}))
+
+	issues := compareNameServerSets([]string{"a.example.", "old.example."}, []string{"a.example.", "new.example."})
+	require.Len(t, issues, 2)
+	assert.Contains(t, issues[0], "old.example.")
+	assert.Contains(t, issues[0], "missing from the zone's own NS records")
+	assert.Contains(t, issues[1], "new.example.")
+	assert.Contains(t, issues[1], "not delegated by the parent")
+
+	assert.Len(t, compareNameServerSets([]string{"a.example."}, nil), 1)
+}
+
+func TestCheckGlue(t *testing.T) {
+	glue := []TraceGlue{
+		{Name: "ns1.example.com.", Type: "A", Address: "192.0.2.1"},
+		{Name: "ns2.example.com.", Type: "A", Address: "192.0.2.99"},
+	}
+	resolved := map[string][]string{
+		"ns1.example.com.": {"192.0.2.1"},
+		"ns2.example.com.": {"192.0.2.2"},
+		"ns3.example.com.": {"192.0.2.3"},
+		"ns.example.net.":  {"198.51.100.1"},
+	}
+
+	issues := checkGlue("example.com.", []string{"ns1.example.com.", "ns2.example.com.", "ns3.example.com.", "ns.example.net."}, glue, resolved)
+	require.Len(t, issues, 3)
+	assert.Contains(t, issues[0], "stale glue for ns2.example.com.")
+	assert.Contains(t, issues[1], "missing glue for ns2.example.com.")
+	assert.Contains(t, issues[2], "in-bailiwick nameserver ns3.example.com. has no glue")
+}
+
+func TestCheckDelegationServers(t *testing.T) {
+	servers := []DelegationServer{
+		{Nameserver: "ns1.example.com.", Address: "192.0.2.1", Reachable: true, Authoritative: true, Rcode: "NOERROR", NameServers: []string{"ns1.example.com.", "ns2.example.com."}},
+		{Nameserver: "ns2.example.com.", Address: "192.0.2.2", Reachable: true, Authoritative: true, Rcode: "NOERROR", NameServers: []string{"ns1.example.com."}},
+		{Nameserver: "ns3.example.com.", Address: "192.0.2.3", Reachable: true, Rcode: "REFUSED"},
+		{Nameserver: "ns4.example.com.", Address: "192.0.2.4", Error: "i/o timeout"},
+		{Nameserver: "ns4.example.com.", Address: "2001:db8::4", Skipped: true, Error: "not queried: this host has no IPv6 route"},
+	}
+
+	issues := checkDelegationServers("example.com.", servers)
+	require.Len(t, issues, 3)
+	assert.Contains(t, issues[0], "lame delegation, REFUSED")
+	assert.Contains(t, issues[1], "did not respond: i/o timeout")
+	assert.Contains(t, issues[2], "disagree on the NS set")
+}
+
+func TestIsIPv6Address(t *testing.T) {
+	assert.True(t, isIPv6Address("2001:db8::1"))
+	assert.False(t, isIPv6Address("192.0.2.1"))
+	assert.False(t, isIPv6Address("::ffff:192.0.2.1"))
+	assert.False(t, isIPv6Address("ns1.example.com."))
+}
+
+func TestQueryDelegationServersWithoutIPv6(t *testing.T) {
+	servers := []DelegationServer{{Nameserver: "ns1.example.com.", Address: "2001:db8::1", FromGlue: true}}
+
+	queryDelegationServers(context.Background(), "example.com.", servers, false, &QueryConfig{Timeout: time.Second})
+
+	assert.True(t, servers[0].Skipped)
+	assert.False(t, servers[0].Reachable)
+	assert.Empty(t, checkDelegationServers("example.com.", servers))
+}
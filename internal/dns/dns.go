@@ -2,15 +2,18 @@ package dns
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"net"
 	"net/http"
 	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/miekg/dns"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/domainname"
 	resp "github.com/patrickdappollonio/mcp-domaintools/internal/response"
 	"github.com/patrickdappollonio/mcp-domaintools/internal/utils"
 	doh "github.com/shynome/doh-client"
@@ -26,9 +29,37 @@ type QueryConfig struct {
 type dnsQueryParams struct {
 	Domain     string `json:"domain"`
 	RecordType string `json:"record_type"`
+	Server     string `json:"server"`
+	Protocol   string `json:"protocol"`
+	ednsParams
+	outputParams
 }
 
-// HandleLocalDNSQuery processes local DNS queries using OS-defined DNS servers.
+// Default DNS-over-HTTPS servers used for remote queries when no server is
+// configured.
+const (
+	defaultRemoteServer  = "https://cloudflare-dns.com/dns-query"
+	fallbackRemoteServer = "https://dns.google/dns-query"
+)
+
+// outputParams selects the alternative representations of a DNS message
+// included in a query response.
+type outputParams struct {
+	IncludeText bool `json:"include_text"`
+	IncludeWire bool `json:"include_wire"`
+}
+
+// remoteDNSQueryParams represents the parameters for remote DNS queries.
+type remoteDNSQueryParams struct {
+	Domain     string `json:"domain"`
+	RecordType string `json:"record_type"`
+	Server     string `json:"server"`
+	ednsParams
+	outputParams
+}
+
+// HandleLocalDNSQuery processes local DNS queries using OS-defined DNS servers,
+// or a single server when one is given.
 func HandleLocalDNSQuery(ctx context.Context, request mcp.CallToolRequest, config *QueryConfig) (*mcp.CallToolResult, error) {
 	var params dnsQueryParams
 	if err := request.BindArguments(&params); err != nil {
@@ -44,9 +75,31 @@ func HandleLocalDNSQuery(ctx context.Context, request mcp.CallToolRequest, confi
 		return nil, fmt.Errorf("parameter \"record_type\" is required")
 	}
 
-	// Validate domain format
-	if strings.Contains(params.Domain, "..") || strings.HasPrefix(params.Domain, ".") {
-		return nil, fmt.Errorf("invalid domain format: %q", params.Domain)
+	// Validate and normalize the domain name
+	name, err := domainname.Parse(params.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	server := strings.TrimSpace(params.Server)
+	protocol := strings.ToLower(strings.TrimSpace(params.Protocol))
+
+	if server == "" && protocol != "" {
+		return nil, fmt.Errorf("parameter \"protocol\" requires a \"server\" to query")
+	}
+
+	if server != "" {
+		if strings.Contains(server, "://") {
+			return nil, fmt.Errorf("invalid server %q: use an IP, a hostname or a host:port; encrypted servers are supported by remote_dns_query", server)
+		}
+
+		if protocol == "" {
+			protocol = transportUDP
+		}
+
+		if protocol != transportUDP && protocol != transportTCP {
+			return nil, fmt.Errorf("unsupported protocol %q: must be %q or %q", params.Protocol, transportUDP, transportTCP)
+		}
 	}
 
 	recordType, err := ConvertToQType(params.RecordType)
@@ -54,39 +107,134 @@ func HandleLocalDNSQuery(ctx context.Context, request mcp.CallToolRequest, confi
 		return nil, err
 	}
 
-	// Ensure domain ends with a dot for DNS queries
-	domain := params.Domain
-	if !strings.HasSuffix(domain, ".") {
-		domain = domain + "."
-	}
-
 	// Create a new DNS message
 	m := new(dns.Msg)
-	m.SetQuestion(domain, recordType)
+	m.SetQuestion(name.FQDN(), recordType)
 	m.RecursionDesired = true
 
-	// Use local resolver for query
-	c := new(dns.Client)
-	c.Timeout = config.Timeout
+	if err := applyEDNS(m, params.ednsParams); err != nil {
+		return nil, err
+	}
 
-	var dnsResponse *dns.Msg
-	var queryErr error
+	// Send the query straight to the given server, when there's one
+	if server != "" {
+		result, err := queryServerDirect(ctx, m, server, protocol, config.Timeout, params.outputParams)
+		if err != nil {
+			return nil, err
+		}
+		result["domainName"] = name
+		return resp.JSON(result)
+	}
+
+	// Send the query to the local resolvers
+	servers, err := getSystemDNSServers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get DNS servers: %w", err)
+	}
+
+	answered, err := queryServers(ctx, m, servers, config.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	// Format the response as JSON using the response package
+	result := createDNSResponse(answered.Response)
+	result["domainName"] = name
+	result["tcpFallback"] = answered.TCPFallback
+	addMessageFormats(result, answered.Response, answered.Wire, params.outputParams)
+
+	return resp.JSON(result)
+}
+
+// queryServerDirect sends a DNS message to a single server over UDP or TCP and
+// returns the formatted response along with the server that answered, the
+// protocol used, the round-trip time and the size of the response message.
+// Truncated UDP responses are retried over TCP.
+func queryServerDirect(ctx context.Context, m *dns.Msg, server, protocol string, timeout time.Duration, output outputParams) (map[string]any, error) {
+	address := serverAddress(server)
+
+	answered, err := exchangeWithTCPFallback(ctx, m, address, protocol, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("DNS query to %s failed: %w", address, err)
+	}
+
+	if answered.TCPFallback {
+		protocol = transportTCP
+	}
 
+	result := createDNSResponse(answered.Response)
+	result["server"] = address
+	result["protocol"] = protocol
+	result["tcpFallback"] = answered.TCPFallback
+	result["rttMs"] = float64(answered.RTT.Microseconds()) / 1000
+	result["messageSize"] = len(answered.Wire)
+
+	addMessageFormats(result, answered.Response, answered.Wire, output)
+
+	return result, nil
+}
+
+// Lookup sends a recursive query for the given name and record type to the
+// OS-defined DNS servers and returns the response. It is used by other
+// packages that need to resolve records as part of a larger check.
+func Lookup(ctx context.Context, name string, qtype uint16, config *QueryConfig) (*dns.Msg, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(name), qtype)
+	m.RecursionDesired = true
+	m.SetEdns0(4096, false)
+
+	return exchangeWithSystemServers(ctx, m, config)
+}
+
+// exchangeWithSystemServers sends a DNS message to each of the OS-defined DNS
+// servers in turn and returns the first response received.
+func exchangeWithSystemServers(ctx context.Context, m *dns.Msg, config *QueryConfig) (*dns.Msg, error) {
 	// Get DNS servers in a cross-platform way
 	servers, err := getSystemDNSServers(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get DNS servers: %w", err)
 	}
 
+	return exchangeWithServers(ctx, m, servers, config)
+}
+
+// exchangeWithServers sends a DNS message to each of the given DNS servers in
+// turn and returns the first response received.
+func exchangeWithServers(ctx context.Context, m *dns.Msg, servers []string, config *QueryConfig) (*dns.Msg, error) {
+	answered, err := queryServers(ctx, m, servers, config.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	return answered.Response, nil
+}
+
+// serverResponse is a DNS response along with the server that sent it, how
+// it was obtained, and the message bytes exactly as they were received.
+type serverResponse struct {
+	Response    *dns.Msg
+	Wire        []byte
+	Server      string
+	RTT         time.Duration
+	TCPFallback bool
+}
+
+// queryServers sends a DNS message to each of the given DNS servers in turn
+// and returns the first response received, reporting whether it had to be
+// retried over TCP because the UDP response was truncated. It stops as soon
+// as the context is canceled.
+func queryServers(ctx context.Context, m *dns.Msg, servers []string, timeout time.Duration) (*serverResponse, error) {
+	var queryErr error
+
 	// Try each configured server until we get a response
 	for _, server := range servers {
-		serverAddr := server
-		if !strings.Contains(server, ":") {
-			serverAddr = net.JoinHostPort(server, "53")
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("DNS query canceled: %w", err)
 		}
-		dnsResponse, _, queryErr = c.Exchange(m, serverAddr)
-		if queryErr == nil && dnsResponse != nil {
-			break
+
+		var answered *serverResponse
+		answered, queryErr = exchangeWithTCPFallback(ctx, m, serverAddress(server), transportUDP, timeout)
+		if queryErr == nil {
+			return answered, nil
 		}
 	}
 
@@ -94,22 +242,78 @@ func HandleLocalDNSQuery(ctx context.Context, request mcp.CallToolRequest, confi
 		return nil, fmt.Errorf("DNS query failed: %w", queryErr)
 	}
 
-	if dnsResponse == nil {
-		return nil, fmt.Errorf("no response from DNS servers")
+	return nil, fmt.Errorf("no response from DNS servers")
+}
+
+// exchangeWithTCPFallback sends a DNS message to a single server over UDP or
+// TCP. A truncated UDP response is retried over TCP, as RFC 7766 requires,
+// and the response reports whether that happened.
+func exchangeWithTCPFallback(ctx context.Context, m *dns.Msg, address, protocol string, timeout time.Duration) (*serverResponse, error) {
+	c := &dns.Client{
+		Net:     protocol,
+		Timeout: timeout,
+	}
+
+	dnsResponse, wire, rtt, err := exchangeWire(ctx, c, m, address)
+	if err != nil {
+		return nil, err
 	}
 
-	// Format the response as JSON using the response package
-	result := createDNSResponse(dnsResponse)
-	return resp.JSON(result)
+	answered := &serverResponse{Response: dnsResponse, Wire: wire, Server: address, RTT: rtt}
+	if protocol != transportUDP || !dnsResponse.Truncated {
+		return answered, nil
+	}
+
+	c.Net = transportTCP
+	dnsResponse, wire, tcpRTT, err := exchangeWire(ctx, c, m, address)
+	if err != nil {
+		return nil, fmt.Errorf("TCP retry of truncated response failed: %w", err)
+	}
+
+	answered.Response, answered.Wire, answered.TCPFallback = dnsResponse, wire, true
+	answered.RTT += tcpRTT
+	return answered, nil
+}
+
+// serverAddress returns the server as a host:port pair, adding the default
+// DNS port when the server does not include one.
+func serverAddress(server string) string {
+	return hostPortWithDefault(server, "53")
+}
+
+// Sources of the system DNS servers.
+const (
+	systemDNSSourceGoResolver = "go_resolver"
+	systemDNSSourceResolvConf = "resolv_conf"
+)
+
+// systemDNSDiscovery describes the system DNS servers and how they were found.
+type systemDNSDiscovery struct {
+	Servers []string
+	Source  string
+	Skipped []string
 }
 
 // getSystemDNSServers returns a list of system DNS servers in a cross-platform way
 func getSystemDNSServers(ctx context.Context) ([]string, error) {
+	discovery, err := discoverSystemDNSServers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return discovery.Servers, nil
+}
+
+// discoverSystemDNSServers finds the system DNS servers, reporting where they
+// came from and which loopback servers were skipped.
+func discoverSystemDNSServers(ctx context.Context) (*systemDNSDiscovery, error) {
 	// Use Go's pure DNS resolver implementation with a custom dialer
 	// to capture the DNS server addresses used by the system - works on all platforms
 
-	// We'll capture any DNS servers discovered during Dial
-	discoveredServers := make([]string, 0)
+	// We'll capture any DNS servers discovered during Dial, which may happen
+	// concurrently for the A and AAAA queries
+	var mu sync.Mutex
+	discovery := &systemDNSDiscovery{Servers: make([]string, 0)}
 
 	dialer := &net.Dialer{
 		Timeout: 5 * time.Second,
@@ -121,12 +325,17 @@ func getSystemDNSServers(ctx context.Context) ([]string, error) {
 		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
 			// Extract the server IP from the address (e.g., "8.8.8.8:53")
 			host, _, err := net.SplitHostPort(address)
-			if err == nil && host != "" && host != "127.0.0.1" && host != "::1" {
-				// Prevent duplicates
-				found := slices.Contains(discoveredServers, host)
-				if !found {
-					discoveredServers = append(discoveredServers, host)
+			if err == nil && host != "" {
+				mu.Lock()
+				if host == "127.0.0.1" || host == "::1" {
+					if !slices.Contains(discovery.Skipped, host) {
+						discovery.Skipped = append(discovery.Skipped, host)
+					}
+				} else if !slices.Contains(discovery.Servers, host) {
+					// Prevent duplicates
+					discovery.Servers = append(discovery.Servers, host)
 				}
+				mu.Unlock()
 			}
 
 			// Use the standard dialer to actually make the connection
@@ -139,24 +348,31 @@ func getSystemDNSServers(ctx context.Context) ([]string, error) {
 	// to capture DNS server addresses
 	_, _ = netResolver.LookupHost(ctx, "one.one.one.one") // Cloudflare's DNS that's likely to exist
 
+	mu.Lock()
+	defer mu.Unlock()
+
 	// If we found any DNS servers through our custom dialer, return them
-	if len(discoveredServers) > 0 {
-		return discoveredServers, nil
+	if len(discovery.Servers) > 0 {
+		discovery.Source = systemDNSSourceGoResolver
+		return discovery, nil
 	}
 
 	// As a secondary fallback, try to read from /etc/resolv.conf
-	dnsConfig, err := dns.ClientConfigFromFile("/etc/resolv.conf")
+	dnsConfig, err := dns.ClientConfigFromFile(resolvConfPath)
 	if err == nil && len(dnsConfig.Servers) > 0 {
-		return dnsConfig.Servers, nil
+		discovery.Servers = dnsConfig.Servers
+		discovery.Source = systemDNSSourceResolvConf
+		return discovery, nil
 	}
 
 	// If no DNS servers were found, return an error
 	return nil, fmt.Errorf("could not discover any system DNS servers")
 }
 
-// HandleRemoteDNSQuery processes DNS queries using DNS-over-HTTPS.
+// HandleRemoteDNSQuery processes DNS queries using an encrypted remote
+// resolver: DNS-over-HTTPS, DNS-over-TLS or DNS-over-QUIC.
 func HandleRemoteDNSQuery(ctx context.Context, request mcp.CallToolRequest, config *QueryConfig) (*mcp.CallToolResult, error) {
-	var params dnsQueryParams
+	var params remoteDNSQueryParams
 	if err := request.BindArguments(&params); err != nil {
 		return nil, fmt.Errorf("failed to parse tool input: %w", utils.ParseJSONUnmarshalError(err))
 	}
@@ -170,9 +386,10 @@ func HandleRemoteDNSQuery(ctx context.Context, request mcp.CallToolRequest, conf
 		return nil, fmt.Errorf("record_type parameter is required")
 	}
 
-	// Validate domain format
-	if strings.Contains(params.Domain, "..") || strings.HasPrefix(params.Domain, ".") {
-		return nil, fmt.Errorf("invalid domain format: %s", params.Domain)
+	// Validate and normalize the domain name
+	name, err := domainname.Parse(params.Domain)
+	if err != nil {
+		return nil, err
 	}
 
 	recordType, err := ConvertToQType(params.RecordType)
@@ -180,32 +397,81 @@ func HandleRemoteDNSQuery(ctx context.Context, request mcp.CallToolRequest, conf
 		return nil, err
 	}
 
-	// Ensure domain ends with a dot for DNS queries
-	domain := params.Domain
-	if !strings.HasSuffix(domain, ".") {
-		domain = domain + "."
-	}
-
 	// Create a new DNS message
 	m := new(dns.Msg)
-	m.SetQuestion(domain, recordType)
+	m.SetQuestion(name.FQDN(), recordType)
 	m.RecursionDesired = true
 
+	if err := applyEDNS(m, params.ednsParams); err != nil {
+		return nil, err
+	}
+
+	// Use the per-call server, then the configured server, and finally
+	// default to Cloudflare
+	server := strings.TrimSpace(params.Server)
+	if server == "" {
+		server = config.RemoteServerAddress
+	}
+
+	customServer := server != ""
+	if !customServer {
+		server = defaultRemoteServer
+	}
+
+	target, err := parseRemoteServer(server)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send the query
+	dnsResponse, wire, _, err := exchangeWithResolver(ctx, m, target, config.Timeout)
+	if err != nil {
+		// Try Google as fallback if not using custom server
+		if customServer {
+			return nil, err
+		}
+
+		target, _ = parseRemoteServer(fallbackRemoteServer)
+		dnsResponse, wire, _, err = exchangeWithResolver(ctx, m, target, config.Timeout)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Format the response as JSON using the response package
+	result := createDNSResponse(dnsResponse)
+	result["domainName"] = name
+	result["transport"] = target.Transport
+	result["server"] = target.Name
+
+	addMessageFormats(result, dnsResponse, wire, params.outputParams)
+
+	return resp.JSON(result)
+}
+
+// parseRemoteServer parses the address of a remote resolver, which must use
+// an encrypted transport selected by its scheme.
+func parseRemoteServer(server string) (resolverTarget, error) {
+	lower := strings.ToLower(server)
+	if !strings.HasPrefix(lower, "https://") && !strings.HasPrefix(lower, "tls://") && !strings.HasPrefix(lower, "quic://") {
+		return resolverTarget{}, fmt.Errorf("unsupported remote server %q: must start with https:// (DoH), tls:// (DoT) or quic:// (DoQ)", server)
+	}
+
+	return parseResolverURL(server)
+}
+
+// exchangeDoH sends a DNS message to a DNS-over-HTTPS server and reads the
+// response, returning it along with the message bytes as received.
+func exchangeDoH(ctx context.Context, m *dns.Msg, dohServer string, timeout time.Duration) (*dns.Msg, []byte, error) {
 	// Create HTTP client with timeout
 	httpClient := &http.Client{
-		Timeout: config.Timeout,
+		Timeout: timeout,
 	}
 
 	// Set timeout context
-	ctxWithTimeout, cancel := context.WithTimeout(ctx, config.Timeout)
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	// Use the specified DoH server or default to Cloudflare
-	dohServer := "https://cloudflare-dns.com/dns-query"
-	if config.RemoteServerAddress != "" {
-		dohServer = config.RemoteServerAddress
-	}
-
 	// Create DoH connection
 	conn := doh.NewConn(httpClient, ctxWithTimeout, dohServer)
 
@@ -213,30 +479,22 @@ func HandleRemoteDNSQuery(ctx context.Context, request mcp.CallToolRequest, conf
 	dnsConn := &dns.Conn{Conn: conn}
 
 	// Send the query
-	err = dnsConn.WriteMsg(m)
-	if err != nil {
-		// Try Google as fallback if not using custom server
-		if config.RemoteServerAddress == "" {
-			conn = doh.NewConn(httpClient, ctxWithTimeout, "https://dns.google/dns-query")
-			dnsConn = &dns.Conn{Conn: conn}
-			err = dnsConn.WriteMsg(m)
-			if err != nil {
-				return nil, fmt.Errorf("DNS-over-HTTPS query failed: %v", err)
-			}
-		} else {
-			return nil, fmt.Errorf("DNS-over-HTTPS query failed: %v", err)
-		}
+	if err := dnsConn.WriteMsg(m); err != nil {
+		return nil, nil, fmt.Errorf("DNS-over-HTTPS query failed: %v", err)
 	}
 
-	// Read the response
-	dnsResponse, err := dnsConn.ReadMsg()
+	// Read the response, keeping its bytes
+	wire, err := dnsConn.ReadMsgHeader(nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read DNS response: %v", err)
+		return nil, nil, fmt.Errorf("failed to read DNS response: %v", err)
 	}
 
-	// Format the response as JSON using the response package
-	result := createDNSResponse(dnsResponse)
-	return resp.JSON(result)
+	dnsResponse := new(dns.Msg)
+	if err := dnsResponse.Unpack(wire); err != nil {
+		return nil, nil, fmt.Errorf("failed to unpack DNS response: %v", err)
+	}
+
+	return dnsResponse, wire, nil
 }
 
 // ConvertToQType converts a string record type to the corresponding DNS query type.
@@ -252,10 +510,15 @@ func ConvertToQType(recordType string) (uint16, error) {
 func createDNSResponse(response *dns.Msg) map[string]any {
 	// Create a response structure that matches DNS public JSON spec
 	result := map[string]any{
-		"status":            response.Rcode,
-		"truncated":         response.Truncated,
-		"authenticatedData": response.AuthenticatedData,
-		"checkingDisabled":  response.CheckingDisabled,
+		"id":                 response.Id,
+		"opcode":             dns.OpcodeToString[response.Opcode],
+		"status":             response.Rcode,
+		"authoritative":      response.Authoritative,
+		"truncated":          response.Truncated,
+		"recursionDesired":   response.RecursionDesired,
+		"recursionAvailable": response.RecursionAvailable,
+		"authenticatedData":  response.AuthenticatedData,
+		"checkingDisabled":   response.CheckingDisabled,
 	}
 
 	// Add the question section
@@ -275,63 +538,77 @@ func createDNSResponse(response *dns.Msg) map[string]any {
 	}
 	result["question"] = questions
 
-	// Add the answer section
+	// Add the answer, authority and additional sections
+	if answers := formatResourceRecords(response.Answer); len(answers) > 0 {
+		result["answer"] = answers
+	}
+
+	if authority := formatResourceRecords(response.Ns); len(authority) > 0 {
+		result["authority"] = authority
+	}
+
+	// The OPT pseudo-record is decoded separately below
+	additional := make([]dns.RR, 0, len(response.Extra))
+	for _, rr := range response.Extra {
+		if rr.Header().Rrtype != dns.TypeOPT {
+			additional = append(additional, rr)
+		}
+	}
+
+	if extra := formatResourceRecords(additional); len(extra) > 0 {
+		result["additional"] = extra
+	}
+
+	// Add human-readable DNS status code
+	if response.Rcode >= 0 && response.Rcode < len(dns.RcodeToString) {
+		result["statusMessage"] = dns.RcodeToString[response.Rcode]
+	}
+
+	// Decode the EDNS options, keeping the client subnet at the top level
+	if opt := response.IsEdns0(); opt != nil {
+		result["edns"] = formatEDNS(opt)
+
+		for _, o := range opt.Option {
+			if subnet, ok := o.(*dns.EDNS0_SUBNET); ok {
+				mask := subnet.SourceNetmask
+				result["ednsClientSubnet"] = fmt.Sprintf("%s/%d", subnet.Address.String(), mask)
+				break
+			}
+		}
+	}
+
+	return result
+}
+
+// formatResourceRecords converts resource records into JSON-serializable maps
+// containing the owner name, type, class, TTL, the record data in
+// presentation format and the typed record data fields.
+func formatResourceRecords(records []dns.RR) []map[string]any {
 	answers := []map[string]any{}
-	for _, a := range response.Answer {
+	for _, a := range records {
 		// Extract the data based on record type
 		var data string
 		switch a.Header().Rrtype {
-		case dns.TypeA:
-			if rec, ok := a.(*dns.A); ok {
-				data = rec.A.String()
-			}
-		case dns.TypeAAAA:
-			if rec, ok := a.(*dns.AAAA); ok {
-				data = rec.AAAA.String()
-			}
 		case dns.TypeCAA:
 			if rec, ok := a.(*dns.CAA); ok {
 				data = fmt.Sprintf("%d %s %q", rec.Flag, rec.Tag, rec.Value)
 			}
-		case dns.TypeCNAME:
-			if rec, ok := a.(*dns.CNAME); ok {
-				data = rec.Target
-			}
-		case dns.TypeMX:
-			if rec, ok := a.(*dns.MX); ok {
-				data = fmt.Sprintf("%d %s", rec.Preference, rec.Mx)
-			}
-		case dns.TypeNS:
-			if rec, ok := a.(*dns.NS); ok {
-				data = rec.Ns
-			}
-		case dns.TypePTR:
-			if rec, ok := a.(*dns.PTR); ok {
-				data = rec.Ptr
-			}
-		case dns.TypeSOA:
-			if rec, ok := a.(*dns.SOA); ok {
-				data = fmt.Sprintf("%s %s %d %d %d %d %d",
-					rec.Ns, rec.Mbox, rec.Serial, rec.Refresh, rec.Retry, rec.Expire, rec.Minttl)
-			}
-		case dns.TypeSRV:
-			if rec, ok := a.(*dns.SRV); ok {
-				data = fmt.Sprintf("%d %d %d %s", rec.Priority, rec.Weight, rec.Port, rec.Target)
-			}
 		case dns.TypeTXT:
 			if rec, ok := a.(*dns.TXT); ok {
 				data = strings.Join(rec.Txt, " ")
 			}
 		default:
-			// Fall back to the string representation
-			data = strings.Fields(a.String())[4]
+			// Use the presentation format without the header fields
+			data = recordDataString(a)
 		}
 
 		answer := map[string]interface{}{
-			"name": a.Header().Name,
-			"type": a.Header().Rrtype,
-			"TTL":  a.Header().Ttl,
-			"data": data,
+			"name":  a.Header().Name,
+			"type":  a.Header().Rrtype,
+			"class": dns.ClassToString[a.Header().Class],
+			"TTL":   a.Header().Ttl,
+			"data":  data,
+			"rdata": recordFields(a),
 		}
 
 		// Add human-readable record type name
@@ -339,30 +616,26 @@ func createDNSResponse(response *dns.Msg) map[string]any {
 			answer["typeName"] = typeName
 		}
 
+		// Decode the service parameters of SVCB and HTTPS records
+		if svcb, ok := svcbData(a); ok {
+			answer["svcb"] = decodeSVCB(svcb)
+		}
+
 		answers = append(answers, answer)
 	}
 
-	if len(answers) > 0 {
-		result["answer"] = answers
-	}
+	return answers
+}
 
-	// Add human-readable DNS status code
-	if response.Rcode >= 0 && response.Rcode < len(dns.RcodeToString) {
-		result["statusMessage"] = dns.RcodeToString[response.Rcode]
+// addMessageFormats adds the requested alternative representations of a DNS
+// message to the result: the dig-style presentation text and the message
+// bytes as received, encoded in base64.
+func addMessageFormats(result map[string]any, response *dns.Msg, wire []byte, params outputParams) {
+	if params.IncludeText {
+		result["text"] = response.String()
 	}
 
-	// Check for EDNS client subnet
-	for _, extra := range response.Extra {
-		if opt, ok := extra.(*dns.OPT); ok {
-			for _, o := range opt.Option {
-				if subnet, ok := o.(*dns.EDNS0_SUBNET); ok {
-					mask := subnet.SourceNetmask
-					result["ednsClientSubnet"] = fmt.Sprintf("%s/%d", subnet.Address.String(), mask)
-					break
-				}
-			}
-		}
+	if params.IncludeWire {
+		result["wire"] = base64.StdEncoding.EncodeToString(wire)
 	}
-
-	return result
 }
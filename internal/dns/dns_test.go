@@ -0,0 +1,185 @@
+package dns
+
+import (
+	"context"
+	"encoding/base64"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startTestDNSServer starts a DNS server on localhost for the given network,
+// "udp" or "tcp", and returns its address once it's serving. The options
+// adjust the server before it starts, such as its address or TSIG secrets.
+// The server is shut down when the test ends.
+func startTestDNSServer(t *testing.T, network string, handler dns.HandlerFunc, options ...func(*dns.Server)) string {
+	t.Helper()
+
+	server := &dns.Server{Addr: "127.0.0.1:0", Handler: handler}
+	for _, option := range options {
+		option(server)
+	}
+
+	if network == "udp" {
+		conn, err := net.ListenPacket("udp", server.Addr)
+		require.NoError(t, err)
+		server.PacketConn = conn
+	} else {
+		listener, err := net.Listen("tcp", server.Addr)
+		require.NoError(t, err)
+		server.Listener = listener
+	}
+
+	started := make(chan struct{})
+	server.NotifyStartedFunc = func() { close(started) }
+	go func() { _ = server.ActivateAndServe() }()
+	<-started
+	t.Cleanup(func() { _ = server.Shutdown() })
+
+	if server.PacketConn != nil {
+		return server.PacketConn.LocalAddr().String()
+	}
+	return server.Listener.Addr().String()
+}
+
+// startAnswerServer starts a DNS server on localhost for the given network
+// that answers every query with the given records.
+func startAnswerServer(t *testing.T, network string, records []dns.RR) string {
+	t.Helper()
+
+	return startTestDNSServer(t, network, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Authoritative = true
+		m.Answer = records
+		_ = w.WriteMsg(m)
+	})
+}
+
+func TestQueryServerDirect(t *testing.T) {
+	records := mustRRs(t, "example.com. 300 IN A 192.0.2.1")
+
+	for _, protocol := range []string{transportUDP, transportTCP} {
+		t.Run(protocol, func(t *testing.T) {
+			address := startAnswerServer(t, protocol, records)
+
+			m := new(dns.Msg)
+			m.SetQuestion("example.com.", dns.TypeA)
+
+			result, err := queryServerDirect(context.Background(), m, address, protocol, 2*time.Second, outputParams{})
+			require.NoError(t, err)
+
+			assert.Equal(t, address, result["server"])
+			assert.Equal(t, protocol, result["protocol"])
+			assert.Positive(t, result["messageSize"])
+			assert.GreaterOrEqual(t, result["rttMs"], 0.0)
+
+			answers := result["answer"].([]map[string]any)
+			require.Len(t, answers, 1)
+			assert.Equal(t, "192.0.2.1", answers[0]["data"])
+		})
+	}
+
+	t.Run("wire keeps the received bytes", func(t *testing.T) {
+		sentc := make(chan []byte, 1)
+		address := startTestDNSServer(t, transportUDP, func(w dns.ResponseWriter, r *dns.Msg) {
+			m := new(dns.Msg)
+			m.SetReply(r)
+			m.Answer = records
+			m.Compress = true
+			packed, _ := m.Pack()
+			_, _ = w.Write(packed)
+			sentc <- packed
+		})
+
+		m := new(dns.Msg)
+		m.SetQuestion("example.com.", dns.TypeA)
+
+		result, err := queryServerDirect(context.Background(), m, address, transportUDP, 2*time.Second, outputParams{IncludeWire: true})
+		require.NoError(t, err)
+		sent := <-sentc
+
+		// Packing the parsed response again would drop the name compression
+		assert.Equal(t, base64.StdEncoding.EncodeToString(sent), result["wire"])
+		assert.Equal(t, len(sent), result["messageSize"])
+	})
+
+	t.Run("unreachable server", func(t *testing.T) {
+		m := new(dns.Msg)
+		m.SetQuestion("example.com.", dns.TypeA)
+
+		// Nothing listens on this TCP port once the listener is closed
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		address := listener.Addr().String()
+		require.NoError(t, listener.Close())
+
+		_, err = queryServerDirect(context.Background(), m, address, transportTCP, time.Second, outputParams{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), address)
+	})
+}
+
+// startTruncatingServer starts a DNS server on localhost that sets the TC bit
+// on every UDP response and answers with the given records over TCP, on the
+// same port.
+func startTruncatingServer(t *testing.T, records []dns.RR) string {
+	t.Helper()
+
+	handler := func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		if _, ok := w.RemoteAddr().(*net.UDPAddr); ok {
+			m.Truncated = true
+		} else {
+			m.Answer = records
+		}
+		_ = w.WriteMsg(m)
+	}
+
+	address := startTestDNSServer(t, "udp", handler)
+	startTestDNSServer(t, "tcp", handler, func(server *dns.Server) { server.Addr = address })
+	return address
+}
+
+func TestTCPFallback(t *testing.T) {
+	records := mustRRs(t,
+		`example.com. 300 IN TXT "first"`,
+		`example.com. 300 IN TXT "second"`,
+	)
+	address := startTruncatingServer(t, records)
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.com.", dns.TypeTXT)
+
+	t.Run("direct query", func(t *testing.T) {
+		result, err := queryServerDirect(context.Background(), m, address, transportUDP, 2*time.Second, outputParams{})
+		require.NoError(t, err)
+
+		assert.Equal(t, true, result["tcpFallback"])
+		assert.Equal(t, transportTCP, result["protocol"])
+		assert.Equal(t, false, result["truncated"])
+		assert.Len(t, result["answer"], 2)
+	})
+
+	t.Run("server list", func(t *testing.T) {
+		answered, err := queryServers(context.Background(), m, []string{address}, 2*time.Second)
+		require.NoError(t, err)
+
+		assert.True(t, answered.TCPFallback)
+		assert.Equal(t, address, answered.Server)
+		assert.Len(t, answered.Response.Answer, 2)
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := queryServers(ctx, m, []string{address}, 2*time.Second)
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,919 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/miekg/dns"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/domainname"
+	resp "github.com/patrickdappollonio/mcp-domaintools/internal/response"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/utils"
+)
+
+// DNSSEC validation states, as defined in RFC 4035 section 4.3.
+const (
+	dnssecSecure        = "secure"
+	dnssecInsecure      = "insecure"
+	dnssecBogus         = "bogus"
+	dnssecIndeterminate = "indeterminate"
+)
+
+// signatureExpiryWarning is how close to expiration a signature must be
+// before a warning is added to the report.
+const signatureExpiryWarning = 72 * time.Hour
+
+// rootTrustAnchors contains the DS records for the root zone KSKs published by
+// IANA at https://data.iana.org/root-anchors/root-anchors.xml.
+var rootTrustAnchors = []string{
+	". IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D",
+	". IN DS 38696 8 2 683D2D0ACB8C9B712A1948B27F741219298D0A450D612C483AF444A4C0FB2B16",
+}
+
+// dnssecParams represents the parameters for DNSSEC validation.
+type dnssecParams struct {
+	Domain     string `json:"domain"`
+	RecordType string `json:"record_type"`
+}
+
+// DNSSECKey represents a DNSKEY record found at a zone apex.
+type DNSSECKey struct {
+	KeyTag    uint16 `json:"key_tag"`
+	Algorithm string `json:"algorithm"`
+	Flags     uint16 `json:"flags"`
+	Role      string `json:"role"`
+	Revoked   bool   `json:"revoked,omitempty"`
+	MatchesDS bool   `json:"matches_ds"`
+}
+
+// DNSSECDelegationSigner represents a DS record published by a parent zone.
+type DNSSECDelegationSigner struct {
+	KeyTag     uint16 `json:"key_tag"`
+	Algorithm  string `json:"algorithm"`
+	DigestType string `json:"digest_type"`
+	Digest     string `json:"digest"`
+	Matched    bool   `json:"matched"`
+}
+
+// DNSSECSignature represents an RRSIG record and the outcome of verifying it.
+type DNSSECSignature struct {
+	TypeCovered    string    `json:"type_covered"`
+	KeyTag         uint16    `json:"key_tag"`
+	Algorithm      string    `json:"algorithm"`
+	SignerName     string    `json:"signer_name"`
+	Inception      time.Time `json:"inception"`
+	Expiration     time.Time `json:"expiration"`
+	ExpiresInHours int       `json:"expires_in_hours"`
+	Valid          bool      `json:"valid"`
+	Error          string    `json:"error,omitempty"`
+}
+
+// DNSSECZone represents the validation state of one zone in the chain of trust.
+type DNSSECZone struct {
+	Zone       string                   `json:"zone"`
+	Status     string                   `json:"status"`
+	DS         []DNSSECDelegationSigner `json:"ds,omitempty"`
+	DSSource   string                   `json:"ds_source,omitempty"`
+	DNSKEYs    []DNSSECKey              `json:"dnskeys,omitempty"`
+	Signatures []DNSSECSignature        `json:"signatures,omitempty"`
+	Warnings   []string                 `json:"warnings,omitempty"`
+	Errors     []string                 `json:"errors,omitempty"`
+}
+
+// DNSSECAnswer represents the validation state of the queried RRset.
+type DNSSECAnswer struct {
+	Status     string            `json:"status"`
+	Response   map[string]any    `json:"response,omitempty"`
+	Signatures []DNSSECSignature `json:"signatures,omitempty"`
+	Errors     []string          `json:"errors,omitempty"`
+}
+
+// DNSSECResponse represents the complete DNSSEC validation response.
+type DNSSECResponse struct {
+	Domain     string           `json:"domain"`
+	DomainName *domainname.Name `json:"domain_name,omitempty"`
+	RecordType string           `json:"record_type"`
+	Status     string           `json:"status"`
+	Zones      []DNSSECZone     `json:"zones"`
+	Answer     DNSSECAnswer     `json:"answer"`
+	FailedLink string           `json:"failed_link,omitempty"`
+	Timestamp  string           `json:"timestamp"`
+}
+
+// HandleDNSSECValidation validates the DNSSEC chain of trust from the root
+// zone down to the requested name and record type.
+func HandleDNSSECValidation(ctx context.Context, request mcp.CallToolRequest, config *QueryConfig) (*mcp.CallToolResult, error) {
+	var params dnssecParams
+	if err := request.BindArguments(&params); err != nil {
+		return nil, fmt.Errorf("failed to parse tool input: %w", utils.ParseJSONUnmarshalError(err))
+	}
+
+	// Validate required parameters
+	if params.Domain == "" {
+		return nil, fmt.Errorf("parameter \"domain\" is required")
+	}
+
+	// Set default record type if not provided
+	if params.RecordType == "" {
+		params.RecordType = "A"
+	}
+
+	// Validate and normalize the domain name
+	name, err := domainname.Parse(params.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	recordType, err := ConvertToQType(params.RecordType)
+	if err != nil {
+		return nil, err
+	}
+
+	result := validateChainOfTrust(ctx, name.FQDN(), recordType, config)
+	result.DomainName = name
+	return resp.JSON(result)
+}
+
+// validateChainOfTrust walks every zone between the root and the queried
+// name, validating DS and DNSKEY records at each delegation, and finally
+// validates the signatures on the queried RRset.
+func validateChainOfTrust(ctx context.Context, domain string, qtype uint16, config *QueryConfig) *DNSSECResponse {
+	result := &DNSSECResponse{
+		Domain:     domain,
+		RecordType: dns.TypeToString[qtype],
+		Zones:      make([]DNSSECZone, 0),
+		Timestamp:  time.Now().Format(time.RFC3339),
+	}
+
+	zones, err := findZoneCuts(ctx, domain, config)
+	if err != nil {
+		result.Status = dnssecIndeterminate
+		result.FailedLink = fmt.Sprintf("unable to determine zone cuts: %v", err)
+		return result
+	}
+
+	now := time.Now()
+	status := dnssecSecure
+	trustedKeys := make(map[string][]*dns.DNSKEY)
+	var parentKeys []*dns.DNSKEY
+
+	for i, zone := range zones {
+		zr := DNSSECZone{Zone: zone}
+
+		// Once the chain becomes insecure or bogus, every zone below inherits it
+		if status != dnssecSecure {
+			zr.Status = status
+			result.Zones = append(result.Zones, zr)
+			continue
+		}
+
+		// Find the DS records that vouch for this zone's keys
+		var dsSet []*dns.DS
+		if i == 0 {
+			dsSet = parseTrustAnchors()
+			zr.DSSource = "IANA root trust anchor"
+			for _, ds := range dsSet {
+				zr.DS = append(zr.DS, delegationSignerInfo(ds))
+			}
+		} else {
+			zr.DSSource = zones[i-1]
+			var insecure bool
+			dsSet, insecure, err = fetchDelegationSigners(ctx, zone, parentKeys, now, &zr, config)
+			switch {
+			case err != nil:
+				zr.Status = dnssecBogus
+				if isIndeterminate(err) {
+					zr.Status = dnssecIndeterminate
+				}
+				zr.Errors = append(zr.Errors, err.Error())
+				result.FailedLink = fmt.Sprintf("%s -> %s: %v", zones[i-1], zone, err)
+			case insecure:
+				zr.Status = dnssecInsecure
+			}
+		}
+
+		if zr.Status != "" {
+			status = zr.Status
+			result.Zones = append(result.Zones, zr)
+			continue
+		}
+
+		keys, err := fetchZoneKeys(ctx, zone, dsSet, now, &zr, config)
+		if err != nil {
+			zr.Status = dnssecBogus
+			if isIndeterminate(err) {
+				zr.Status = dnssecIndeterminate
+			}
+			zr.Errors = append(zr.Errors, err.Error())
+			result.FailedLink = fmt.Sprintf("%s DNSKEY: %v", zone, err)
+			status = zr.Status
+			result.Zones = append(result.Zones, zr)
+			continue
+		}
+
+		zr.Status = dnssecSecure
+		trustedKeys[dns.CanonicalName(zone)] = keys
+		parentKeys = keys
+		result.Zones = append(result.Zones, zr)
+	}
+
+	// Finally, validate the requested RRset itself
+	result.Answer = validateAnswer(ctx, domain, qtype, status, trustedKeys, now, config)
+	result.Status = result.Answer.Status
+	if result.FailedLink == "" && result.Answer.Status == dnssecBogus && len(result.Answer.Errors) > 0 {
+		result.FailedLink = fmt.Sprintf("%s %s: %s", domain, dns.TypeToString[qtype], result.Answer.Errors[0])
+	}
+
+	return result
+}
+
+// indeterminateError marks failures caused by being unable to fetch data,
+// rather than by data that failed validation.
+type indeterminateError struct {
+	err error
+}
+
+func (e *indeterminateError) Error() string { return e.err.Error() }
+
+func (e *indeterminateError) Unwrap() error { return e.err }
+
+// isIndeterminate reports whether the error is an indeterminateError.
+func isIndeterminate(err error) bool {
+	var indeterminate *indeterminateError
+	return errors.As(err, &indeterminate)
+}
+
+// queryDNSSEC sends a DNSSEC-aware query to the local resolvers. The CD bit
+// is set so that resolvers return data even when their own validation fails,
+// allowing us to determine why.
+func queryDNSSEC(ctx context.Context, name string, qtype uint16, config *QueryConfig) (*dns.Msg, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(name, qtype)
+	m.RecursionDesired = true
+	m.CheckingDisabled = true
+	m.SetEdns0(4096, true)
+
+	response, err := exchangeWithSystemServers(ctx, m, config)
+	if err != nil {
+		return nil, &indeterminateError{err: err}
+	}
+
+	if response.Rcode != dns.RcodeSuccess && response.Rcode != dns.RcodeNameError {
+		return nil, &indeterminateError{err: fmt.Errorf("query for %s %s returned %s", name, dns.TypeToString[qtype], dns.RcodeToString[response.Rcode])}
+	}
+
+	return response, nil
+}
+
+// findZoneCuts returns the zones between the root and the given name,
+// inclusive, by asking for the SOA record at each ancestor.
+func findZoneCuts(ctx context.Context, domain string, config *QueryConfig) ([]string, error) {
+	zones := []string{"."}
+
+	labels := dns.SplitDomainName(domain)
+	for i := len(labels) - 1; i >= 0; i-- {
+		name := dns.Fqdn(strings.Join(labels[i:], "."))
+
+		response, err := queryDNSSEC(ctx, name, dns.TypeSOA, config)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, rr := range response.Answer {
+			if soa, ok := rr.(*dns.SOA); ok && strings.EqualFold(soa.Header().Name, name) {
+				zones = append(zones, name)
+				break
+			}
+		}
+	}
+
+	return zones, nil
+}
+
+// parseTrustAnchors parses the built-in root zone trust anchors.
+func parseTrustAnchors() []*dns.DS {
+	anchors := make([]*dns.DS, 0, len(rootTrustAnchors))
+	for _, anchor := range rootTrustAnchors {
+		rr, err := dns.NewRR(anchor)
+		if err != nil {
+			continue
+		}
+		if ds, ok := rr.(*dns.DS); ok {
+			anchors = append(anchors, ds)
+		}
+	}
+	return anchors
+}
+
+// fetchDelegationSigners fetches the DS RRset for a zone from its parent and
+// verifies it with the parent's keys. When the parent proves that no DS
+// exists, the delegation is reported as insecure.
+func fetchDelegationSigners(ctx context.Context, zone string, parentKeys []*dns.DNSKEY, now time.Time, zr *DNSSECZone, config *QueryConfig) ([]*dns.DS, bool, error) {
+	response, err := queryDNSSEC(ctx, zone, dns.TypeDS, config)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var dsSet []*dns.DS
+	var dsRRs []dns.RR
+	for _, rr := range response.Answer {
+		if ds, ok := rr.(*dns.DS); ok && strings.EqualFold(ds.Header().Name, zone) {
+			dsSet = append(dsSet, ds)
+			dsRRs = append(dsRRs, ds)
+		}
+	}
+
+	// No DS records: the parent must prove their absence
+	if len(dsSet) == 0 {
+		sigs, err := verifyDSDenial(zone, response, parentKeys, now)
+		zr.Signatures = append(zr.Signatures, sigs...)
+		if err != nil {
+			return nil, false, err
+		}
+		zr.Warnings = append(zr.Warnings, "parent zone proves that no DS record exists, so this delegation is unsigned")
+		return nil, true, nil
+	}
+
+	for _, ds := range dsSet {
+		zr.DS = append(zr.DS, delegationSignerInfo(ds))
+	}
+
+	sigs, ok := verifyRRSet(dsRRs, rrsigsFor(response.Answer, zone, dns.TypeDS), parentKeys, now)
+	zr.Signatures = append(zr.Signatures, sigs...)
+	addExpiryWarnings(zr, sigs, now)
+	if !ok {
+		return nil, false, fmt.Errorf("DS RRset is not signed by a valid key of the parent zone")
+	}
+
+	return dsSet, false, nil
+}
+
+// fetchZoneKeys fetches the DNSKEY RRset for a zone, checks that at least one
+// key matches a trusted DS record and that the RRset is signed by such a key.
+func fetchZoneKeys(ctx context.Context, zone string, dsSet []*dns.DS, now time.Time, zr *DNSSECZone, config *QueryConfig) ([]*dns.DNSKEY, error) {
+	response, err := queryDNSSEC(ctx, zone, dns.TypeDNSKEY, config)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []*dns.DNSKEY
+	var keyRRs []dns.RR
+	for _, rr := range response.Answer {
+		if key, ok := rr.(*dns.DNSKEY); ok && strings.EqualFold(key.Header().Name, zone) {
+			keys = append(keys, key)
+			keyRRs = append(keyRRs, key)
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("zone has DS records but publishes no DNSKEY records")
+	}
+
+	// Match DS records to DNSKEYs by recomputing the digest
+	var secureEntryPoints []*dns.DNSKEY
+	for _, key := range keys {
+		entry := DNSSECKey{
+			KeyTag:    key.KeyTag(),
+			Algorithm: algorithmName(key.Algorithm),
+			Flags:     key.Flags,
+			Role:      keyRole(key),
+			Revoked:   key.Flags&dns.REVOKE != 0,
+		}
+
+		for i, ds := range dsSet {
+			if matchesDS(key, ds) && !entry.Revoked {
+				entry.MatchesDS = true
+				secureEntryPoints = append(secureEntryPoints, key)
+				if i < len(zr.DS) {
+					zr.DS[i].Matched = true
+				}
+			}
+		}
+
+		zr.DNSKEYs = append(zr.DNSKEYs, entry)
+	}
+
+	if len(secureEntryPoints) == 0 {
+		return nil, fmt.Errorf("no DNSKEY matches any DS record published by the parent zone")
+	}
+
+	sigs, ok := verifyRRSet(keyRRs, rrsigsFor(response.Answer, zone, dns.TypeDNSKEY), secureEntryPoints, now)
+	zr.Signatures = append(zr.Signatures, sigs...)
+	addExpiryWarnings(zr, sigs, now)
+	if !ok {
+		return nil, fmt.Errorf("DNSKEY RRset is not signed by a key matching a DS record")
+	}
+
+	return keys, nil
+}
+
+// validateAnswer queries the requested RRset and verifies its signatures
+// with the keys of the zone that signed it.
+func validateAnswer(ctx context.Context, domain string, qtype uint16, chainStatus string, trustedKeys map[string][]*dns.DNSKEY, now time.Time, config *QueryConfig) DNSSECAnswer {
+	response, err := queryDNSSEC(ctx, domain, qtype, config)
+	if err != nil {
+		return DNSSECAnswer{Status: dnssecIndeterminate, Errors: []string{err.Error()}}
+	}
+
+	if chainStatus != dnssecSecure {
+		return DNSSECAnswer{Status: chainStatus, Response: createDNSResponse(response)}
+	}
+
+	return validateResponse(domain, qtype, response, trustedKeys, now)
+}
+
+// validateResponse verifies the signatures of a response with the keys of
+// the validated chain. Negative answers and answers synthesized from a
+// wildcard must also carry the NSEC or NSEC3 records that prove them.
+func validateResponse(domain string, qtype uint16, response *dns.Msg, trustedKeys map[string][]*dns.DNSKEY, now time.Time) DNSSECAnswer {
+	answer := DNSSECAnswer{Status: dnssecSecure, Response: createDNSResponse(response)}
+	negative := len(response.Answer) == 0
+
+	// Group the answer section into RRsets and verify each of them
+	var expanded []*dns.RRSIG
+	for _, rrset := range groupRRSets(response.Answer) {
+		sig := verifyAnswerRRSet(&answer, response.Answer, rrset, trustedKeys, now)
+		if sig != nil && isWildcardExpansion(rrset[0].Header().Name, sig) {
+			expanded = append(expanded, sig)
+		}
+	}
+
+	// The NSEC/NSEC3 records in the authority section carry the proofs. The
+	// rest of it is only verified for negative answers, where the SOA record
+	// is signed too.
+	var proof denialProof
+	for _, rrset := range groupRRSets(response.Ns) {
+		rrtype := rrset[0].Header().Rrtype
+		denial := rrtype == dns.TypeNSEC || rrtype == dns.TypeNSEC3
+		if !negative && !denial {
+			continue
+		}
+
+		if sig := verifyAnswerRRSet(&answer, response.Ns, rrset, trustedKeys, now); sig != nil && denial {
+			proof.add(rrset)
+		}
+	}
+
+	switch {
+	case negative && response.Rcode == dns.RcodeNameError:
+		if !proof.provesNameError(domain) {
+			answer.Status = dnssecBogus
+			answer.Errors = append(answer.Errors, fmt.Sprintf("no valid NSEC or NSEC3 record proves that %s does not exist", domain))
+		}
+	case negative:
+		if !proof.provesNoData(domain, qtype) {
+			answer.Status = dnssecBogus
+			answer.Errors = append(answer.Errors, fmt.Sprintf("no valid NSEC or NSEC3 record proves that %s has no %s records", domain, dns.TypeToString[qtype]))
+		}
+	}
+
+	for _, sig := range expanded {
+		if !proof.provesExpansion(sig.Header().Name, int(sig.Labels)) {
+			answer.Status = dnssecBogus
+			answer.Errors = append(answer.Errors, fmt.Sprintf("%s %s RRset was synthesized from a wildcard, but no valid NSEC or NSEC3 record proves that %s does not exist", sig.Header().Name, dns.TypeToString[sig.TypeCovered], sig.Header().Name))
+		}
+	}
+
+	return answer
+}
+
+// verifyAnswerRRSet verifies an RRset of a response section, recording the
+// signatures and any error in the answer. It returns a valid signature of the
+// RRset, or nil when there is none.
+func verifyAnswerRRSet(answer *DNSSECAnswer, section []dns.RR, rrset []dns.RR, trustedKeys map[string][]*dns.DNSKEY, now time.Time) *dns.RRSIG {
+	header := rrset[0].Header()
+	sigs := rrsigsFor(section, header.Name, header.Rrtype)
+	if len(sigs) == 0 {
+		answer.Status = dnssecBogus
+		answer.Errors = append(answer.Errors, fmt.Sprintf("%s %s RRset has no signatures", header.Name, dns.TypeToString[header.Rrtype]))
+		return nil
+	}
+
+	keys, ok := trustedKeys[dns.CanonicalName(sigs[0].SignerName)]
+	if !ok {
+		if answer.Status == dnssecSecure {
+			answer.Status = dnssecIndeterminate
+		}
+		answer.Errors = append(answer.Errors, fmt.Sprintf("%s %s RRset is signed by %s, which is outside the validated chain", header.Name, dns.TypeToString[header.Rrtype], sigs[0].SignerName))
+		return nil
+	}
+
+	results, _ := verifyRRSet(rrset, sigs, keys, now)
+	answer.Signatures = append(answer.Signatures, results...)
+	for i, result := range results {
+		if result.Valid {
+			return sigs[i]
+		}
+	}
+
+	answer.Status = dnssecBogus
+	answer.Errors = append(answer.Errors, fmt.Sprintf("%s %s RRset has no valid signature", header.Name, dns.TypeToString[header.Rrtype]))
+	return nil
+}
+
+// isWildcardExpansion reports whether an RRSIG covers an RRset synthesized
+// from a wildcard: its Labels field counts fewer labels than the owner name
+// has, not counting a leading "*" (RFC 4035 section 5.3.4).
+func isWildcardExpansion(owner string, sig *dns.RRSIG) bool {
+	labels := dns.CountLabel(owner)
+	if strings.HasPrefix(owner, "*.") {
+		labels--
+	}
+	return int(sig.Labels) < labels
+}
+
+// verifyDSDenial checks that a response without DS records carries signed
+// NSEC or NSEC3 records proving that the DS record does not exist.
+func verifyDSDenial(zone string, response *dns.Msg, parentKeys []*dns.DNSKEY, now time.Time) ([]DNSSECSignature, error) {
+	var results []DNSSECSignature
+	var proof denialProof
+
+	for _, rrset := range groupRRSets(response.Ns) {
+		header := rrset[0].Header()
+		if header.Rrtype != dns.TypeNSEC && header.Rrtype != dns.TypeNSEC3 {
+			continue
+		}
+
+		sigs, ok := verifyRRSet(rrset, rrsigsFor(response.Ns, header.Name, header.Rrtype), parentKeys, now)
+		results = append(results, sigs...)
+		if ok {
+			proof.add(rrset)
+		}
+	}
+
+	if !proof.provesNoData(zone, dns.TypeDS) {
+		return results, fmt.Errorf("parent zone returned no DS records and no valid NSEC/NSEC3 proof of their absence")
+	}
+
+	return results, nil
+}
+
+// denialProof holds the NSEC and NSEC3 records of a response whose
+// signatures were verified, and checks the denials of existence they prove
+// (RFC 4035 section 5.4 and RFC 5155 section 8).
+type denialProof struct {
+	nsec  []*dns.NSEC
+	nsec3 []*dns.NSEC3
+}
+
+// add records the NSEC and NSEC3 records of an RRset.
+func (p *denialProof) add(rrset []dns.RR) {
+	for _, rr := range rrset {
+		switch rec := rr.(type) {
+		case *dns.NSEC:
+			p.nsec = append(p.nsec, rec)
+		case *dns.NSEC3:
+			p.nsec3 = append(p.nsec3, rec)
+		}
+	}
+}
+
+// provesNameError reports whether the records prove that a name does not
+// exist, and that no wildcard could have synthesized it.
+func (p *denialProof) provesNameError(name string) bool {
+	encloser, _, ok := p.closestEncloser(name)
+	return ok && p.covers(wildcardOf(encloser))
+}
+
+// provesNoData reports whether the records prove that a name has no records
+// of the given type, either at the name itself or at the wildcard that would
+// have synthesized it.
+func (p *denialProof) provesNoData(name string, qtype uint16) bool {
+	if p.matchesWithoutType(name, qtype) {
+		return true
+	}
+
+	encloser, optOut, ok := p.closestEncloser(name)
+	if !ok {
+		return false
+	}
+
+	// An opt-out NSEC3 record covers unsigned delegations, which have no DS
+	if optOut && qtype == dns.TypeDS {
+		return true
+	}
+
+	return p.matchesWithoutType(wildcardOf(encloser), qtype)
+}
+
+// provesExpansion reports whether the records prove that an answer
+// synthesized from a wildcard with the given number of labels was correct:
+// no name closer to the owner than the wildcard exists.
+func (p *denialProof) provesExpansion(owner string, labels int) bool {
+	for _, rec := range p.nsec {
+		if nsecCovers(rec, owner) {
+			return true
+		}
+	}
+
+	nextCloser := ancestorName(owner, labels+1)
+	for _, rec := range p.nsec3 {
+		if rec.Cover(nextCloser) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchesWithoutType reports whether a record matches the name and proves
+// that it has neither the given type nor a CNAME.
+func (p *denialProof) matchesWithoutType(name string, qtype uint16) bool {
+	for _, rec := range p.nsec {
+		if strings.EqualFold(rec.Header().Name, name) && deniesType(rec.TypeBitMap, qtype) {
+			return true
+		}
+	}
+
+	for _, rec := range p.nsec3 {
+		if rec.Match(name) && deniesType(rec.TypeBitMap, qtype) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// deniesType reports whether a type bitmap proves that a name has neither
+// the given type nor a CNAME. A DS denial must come from the parent side of
+// the delegation, with the NS bit set and the SOA bit clear: the child's apex
+// record lacks DS too, but says nothing about the parent (RFC 6840 section
+// 4.4 and RFC 5155 section 8.6).
+func deniesType(bitmap []uint16, qtype uint16) bool {
+	if hasType(bitmap, qtype) || hasType(bitmap, dns.TypeCNAME) {
+		return false
+	}
+
+	if qtype == dns.TypeDS {
+		return hasType(bitmap, dns.TypeNS) && !hasType(bitmap, dns.TypeSOA)
+	}
+
+	return true
+}
+
+// covers reports whether a record proves that the name does not exist.
+func (p *denialProof) covers(name string) bool {
+	for _, rec := range p.nsec {
+		if nsecCovers(rec, name) {
+			return true
+		}
+	}
+
+	for _, rec := range p.nsec3 {
+		if rec.Cover(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// closestEncloser finds the closest existing ancestor of a name that does
+// not exist, along with whether an opt-out NSEC3 record covers the name
+// below it. With NSEC, the encloser is the longest name shared with the
+// record covering the name. With NSEC3, the encloser must match a record and
+// the next closer name must be covered by another (RFC 5155 section 8.3).
+func (p *denialProof) closestEncloser(name string) (string, bool, bool) {
+	for _, rec := range p.nsec {
+		if nsecCovers(rec, name) {
+			labels := max(dns.CompareDomainName(name, rec.Header().Name), dns.CompareDomainName(name, rec.NextDomain))
+			return ancestorName(name, labels), false, true
+		}
+	}
+
+	total := dns.CountLabel(name)
+	for labels := total - 1; labels >= 0; labels-- {
+		encloser := ancestorName(name, labels)
+		if !p.matchesNSEC3(encloser) {
+			continue
+		}
+
+		nextCloser := ancestorName(name, labels+1)
+		for _, rec := range p.nsec3 {
+			if rec.Cover(nextCloser) {
+				return encloser, rec.Flags&0x01 != 0, true
+			}
+		}
+		return "", false, false
+	}
+
+	return "", false, false
+}
+
+// matchesNSEC3 reports whether an NSEC3 record matches the name.
+func (p *denialProof) matchesNSEC3(name string) bool {
+	for _, rec := range p.nsec3 {
+		if rec.Match(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// nsecCovers reports whether an NSEC record proves that a name does not
+// exist, because it sorts between the owner and the next name. The last
+// record of a zone wraps around to the apex.
+func nsecCovers(rec *dns.NSEC, name string) bool {
+	owner, next := rec.Header().Name, rec.NextDomain
+	if canonicalCompare(owner, next) < 0 {
+		return canonicalCompare(owner, name) < 0 && canonicalCompare(name, next) < 0
+	}
+	return dns.IsSubDomain(next, name) && canonicalCompare(owner, name) < 0
+}
+
+// canonicalCompare compares two names in the canonical DNS order, label by
+// label from the rightmost one, ignoring case (RFC 4034 section 6.1).
+func canonicalCompare(a, b string) int {
+	left, right := dns.SplitDomainName(strings.ToLower(a)), dns.SplitDomainName(strings.ToLower(b))
+	for i, j := len(left)-1, len(right)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if c := strings.Compare(left[i], right[j]); c != 0 {
+			return c
+		}
+	}
+	return len(left) - len(right)
+}
+
+// ancestorName returns the ancestor of a name made of its rightmost labels.
+func ancestorName(name string, labels int) string {
+	parts := dns.SplitDomainName(name)
+	if labels <= 0 {
+		return "."
+	}
+	if labels >= len(parts) {
+		return dns.Fqdn(name)
+	}
+	return dns.Fqdn(strings.Join(parts[len(parts)-labels:], "."))
+}
+
+// wildcardOf returns the wildcard name directly below a name.
+func wildcardOf(name string) string {
+	if name == "." {
+		return "*."
+	}
+	return "*." + name
+}
+
+// verifyRRSet verifies an RRset against the given signatures using the given
+// keys. It returns a report for every signature and whether at least one of
+// them is both cryptographically valid and within its validity period.
+func verifyRRSet(rrset []dns.RR, sigs []*dns.RRSIG, keys []*dns.DNSKEY, now time.Time) ([]DNSSECSignature, bool) {
+	results := make([]DNSSECSignature, 0, len(sigs))
+	anyValid := false
+
+	for _, sig := range sigs {
+		expiration := time.Unix(int64(sig.Expiration), 0).UTC()
+		result := DNSSECSignature{
+			TypeCovered:    dns.TypeToString[sig.TypeCovered],
+			KeyTag:         sig.KeyTag,
+			Algorithm:      algorithmName(sig.Algorithm),
+			SignerName:     sig.SignerName,
+			Inception:      time.Unix(int64(sig.Inception), 0).UTC(),
+			Expiration:     expiration,
+			ExpiresInHours: int(expiration.Sub(now).Hours()),
+		}
+
+		var key *dns.DNSKEY
+		for _, k := range keys {
+			if k.KeyTag() == sig.KeyTag && k.Algorithm == sig.Algorithm && strings.EqualFold(k.Header().Name, sig.SignerName) {
+				key = k
+				break
+			}
+		}
+
+		switch {
+		case key == nil:
+			result.Error = fmt.Sprintf("no trusted DNSKEY with key tag %d and algorithm %s", sig.KeyTag, algorithmName(sig.Algorithm))
+		case !sig.ValidityPeriod(now):
+			result.Error = fmt.Sprintf("signature is outside its validity period (%s to %s)", result.Inception.Format(time.RFC3339), result.Expiration.Format(time.RFC3339))
+		default:
+			if err := sig.Verify(key, rrset); err != nil {
+				result.Error = fmt.Sprintf("signature verification failed: %v", err)
+			} else {
+				result.Valid = true
+				anyValid = true
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return results, anyValid
+}
+
+// addExpiryWarnings adds a warning for every valid signature that is close
+// to expiring.
+func addExpiryWarnings(zr *DNSSECZone, sigs []DNSSECSignature, now time.Time) {
+	for _, sig := range sigs {
+		if sig.Valid && sig.Expiration.Sub(now) < signatureExpiryWarning {
+			zr.Warnings = append(zr.Warnings, fmt.Sprintf("%s signature by key %d expires in %d hours", sig.TypeCovered, sig.KeyTag, sig.ExpiresInHours))
+		}
+	}
+}
+
+// rrsigsFor returns the RRSIG records in a section that cover the given
+// owner name and type.
+func rrsigsFor(section []dns.RR, name string, covered uint16) []*dns.RRSIG {
+	var sigs []*dns.RRSIG
+	for _, rr := range section {
+		if sig, ok := rr.(*dns.RRSIG); ok && sig.TypeCovered == covered && strings.EqualFold(sig.Header().Name, name) {
+			sigs = append(sigs, sig)
+		}
+	}
+	return sigs
+}
+
+// groupRRSets groups the records of a section into RRsets by owner name and
+// type, skipping RRSIG and OPT records. RRsets are returned in the order in
+// which they first appear.
+func groupRRSets(section []dns.RR) [][]dns.RR {
+	var order []string
+	sets := make(map[string][]dns.RR)
+
+	for _, rr := range section {
+		header := rr.Header()
+		if header.Rrtype == dns.TypeRRSIG || header.Rrtype == dns.TypeOPT {
+			continue
+		}
+
+		key := dns.CanonicalName(header.Name) + "/" + dns.TypeToString[header.Rrtype]
+		if _, exists := sets[key]; !exists {
+			order = append(order, key)
+		}
+		sets[key] = append(sets[key], rr)
+	}
+
+	result := make([][]dns.RR, 0, len(order))
+	for _, key := range order {
+		result = append(result, sets[key])
+	}
+	return result
+}
+
+// delegationSignerInfo converts a DS record into its report form.
+func delegationSignerInfo(ds *dns.DS) DNSSECDelegationSigner {
+	return DNSSECDelegationSigner{
+		KeyTag:     ds.KeyTag,
+		Algorithm:  algorithmName(ds.Algorithm),
+		DigestType: digestName(ds.DigestType),
+		Digest:     strings.ToUpper(ds.Digest),
+	}
+}
+
+// matchesDS reports whether a DS record was generated from the given key.
+func matchesDS(key *dns.DNSKEY, ds *dns.DS) bool {
+	if key.KeyTag() != ds.KeyTag || key.Algorithm != ds.Algorithm {
+		return false
+	}
+
+	computed := key.ToDS(ds.DigestType)
+	return computed != nil && strings.EqualFold(computed.Digest, ds.Digest)
+}
+
+// hasType reports whether a type bitmap includes the given type.
+func hasType(bitmap []uint16, rrtype uint16) bool {
+	for _, t := range bitmap {
+		if t == rrtype {
+			return true
+		}
+	}
+	return false
+}
+
+// keyRole returns a human-readable role for a DNSKEY based on its flags.
+func keyRole(key *dns.DNSKEY) string {
+	switch {
+	case key.Flags&dns.ZONE == 0:
+		return "non-zone"
+	case key.Flags&dns.SEP != 0:
+		return "KSK"
+	default:
+		return "ZSK"
+	}
+}
+
+// algorithmName returns the mnemonic for a DNSSEC algorithm number.
+func algorithmName(alg uint8) string {
+	if name, ok := dns.AlgorithmToString[alg]; ok {
+		return name
+	}
+	return fmt.Sprintf("ALG%d", alg)
+}
+
+// digestName returns the mnemonic for a DS digest type.
+func digestName(digestType uint8) string {
+	switch digestType {
+	case dns.SHA1:
+		return "SHA-1"
+	case dns.SHA256:
+		return "SHA-256"
+	case dns.GOST94:
+		return "GOST R 34.11-94"
+	case dns.SHA384:
+		return "SHA-384"
+	default:
+		return fmt.Sprintf("DIGEST%d", digestType)
+	}
+}
@@ -0,0 +1,323 @@
+package dns
+
+import (
+	"crypto"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSigningKey generates a DNSKEY and its private key for the given zone.
+func newSigningKey(t *testing.T, zone string, flags uint16) (*dns.DNSKEY, crypto.Signer) {
+	t.Helper()
+
+	key := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: zone, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     flags,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+	}
+
+	priv, err := key.Generate(256)
+	require.NoError(t, err)
+
+	signer, ok := priv.(crypto.Signer)
+	require.True(t, ok)
+
+	return key, signer
+}
+
+// signRRSet signs an RRset with the given key, valid between inception and expiration.
+func signRRSet(t *testing.T, key *dns.DNSKEY, signer crypto.Signer, rrset []dns.RR, inception, expiration time.Time) *dns.RRSIG {
+	t.Helper()
+
+	sig := &dns.RRSIG{
+		Hdr:        dns.RR_Header{Name: rrset[0].Header().Name, Rrtype: dns.TypeRRSIG, Class: dns.ClassINET, Ttl: rrset[0].Header().Ttl},
+		KeyTag:     key.KeyTag(),
+		SignerName: key.Header().Name,
+		Algorithm:  key.Algorithm,
+		Inception:  uint32(inception.Unix()),
+		Expiration: uint32(expiration.Unix()),
+	}
+
+	require.NoError(t, sig.Sign(signer, rrset))
+	return sig
+}
+
+func TestVerifyRRSet(t *testing.T) {
+	now := time.Now()
+	key, signer := newSigningKey(t, "example.com.", dns.ZONE)
+	otherKey, _ := newSigningKey(t, "example.com.", dns.ZONE)
+
+	a, err := dns.NewRR("www.example.com. 300 IN A 192.0.2.1")
+	require.NoError(t, err)
+	rrset := []dns.RR{a}
+
+	t.Run("valid signature", func(t *testing.T) {
+		sig := signRRSet(t, key, signer, rrset, now.Add(-time.Hour), now.Add(24*time.Hour))
+
+		results, ok := verifyRRSet(rrset, []*dns.RRSIG{sig}, []*dns.DNSKEY{key}, now)
+
+		assert.True(t, ok)
+		require.Len(t, results, 1)
+		assert.True(t, results[0].Valid)
+		assert.Equal(t, "A", results[0].TypeCovered)
+		assert.Empty(t, results[0].Error)
+	})
+
+	t.Run("expired signature", func(t *testing.T) {
+		sig := signRRSet(t, key, signer, rrset, now.Add(-48*time.Hour), now.Add(-time.Hour))
+
+		results, ok := verifyRRSet(rrset, []*dns.RRSIG{sig}, []*dns.DNSKEY{key}, now)
+
+		assert.False(t, ok)
+		require.Len(t, results, 1)
+		assert.Contains(t, results[0].Error, "outside its validity period")
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		sig := signRRSet(t, key, signer, rrset, now.Add(-time.Hour), now.Add(24*time.Hour))
+
+		results, ok := verifyRRSet(rrset, []*dns.RRSIG{sig}, []*dns.DNSKEY{otherKey}, now)
+
+		assert.False(t, ok)
+		require.Len(t, results, 1)
+		assert.Contains(t, results[0].Error, "no trusted DNSKEY")
+	})
+
+	t.Run("tampered data", func(t *testing.T) {
+		sig := signRRSet(t, key, signer, rrset, now.Add(-time.Hour), now.Add(24*time.Hour))
+
+		tampered, err := dns.NewRR("www.example.com. 300 IN A 192.0.2.99")
+		require.NoError(t, err)
+
+		results, ok := verifyRRSet([]dns.RR{tampered}, []*dns.RRSIG{sig}, []*dns.DNSKEY{key}, now)
+
+		assert.False(t, ok)
+		require.Len(t, results, 1)
+		assert.Contains(t, results[0].Error, "signature verification failed")
+	})
+}
+
+func TestValidateResponse(t *testing.T) {
+	now := time.Now()
+	key, signer := newSigningKey(t, "example.com.", dns.ZONE)
+	trustedKeys := map[string][]*dns.DNSKEY{"example.com.": {key}}
+
+	// signed returns an RRset followed by its signature
+	signed := func(records ...string) []dns.RR {
+		rrset := mustRRs(t, records...)
+		return append(rrset, signRRSet(t, key, signer, rrset, now.Add(-time.Hour), now.Add(24*time.Hour)))
+	}
+
+	soa := signed("example.com. 300 IN SOA ns1.example.com. hostmaster.example.com. 1 7200 3600 1209600 300")
+
+	// An NSEC3 record with an empty salt and no extra iterations
+	nsec3 := func(name, next, types string) string {
+		return dns.HashName(name, dns.SHA1, 0, "") + ".example.com. 300 IN NSEC3 1 0 0 - " + next + " " + types
+	}
+	// These hashed names sort before and after every other, so a record
+	// between them covers every name
+	const firstHash, lastHash = "00000000000000000000000000000000", "VVVVVVVVVVVVVVVVVVVVVVVVVVVVVVVV"
+	coverAll := signed(firstHash + ".example.com. 300 IN NSEC3 1 0 0 - " + lastHash + " A RRSIG")
+
+	response := func(rcode int, answer []dns.RR, ns ...[]dns.RR) *dns.Msg {
+		m := new(dns.Msg)
+		m.SetQuestion("www.example.com.", dns.TypeAAAA)
+		m.Rcode = rcode
+		m.Answer = answer
+		for _, rrset := range ns {
+			m.Ns = append(m.Ns, rrset...)
+		}
+		return m
+	}
+
+	t.Run("nodata with a matching NSEC record", func(t *testing.T) {
+		msg := response(dns.RcodeSuccess, nil, soa, signed("www.example.com. 300 IN NSEC z.example.com. A RRSIG NSEC"))
+
+		answer := validateResponse("www.example.com.", dns.TypeAAAA, msg, trustedKeys, now)
+		assert.Equal(t, dnssecSecure, answer.Status)
+		assert.Empty(t, answer.Errors)
+	})
+
+	t.Run("nodata with an NSEC record listing the type", func(t *testing.T) {
+		msg := response(dns.RcodeSuccess, nil, soa, signed("www.example.com. 300 IN NSEC z.example.com. A AAAA RRSIG NSEC"))
+
+		answer := validateResponse("www.example.com.", dns.TypeAAAA, msg, trustedKeys, now)
+		assert.Equal(t, dnssecBogus, answer.Status)
+		assert.Equal(t, []string{"no valid NSEC or NSEC3 record proves that www.example.com. has no AAAA records"}, answer.Errors)
+	})
+
+	t.Run("negative answer without a proof", func(t *testing.T) {
+		answer := validateResponse("www.example.com.", dns.TypeAAAA, response(dns.RcodeSuccess, nil, soa), trustedKeys, now)
+		assert.Equal(t, dnssecBogus, answer.Status)
+
+		// An empty authority section proves nothing either
+		answer = validateResponse("www.example.com.", dns.TypeAAAA, response(dns.RcodeNameError, nil), trustedKeys, now)
+		assert.Equal(t, dnssecBogus, answer.Status)
+		assert.Equal(t, []string{"no valid NSEC or NSEC3 record proves that www.example.com. does not exist"}, answer.Errors)
+	})
+
+	t.Run("name error with NSEC records", func(t *testing.T) {
+		msg := response(dns.RcodeNameError, nil, soa,
+			signed("mail.example.com. 300 IN NSEC zz.example.com. A RRSIG NSEC"),
+			signed("example.com. 300 IN NSEC a.example.com. SOA NS RRSIG NSEC DNSKEY"),
+		)
+
+		answer := validateResponse("www.example.com.", dns.TypeAAAA, msg, trustedKeys, now)
+		assert.Equal(t, dnssecSecure, answer.Status)
+
+		// Without the record covering the wildcard, one could have answered
+		msg = response(dns.RcodeNameError, nil, soa, signed("mail.example.com. 300 IN NSEC zz.example.com. A RRSIG NSEC"))
+
+		answer = validateResponse("www.example.com.", dns.TypeAAAA, msg, trustedKeys, now)
+		assert.Equal(t, dnssecBogus, answer.Status)
+	})
+
+	t.Run("name error with an NSEC record that doesn't cover the name", func(t *testing.T) {
+		msg := response(dns.RcodeNameError, nil, soa, signed("a.example.com. 300 IN NSEC b.example.com. A RRSIG NSEC"))
+
+		answer := validateResponse("www.example.com.", dns.TypeAAAA, msg, trustedKeys, now)
+		assert.Equal(t, dnssecBogus, answer.Status)
+	})
+
+	t.Run("NSEC3 proofs", func(t *testing.T) {
+		nodata := response(dns.RcodeSuccess, nil, soa, signed(nsec3("www.example.com.", lastHash, "A RRSIG")))
+		answer := validateResponse("www.example.com.", dns.TypeAAAA, nodata, trustedKeys, now)
+		assert.Equal(t, dnssecSecure, answer.Status)
+
+		// The closest encloser matches, and the next closer name and the
+		// wildcard are covered
+		nxdomain := response(dns.RcodeNameError, nil, soa,
+			signed(nsec3("example.com.", lastHash, "SOA NS RRSIG DNSKEY NSEC3PARAM")),
+			coverAll,
+		)
+		answer = validateResponse("www.example.com.", dns.TypeAAAA, nxdomain, trustedKeys, now)
+		assert.Equal(t, dnssecSecure, answer.Status)
+
+		// Without the closest encloser there is no proof
+		nxdomain = response(dns.RcodeNameError, nil, soa, coverAll)
+		answer = validateResponse("www.example.com.", dns.TypeAAAA, nxdomain, trustedKeys, now)
+		assert.Equal(t, dnssecBogus, answer.Status)
+	})
+
+	t.Run("wildcard expansion", func(t *testing.T) {
+		// Sign the wildcard and rename it, as an authoritative server does
+		// when it synthesizes an answer
+		expanded := signed("*.example.com. 300 IN A 192.0.2.1")
+		for _, rr := range expanded {
+			rr.Header().Name = "www.example.com."
+		}
+
+		answer := validateResponse("www.example.com.", dns.TypeA, response(dns.RcodeSuccess, expanded), trustedKeys, now)
+		assert.Equal(t, dnssecBogus, answer.Status)
+		assert.Equal(t, []string{"www.example.com. A RRset was synthesized from a wildcard, but no valid NSEC or NSEC3 record proves that www.example.com. does not exist"}, answer.Errors)
+
+		msg := response(dns.RcodeSuccess, expanded, signed("mail.example.com. 300 IN NSEC zz.example.com. A RRSIG NSEC"))
+		answer = validateResponse("www.example.com.", dns.TypeA, msg, trustedKeys, now)
+		assert.Equal(t, dnssecSecure, answer.Status)
+		assert.Empty(t, answer.Errors)
+	})
+
+	t.Run("unsigned proof", func(t *testing.T) {
+		msg := response(dns.RcodeSuccess, nil, soa, mustRRs(t, "www.example.com. 300 IN NSEC z.example.com. A RRSIG NSEC"))
+
+		answer := validateResponse("www.example.com.", dns.TypeAAAA, msg, trustedKeys, now)
+		assert.Equal(t, dnssecBogus, answer.Status)
+		assert.Contains(t, answer.Errors, "www.example.com. NSEC RRset has no signatures")
+	})
+}
+
+func TestVerifyDSDenial(t *testing.T) {
+	now := time.Now()
+	key, signer := newSigningKey(t, "example.com.", dns.ZONE)
+	parentKeys := []*dns.DNSKEY{key}
+
+	// referral returns a response whose authority section holds the signed record
+	referral := func(record string) *dns.Msg {
+		rrset := mustRRs(t, record)
+		m := new(dns.Msg)
+		m.SetQuestion("child.example.com.", dns.TypeDS)
+		m.Ns = append(rrset, signRRSet(t, key, signer, rrset, now.Add(-time.Hour), now.Add(24*time.Hour)))
+		return m
+	}
+
+	nsec3 := func(types string) string {
+		return dns.HashName("child.example.com.", dns.SHA1, 0, "") + ".example.com. 300 IN NSEC3 1 0 0 - VVVVVVVVVVVVVVVVVVVVVVVVVVVVVVVV " + types
+	}
+
+	tests := []struct {
+		name    string
+		record  string
+		wantErr bool
+	}{
+		{name: "NSEC at the delegation point", record: "child.example.com. 300 IN NSEC d.example.com. NS RRSIG NSEC"},
+		{name: "NSEC3 at the delegation point", record: nsec3("NS")},
+		{name: "NSEC from the child apex", record: "child.example.com. 300 IN NSEC a.child.example.com. NS SOA RRSIG NSEC DNSKEY", wantErr: true},
+		{name: "NSEC3 from the child apex", record: nsec3("NS SOA RRSIG DNSKEY NSEC3PARAM"), wantErr: true},
+		{name: "NSEC without a delegation", record: "child.example.com. 300 IN NSEC d.example.com. A RRSIG NSEC", wantErr: true},
+		{name: "NSEC listing DS", record: "child.example.com. 300 IN NSEC d.example.com. NS DS RRSIG NSEC", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sigs, err := verifyDSDenial("child.example.com.", referral(tt.record), parentKeys, now)
+			require.Len(t, sigs, 1)
+			assert.True(t, sigs[0].Valid)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestMatchesDS(t *testing.T) {
+	key, _ := newSigningKey(t, "example.com.", dns.ZONE|dns.SEP)
+	otherKey, _ := newSigningKey(t, "example.com.", dns.ZONE|dns.SEP)
+
+	ds := key.ToDS(dns.SHA256)
+	require.NotNil(t, ds)
+
+	assert.True(t, matchesDS(key, ds))
+	assert.False(t, matchesDS(otherKey, ds))
+	assert.Equal(t, "KSK", keyRole(key))
+}
+
+func TestParseTrustAnchors(t *testing.T) {
+	anchors := parseTrustAnchors()
+
+	require.Len(t, anchors, len(rootTrustAnchors))
+	for _, anchor := range anchors {
+		assert.Equal(t, ".", anchor.Header().Name)
+		assert.Equal(t, uint8(dns.RSASHA256), anchor.Algorithm)
+	}
+}
+
+func TestGroupRRSets(t *testing.T) {
+	records := []string{
+		"example.com. 300 IN A 192.0.2.1",
+		"example.com. 300 IN MX 10 mail.example.com.",
+		"EXAMPLE.com. 300 IN A 192.0.2.2",
+	}
+
+	var section []dns.RR
+	for _, record := range records {
+		rr, err := dns.NewRR(record)
+		require.NoError(t, err)
+		section = append(section, rr)
+	}
+
+	sets := groupRRSets(section)
+
+	require.Len(t, sets, 2)
+	assert.Len(t, sets[0], 2)
+	assert.Equal(t, dns.TypeA, sets[0][0].Header().Rrtype)
+	assert.Len(t, sets[1], 1)
+	assert.Equal(t, dns.TypeMX, sets[1][0].Header().Rrtype)
+}
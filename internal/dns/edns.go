@@ -0,0 +1,269 @@
+package dns
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"unicode"
+
+	"github.com/miekg/dns"
+)
+
+// Default EDNS client subnet prefix lengths applied when a bare address is
+// given, as recommended by RFC 7871 section 11.1.
+const (
+	defaultSubnetPrefixV4 = 24
+	defaultSubnetPrefixV6 = 56
+)
+
+// ednsOptionNames maps EDNS option codes to their registered names.
+var ednsOptionNames = map[uint16]string{
+	dns.EDNS0LLQ:          "LLQ",
+	dns.EDNS0UL:           "UL",
+	dns.EDNS0NSID:         "NSID",
+	dns.EDNS0ESU:          "ESU",
+	dns.EDNS0DAU:          "DAU",
+	dns.EDNS0DHU:          "DHU",
+	dns.EDNS0N3U:          "N3U",
+	dns.EDNS0SUBNET:       "ECS",
+	dns.EDNS0EXPIRE:       "EXPIRE",
+	dns.EDNS0COOKIE:       "COOKIE",
+	dns.EDNS0TCPKEEPALIVE: "TCP_KEEPALIVE",
+	dns.EDNS0PADDING:      "PADDING",
+	dns.EDNS0EDE:          "EDE",
+}
+
+// ednsParams holds the EDNS options that can be set on a DNS query.
+type ednsParams struct {
+	DNSSECOK     bool   `json:"dnssec_ok"`
+	UDPSize      int    `json:"udp_size"`
+	ClientSubnet string `json:"client_subnet"`
+	NSID         bool   `json:"nsid"`
+	Cookie       bool   `json:"cookie"`
+}
+
+// enabled reports whether any EDNS option was requested.
+func (p ednsParams) enabled() bool {
+	return p.DNSSECOK || p.UDPSize != 0 || p.ClientSubnet != "" || p.NSID || p.Cookie
+}
+
+// applyEDNS adds an OPT record carrying the requested EDNS options to the
+// message. The message is left untouched when no option was requested.
+func applyEDNS(m *dns.Msg, params ednsParams) error {
+	if !params.enabled() {
+		return nil
+	}
+
+	udpSize := params.UDPSize
+	if udpSize == 0 {
+		udpSize = dns.DefaultMsgSize
+	}
+
+	if udpSize < dns.MinMsgSize || udpSize > dns.MaxMsgSize {
+		return fmt.Errorf("invalid udp_size %d: must be between %d and %d", udpSize, dns.MinMsgSize, dns.MaxMsgSize)
+	}
+
+	m.SetEdns0(uint16(udpSize), params.DNSSECOK)
+	opt := m.IsEdns0()
+
+	if params.ClientSubnet != "" {
+		subnet, err := parseClientSubnet(params.ClientSubnet)
+		if err != nil {
+			return err
+		}
+		opt.Option = append(opt.Option, subnet)
+	}
+
+	if params.NSID {
+		opt.Option = append(opt.Option, &dns.EDNS0_NSID{Code: dns.EDNS0NSID})
+	}
+
+	if params.Cookie {
+		// A client cookie is 8 random bytes (RFC 7873 section 4)
+		clientCookie := make([]byte, 8)
+		if _, err := rand.Read(clientCookie); err != nil {
+			return fmt.Errorf("failed to generate client cookie: %w", err)
+		}
+		opt.Option = append(opt.Option, &dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: hex.EncodeToString(clientCookie)})
+	}
+
+	return nil
+}
+
+// parseClientSubnet parses an EDNS client subnet given as a CIDR prefix or a
+// bare address. Bare addresses use the default prefix length for their family.
+func parseClientSubnet(value string) (*dns.EDNS0_SUBNET, error) {
+	value = strings.TrimSpace(value)
+
+	var ip net.IP
+	var prefix int
+
+	if strings.Contains(value, "/") {
+		addr, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client_subnet %q: %w", value, err)
+		}
+		ip = addr
+		prefix, _ = network.Mask.Size()
+	} else {
+		ip = net.ParseIP(value)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid client_subnet %q: must be an IP address or a CIDR prefix", value)
+		}
+		prefix = defaultSubnetPrefixV6
+		if ip.To4() != nil {
+			prefix = defaultSubnetPrefixV4
+		}
+	}
+
+	subnet := &dns.EDNS0_SUBNET{
+		Code:          dns.EDNS0SUBNET,
+		SourceNetmask: uint8(prefix),
+	}
+
+	// Only the bits covered by the prefix may be set (RFC 7871 section 6)
+	if ip4 := ip.To4(); ip4 != nil {
+		subnet.Family = 1
+		subnet.Address = ip4.Mask(net.CIDRMask(prefix, 32))
+	} else {
+		subnet.Family = 2
+		subnet.Address = ip.Mask(net.CIDRMask(prefix, 128))
+	}
+
+	return subnet, nil
+}
+
+// formatEDNS converts the OPT record of a response into a JSON-serializable
+// map, decoding every option it carries.
+func formatEDNS(opt *dns.OPT) map[string]any {
+	options := []map[string]any{}
+	for _, o := range opt.Option {
+		options = append(options, formatEDNSOption(o))
+	}
+
+	return map[string]any{
+		"version":  opt.Version(),
+		"udpSize":  opt.UDPSize(),
+		"dnssecOk": opt.Do(),
+		"options":  options,
+	}
+}
+
+// formatEDNSOption decodes a single EDNS option into a JSON-serializable map
+// with its code, name and option-specific fields.
+func formatEDNSOption(o dns.EDNS0) map[string]any {
+	code := o.Option()
+
+	name, ok := ednsOptionNames[code]
+	if !ok {
+		name = fmt.Sprintf("OPT%d", code)
+	}
+
+	option := map[string]any{
+		"code": code,
+		"name": name,
+		"data": o.String(),
+	}
+
+	switch rec := o.(type) {
+	case *dns.EDNS0_SUBNET:
+		option["family"] = rec.Family
+		option["sourcePrefix"] = rec.SourceNetmask
+		option["scopePrefix"] = rec.SourceScope
+		option["address"] = rec.Address.String()
+
+	case *dns.EDNS0_NSID:
+		option["hex"] = rec.Nsid
+		if raw, err := hex.DecodeString(rec.Nsid); err == nil && isPrintable(raw) {
+			option["text"] = string(raw)
+		}
+
+	case *dns.EDNS0_COOKIE:
+		// The client cookie is the first 8 bytes, followed by the server cookie
+		if len(rec.Cookie) >= 16 {
+			option["clientCookie"] = rec.Cookie[:16]
+			if len(rec.Cookie) > 16 {
+				option["serverCookie"] = rec.Cookie[16:]
+			}
+		}
+
+	case *dns.EDNS0_EDE:
+		option["infoCode"] = rec.InfoCode
+		option["purpose"] = dns.ExtendedErrorCodeToString[rec.InfoCode]
+		if rec.ExtraText != "" {
+			option["extraText"] = rec.ExtraText
+		}
+
+	case *dns.EDNS0_EXPIRE:
+		if !rec.Empty {
+			option["expire"] = rec.Expire
+		}
+
+	case *dns.EDNS0_TCP_KEEPALIVE:
+		// The timeout is expressed in units of 100 milliseconds
+		option["timeoutMs"] = int(rec.Timeout) * 100
+
+	case *dns.EDNS0_PADDING:
+		option["length"] = len(rec.Padding)
+
+	case *dns.EDNS0_UL:
+		option["lease"] = rec.Lease
+		if rec.KeyLease != 0 {
+			option["keyLease"] = rec.KeyLease
+		}
+
+	case *dns.EDNS0_LLQ:
+		option["version"] = rec.Version
+		option["opcode"] = rec.Opcode
+		option["error"] = rec.Error
+		option["id"] = rec.Id
+		option["leaseLife"] = rec.LeaseLife
+
+	case *dns.EDNS0_DAU:
+		option["algorithms"] = codeNames(rec.AlgCode, dns.AlgorithmToString)
+
+	case *dns.EDNS0_DHU:
+		option["algorithms"] = codeNames(rec.AlgCode, dns.HashToString)
+
+	case *dns.EDNS0_N3U:
+		option["algorithms"] = codeNames(rec.AlgCode, dns.HashToString)
+
+	case *dns.EDNS0_ESU:
+		option["uri"] = rec.Uri
+
+	case *dns.EDNS0_LOCAL:
+		option["hex"] = hex.EncodeToString(rec.Data)
+	}
+
+	return option
+}
+
+// codeNames converts algorithm codes into their names, keeping the number
+// for codes without a known name.
+func codeNames(codes []uint8, names map[uint8]string) []string {
+	result := make([]string, 0, len(codes))
+	for _, code := range codes {
+		if name, ok := names[code]; ok {
+			result = append(result, name)
+			continue
+		}
+		result = append(result, fmt.Sprintf("%d", code))
+	}
+	return result
+}
+
+// isPrintable reports whether the bytes are non-empty printable text.
+func isPrintable(b []byte) bool {
+	if len(b) == 0 {
+		return false
+	}
+
+	for _, r := range string(b) {
+		if !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
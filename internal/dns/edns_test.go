@@ -0,0 +1,119 @@
+package dns
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseClientSubnet(t *testing.T) {
+	tests := []struct {
+		value   string
+		family  uint16
+		prefix  uint8
+		address string
+		wantErr bool
+	}{
+		{value: "203.0.113.77/24", family: 1, prefix: 24, address: "203.0.113.0"},
+		{value: "198.51.100.9", family: 1, prefix: 24, address: "198.51.100.0"},
+		{value: "2001:db8:abcd:1234::1", family: 2, prefix: 56, address: "2001:db8:abcd:1200::"},
+		{value: "2001:db8::/32", family: 2, prefix: 32, address: "2001:db8::"},
+		{value: "0.0.0.0/0", family: 1, prefix: 0, address: "0.0.0.0"},
+		{value: "not-an-ip", wantErr: true},
+		{value: "192.0.2.0/33", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			subnet, err := parseClientSubnet(tt.value)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.family, subnet.Family)
+			assert.Equal(t, tt.prefix, subnet.SourceNetmask)
+			assert.Equal(t, tt.address, subnet.Address.String())
+		})
+	}
+}
+
+func TestApplyEDNS(t *testing.T) {
+	t.Run("no options", func(t *testing.T) {
+		m := new(dns.Msg)
+		m.SetQuestion("example.com.", dns.TypeA)
+		require.NoError(t, applyEDNS(m, ednsParams{}))
+		assert.Nil(t, m.IsEdns0())
+	})
+
+	t.Run("all options", func(t *testing.T) {
+		m := new(dns.Msg)
+		m.SetQuestion("example.com.", dns.TypeA)
+		require.NoError(t, applyEDNS(m, ednsParams{
+			DNSSECOK:     true,
+			UDPSize:      1232,
+			ClientSubnet: "192.0.2.0/24",
+			NSID:         true,
+			Cookie:       true,
+		}))
+
+		opt := m.IsEdns0()
+		require.NotNil(t, opt)
+		assert.True(t, opt.Do())
+		assert.Equal(t, uint16(1232), opt.UDPSize())
+		require.Len(t, opt.Option, 3)
+		assert.Len(t, opt.Option[2].(*dns.EDNS0_COOKIE).Cookie, 16)
+
+		// The options must survive the wire format
+		_, err := m.Pack()
+		require.NoError(t, err)
+	})
+
+	t.Run("invalid buffer size", func(t *testing.T) {
+		m := new(dns.Msg)
+		require.Error(t, applyEDNS(m, ednsParams{UDPSize: 100}))
+	})
+}
+
+func TestFormatEDNS(t *testing.T) {
+	opt := &dns.OPT{Hdr: dns.RR_Header{Name: ".", Rrtype: dns.TypeOPT}}
+	opt.SetUDPSize(1232)
+	opt.SetDo()
+	opt.Option = []dns.EDNS0{
+		&dns.EDNS0_NSID{Code: dns.EDNS0NSID, Nsid: hex.EncodeToString([]byte("fra-01"))},
+		&dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, SourceScope: 20, Address: []byte{192, 0, 2, 0}},
+		&dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: "0102030405060708aabbccddeeff0011"},
+		&dns.EDNS0_EDE{InfoCode: dns.ExtendedErrorCodeStaleAnswer, ExtraText: "served stale"},
+		&dns.EDNS0_TCP_KEEPALIVE{Code: dns.EDNS0TCPKEEPALIVE, Timeout: 50},
+		&dns.EDNS0_LOCAL{Code: 65001, Data: []byte{0xde, 0xad}},
+	}
+
+	edns := formatEDNS(opt)
+	assert.Equal(t, uint16(1232), edns["udpSize"])
+	assert.Equal(t, true, edns["dnssecOk"])
+
+	options := edns["options"].([]map[string]any)
+	require.Len(t, options, 6)
+
+	assert.Equal(t, "NSID", options[0]["name"])
+	assert.Equal(t, "fra-01", options[0]["text"])
+
+	assert.Equal(t, "ECS", options[1]["name"])
+	assert.Equal(t, uint8(20), options[1]["scopePrefix"])
+	assert.Equal(t, "192.0.2.0", options[1]["address"])
+
+	assert.Equal(t, "0102030405060708", options[2]["clientCookie"])
+	assert.Equal(t, "aabbccddeeff0011", options[2]["serverCookie"])
+
+	assert.Equal(t, "Stale Answer", options[3]["purpose"])
+	assert.Equal(t, "served stale", options[3]["extraText"])
+
+	assert.Equal(t, 5000, options[4]["timeoutMs"])
+
+	assert.Equal(t, "OPT65001", options[5]["name"])
+	assert.Equal(t, "dead", options[5]["hex"])
+}
@@ -0,0 +1,494 @@
+package dns
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/miekg/dns"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/domainname"
+	httpping "github.com/patrickdappollonio/mcp-domaintools/internal/http_ping"
+	resp "github.com/patrickdappollonio/mcp-domaintools/internal/response"
+	internaltls "github.com/patrickdappollonio/mcp-domaintools/internal/tls"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/utils"
+	"golang.org/x/sync/errgroup"
+)
+
+// Limits applied when probing the endpoints of HTTPS records.
+const (
+	httpsProbeConcurrency = 8
+	maxHTTPSEndpoints     = 5
+)
+
+// Transports used to probe an application protocol.
+const (
+	transportTLS  = "tls"
+	transportQUIC = "quic"
+)
+
+// defaultHTTPSALPN is the protocol clients assume an HTTPS endpoint supports
+// unless the record sets no-default-alpn (RFC 9460 section 7.1.2).
+const defaultHTTPSALPN = "http/1.1"
+
+// httpsRecordCheckParams represents the parameters for HTTPS record checks.
+type httpsRecordCheckParams struct {
+	Domain string `json:"domain"`
+	Port   int    `json:"port"`
+}
+
+// HTTPSProtocolCheck represents the handshake made for one advertised
+// application protocol.
+type HTTPSProtocolCheck struct {
+	Protocol           string `json:"protocol"`
+	Transport          string `json:"transport"`
+	Negotiated         bool   `json:"negotiated"`
+	NegotiatedProtocol string `json:"negotiated_protocol,omitempty"`
+	TLSVersion         string `json:"tls_version,omitempty"`
+	Error              string `json:"error,omitempty"`
+}
+
+// HTTPSHintCheck represents the check of an address hint.
+type HTTPSHintCheck struct {
+	Address   string `json:"address"`
+	InDNS     bool   `json:"in_dns"`
+	Reachable bool   `json:"reachable"`
+	Error     string `json:"error,omitempty"`
+}
+
+// HTTPSECHCheck represents a handshake made with the advertised ECH configs.
+type HTTPSECHCheck struct {
+	Accepted     bool        `json:"accepted"`
+	RetryConfigs []ECHConfig `json:"retry_configs,omitempty"`
+	Error        string      `json:"error,omitempty"`
+}
+
+// HTTPSEndpointCheck represents the checks of a ServiceMode record against
+// the endpoint it points to.
+type HTTPSEndpointCheck struct {
+	Priority  uint16               `json:"priority"`
+	Target    string               `json:"target"`
+	Port      int                  `json:"port"`
+	Addresses []string             `json:"addresses"`
+	Protocols []HTTPSProtocolCheck `json:"protocols"`
+	Hints     []HTTPSHintCheck     `json:"hints,omitempty"`
+	ECH       *HTTPSECHCheck       `json:"ech,omitempty"`
+	Issues    []string             `json:"issues,omitempty"`
+}
+
+// HTTPSAltSvcCheck represents the Alt-Svc header the origin sends over HTTP.
+type HTTPSAltSvcCheck struct {
+	URL        string   `json:"url"`
+	StatusCode int      `json:"status_code,omitempty"`
+	Protocol   string   `json:"protocol,omitempty"`
+	Header     string   `json:"header,omitempty"`
+	Protocols  []string `json:"protocols,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// HTTPSRecordCheckResponse represents the complete HTTPS record check response.
+type HTTPSRecordCheckResponse struct {
+	Domain     string               `json:"domain"`
+	DomainName *domainname.Name     `json:"domain_name,omitempty"`
+	Port       int                  `json:"port"`
+	QueryName  string               `json:"query_name"`
+	AliasChain []string             `json:"alias_chain,omitempty"`
+	Records    []map[string]any     `json:"records"`
+	Endpoints  []HTTPSEndpointCheck `json:"endpoints"`
+	AltSvc     *HTTPSAltSvcCheck    `json:"alt_svc,omitempty"`
+	Issues     []string             `json:"issues,omitempty"`
+	Timestamp  string               `json:"timestamp"`
+}
+
+// HandleHTTPSRecordCheck decodes the HTTPS records of an origin and checks
+// the protocols, address hints and ECH configs they advertise against what
+// the origin negotiates.
+func HandleHTTPSRecordCheck(ctx context.Context, request mcp.CallToolRequest, config *QueryConfig) (*mcp.CallToolResult, error) {
+	var params httpsRecordCheckParams
+	if err := request.BindArguments(&params); err != nil {
+		return nil, fmt.Errorf("failed to parse tool input: %w", utils.ParseJSONUnmarshalError(err))
+	}
+
+	// Validate required parameters
+	if params.Domain == "" {
+		return nil, fmt.Errorf("parameter \"domain\" is required")
+	}
+
+	// Validate and normalize the domain name
+	name, err := domainname.Parse(params.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	port := params.Port
+	if port == 0 {
+		port = 443
+	}
+	if port < 1 || port > 65535 {
+		return nil, fmt.Errorf("invalid port %d", port)
+	}
+
+	// Get DNS servers once, rather than once per name
+	servers, err := getSystemDNSServers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get DNS servers: %w", err)
+	}
+
+	result := checkHTTPSRecords(ctx, name.ASCII, port, servers, config.Timeout)
+	result.DomainName = name
+
+	altSvc := &HTTPSAltSvcCheck{URL: "https://" + net.JoinHostPort(result.Domain, strconv.Itoa(port)) + "/"}
+	probe, err := httpping.Probe(ctx, altSvc.URL, config.Timeout)
+	if err != nil {
+		altSvc.Error = err.Error()
+	} else {
+		altSvc.StatusCode = probe.StatusCode
+		altSvc.Protocol = probe.Protocol
+		altSvc.Header = probe.AltSvc
+		altSvc.Protocols = httpping.AltSvcProtocols(probe.AltSvc)
+	}
+	compareAltSvc(result, altSvc)
+
+	return resp.JSON(result)
+}
+
+// checkHTTPSRecords looks up the HTTPS records of an origin, following
+// AliasMode records, and probes the endpoints of the ServiceMode records.
+func checkHTTPSRecords(ctx context.Context, domain string, port int, servers []string, timeout time.Duration) *HTTPSRecordCheckResponse {
+	result := &HTTPSRecordCheckResponse{
+		Domain:    domain,
+		Port:      port,
+		QueryName: httpsQueryName(domain, port),
+		Records:   make([]map[string]any, 0),
+		Endpoints: make([]HTTPSEndpointCheck, 0),
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	query := func(name string, qtype uint16) (*serverResponse, error) {
+		m := new(dns.Msg)
+		m.SetQuestion(name, qtype)
+		m.RecursionDesired = true
+		m.SetEdns0(4096, false)
+		return queryServers(ctx, m, servers, timeout)
+	}
+
+	name := result.QueryName
+	var services []*dns.SVCB
+	for {
+		answered, err := query(name, dns.TypeHTTPS)
+		if err != nil {
+			result.Issues = append(result.Issues, fmt.Sprintf("query for %s failed: %s", name, err))
+			return result
+		}
+
+		var records []dns.RR
+		var alias *dns.SVCB
+		services = nil
+		for _, rr := range answered.Response.Answer {
+			svcb, ok := svcbData(rr)
+			if !ok || rr.Header().Rrtype != dns.TypeHTTPS {
+				continue
+			}
+
+			records = append(records, rr)
+			if svcb.Priority == 0 {
+				alias = svcb
+			} else {
+				services = append(services, svcb)
+			}
+		}
+		result.Records = append(result.Records, formatResourceRecords(records)...)
+
+		if len(records) == 0 {
+			if len(result.AliasChain) == 0 {
+				result.Issues = append(result.Issues, fmt.Sprintf("no HTTPS records found at %s; clients connect to %s over TCP with the default protocols", name, domain))
+			} else {
+				result.Issues = append(result.Issues, fmt.Sprintf("the alias target %s has no HTTPS records; clients connect to its addresses over TCP with the default protocols", name))
+			}
+			return result
+		}
+
+		// An AliasMode record makes clients ignore the rest of the RRset (RFC 9460 section 2.4.2)
+		if alias == nil {
+			break
+		}
+
+		if alias.Target == "." {
+			result.Issues = append(result.Issues, fmt.Sprintf("the AliasMode record at %s has the target \".\", which means the service is not available", name))
+			return result
+		}
+
+		if slices.Contains(result.AliasChain, alias.Target) || len(result.AliasChain) >= maxCNAMEHops {
+			result.Issues = append(result.Issues, fmt.Sprintf("stopped following AliasMode records at %s to avoid a loop", alias.Target))
+			return result
+		}
+
+		result.AliasChain = append(result.AliasChain, alias.Target)
+		name = alias.Target
+	}
+
+	// Clients try the endpoints in priority order
+	sort.SliceStable(services, func(i, j int) bool {
+		return services[i].Priority < services[j].Priority
+	})
+
+	if len(services) > maxHTTPSEndpoints {
+		result.Issues = append(result.Issues, fmt.Sprintf("only the first %d of %d ServiceMode records were probed", maxHTTPSEndpoints, len(services)))
+		services = services[:maxHTTPSEndpoints]
+	}
+
+	for _, service := range services {
+		result.Endpoints = append(result.Endpoints, checkHTTPSEndpoint(ctx, domain, port, service, query, timeout))
+	}
+
+	return result
+}
+
+// httpsQueryName returns the name holding the HTTPS records of an origin:
+// the origin itself on the default port, or a port-prefixed name otherwise
+// (RFC 9460 section 9.1).
+func httpsQueryName(domain string, port int) string {
+	if port == 443 {
+		return dns.Fqdn(domain)
+	}
+	return "_" + strconv.Itoa(port) + "._https." + dns.Fqdn(domain)
+}
+
+// checkHTTPSEndpoint resolves the target of a ServiceMode record and
+// connects to it to check each advertised protocol, address hint and the ECH
+// configs. TLS handshakes use the origin as the server name, as clients do.
+func checkHTTPSEndpoint(ctx context.Context, origin string, port int, service *dns.SVCB, query func(string, uint16) (*serverResponse, error), timeout time.Duration) HTTPSEndpointCheck {
+	decoded := decodeSVCB(service)
+
+	endpoint := HTTPSEndpointCheck{
+		Priority:  service.Priority,
+		Target:    service.Target,
+		Port:      port,
+		Addresses: make([]string, 0),
+		Protocols: make([]HTTPSProtocolCheck, 0),
+	}
+
+	// A ServiceMode target of "." stands for the owner name
+	if endpoint.Target == "." {
+		endpoint.Target = service.Hdr.Name
+	}
+	if decoded.Port != 0 {
+		endpoint.Port = int(decoded.Port)
+	}
+
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		answered, err := query(endpoint.Target, qtype)
+		if err != nil {
+			endpoint.Issues = append(endpoint.Issues, fmt.Sprintf("%s query for %s failed: %s", dns.TypeToString[qtype], endpoint.Target, err))
+			continue
+		}
+
+		for _, rr := range answered.Response.Answer {
+			switch record := rr.(type) {
+			case *dns.A:
+				endpoint.Addresses = append(endpoint.Addresses, record.A.String())
+			case *dns.AAAA:
+				endpoint.Addresses = append(endpoint.Addresses, record.AAAA.String())
+			}
+		}
+	}
+
+	for _, hint := range append(decoded.IPv4Hint, decoded.IPv6Hint...) {
+		endpoint.Hints = append(endpoint.Hints, HTTPSHintCheck{
+			Address: hint,
+			InDNS:   slices.Contains(endpoint.Addresses, hint),
+		})
+	}
+
+	// Connect to the target's own addresses, falling back to the hints
+	var address string
+	switch {
+	case len(endpoint.Addresses) > 0:
+		address = endpoint.Addresses[0]
+	case len(endpoint.Hints) > 0:
+		address = endpoint.Hints[0].Address
+		endpoint.Issues = append(endpoint.Issues, fmt.Sprintf("%s has no A or AAAA records, so only clients using the hints can connect", endpoint.Target))
+	default:
+		endpoint.Issues = append(endpoint.Issues, fmt.Sprintf("%s has no A or AAAA records and the record has no hints, so clients can't connect", endpoint.Target))
+		return endpoint
+	}
+
+	protocols := slices.Clone(decoded.ALPN)
+	if !decoded.NoDefaultALPN && !slices.Contains(protocols, defaultHTTPSALPN) {
+		protocols = append(protocols, defaultHTTPSALPN)
+	}
+
+	var tcpProtocols []string
+	for _, protocol := range protocols {
+		check := HTTPSProtocolCheck{Protocol: protocol, Transport: transportTLS}
+		if isHTTP3(protocol) {
+			check.Transport = transportQUIC
+		} else {
+			tcpProtocols = append(tcpProtocols, protocol)
+		}
+		endpoint.Protocols = append(endpoint.Protocols, check)
+	}
+
+	var eg errgroup.Group
+	eg.SetLimit(httpsProbeConcurrency)
+
+	for i := range endpoint.Protocols {
+		check := &endpoint.Protocols[i]
+		eg.Go(func() error {
+			probeProtocol(ctx, check, address, endpoint.Port, origin, timeout)
+			return nil
+		})
+	}
+
+	for i := range endpoint.Hints {
+		hint := &endpoint.Hints[i]
+		eg.Go(func() error {
+			if _, err := internaltls.Handshake(ctx, hint.Address, endpoint.Port, origin, tcpProtocols, nil, timeout); err != nil {
+				hint.Error = err.Error()
+			} else {
+				hint.Reachable = true
+			}
+			return nil
+		})
+	}
+
+	if echConfigs := echConfigList(service); echConfigs != nil {
+		endpoint.ECH = &HTTPSECHCheck{}
+		eg.Go(func() error {
+			probeECH(ctx, endpoint.ECH, echConfigs, address, endpoint.Port, origin, tcpProtocols, timeout)
+			return nil
+		})
+	}
+
+	_ = eg.Wait()
+
+	summarizeHTTPSEndpoint(&endpoint, decoded)
+	return endpoint
+}
+
+// isHTTP3 reports whether an ALPN protocol ID is HTTP/3 or one of its
+// drafts, which run over QUIC rather than TLS over TCP.
+func isHTTP3(protocol string) bool {
+	return protocol == "h3" || strings.HasPrefix(protocol, "h3-")
+}
+
+// echConfigList returns the raw ECHConfigList of the "ech" parameter.
+func echConfigList(service *dns.SVCB) []byte {
+	for _, kv := range service.Value {
+		if ech, ok := kv.(*dns.SVCBECHConfig); ok {
+			return ech.ECH
+		}
+	}
+	return nil
+}
+
+// probeProtocol offers a single application protocol to the endpoint and
+// records whether the server selected it.
+func probeProtocol(ctx context.Context, check *HTTPSProtocolCheck, address string, port int, serverName string, timeout time.Duration) {
+	var negotiated *internaltls.HandshakeResult
+	var err error
+	if check.Transport == transportQUIC {
+		negotiated, err = internaltls.QUICHandshake(ctx, address, port, serverName, []string{check.Protocol}, timeout)
+	} else {
+		negotiated, err = internaltls.Handshake(ctx, address, port, serverName, []string{check.Protocol}, nil, timeout)
+	}
+	if err != nil {
+		check.Error = err.Error()
+		return
+	}
+
+	check.TLSVersion = negotiated.Version
+	check.NegotiatedProtocol = negotiated.NegotiatedProtocol
+
+	// Servers without ALPN support select no protocol and speak HTTP/1.1
+	check.Negotiated = negotiated.NegotiatedProtocol == check.Protocol ||
+		(negotiated.NegotiatedProtocol == "" && check.Protocol == defaultHTTPSALPN)
+}
+
+// probeECH performs a handshake with the advertised ECH configs and records
+// whether the server accepted them, along with the configs it suggests
+// instead when it doesn't.
+func probeECH(ctx context.Context, check *HTTPSECHCheck, configs []byte, address string, port int, serverName string, protocols []string, timeout time.Duration) {
+	negotiated, err := internaltls.Handshake(ctx, address, port, serverName, protocols, configs, timeout)
+	if err == nil {
+		check.Accepted = negotiated.ECHAccepted
+		return
+	}
+
+	var rejection *tls.ECHRejectionError
+	if errors.As(err, &rejection) && len(rejection.RetryConfigList) > 0 {
+		check.RetryConfigs = decodeECHConfigList(rejection.RetryConfigList).Configs
+	}
+	check.Error = err.Error()
+}
+
+// summarizeHTTPSEndpoint adds an issue for every advertised feature the
+// endpoint doesn't deliver.
+func summarizeHTTPSEndpoint(endpoint *HTTPSEndpointCheck, decoded SVCBRecord) {
+	endpoint.Issues = append(endpoint.Issues, decoded.Issues...)
+
+	for _, check := range endpoint.Protocols {
+		switch {
+		case check.Error != "":
+			endpoint.Issues = append(endpoint.Issues, fmt.Sprintf("%s is advertised, but the %s handshake offering it failed: %s", check.Protocol, strings.ToUpper(check.Transport), check.Error))
+		case !check.Negotiated && check.NegotiatedProtocol == "":
+			endpoint.Issues = append(endpoint.Issues, fmt.Sprintf("%s is advertised, but the server didn't select any protocol when offered it", check.Protocol))
+		case !check.Negotiated:
+			endpoint.Issues = append(endpoint.Issues, fmt.Sprintf("%s is advertised, but the server selected %s when offered it", check.Protocol, check.NegotiatedProtocol))
+		}
+	}
+
+	for _, hint := range endpoint.Hints {
+		if !hint.InDNS {
+			endpoint.Issues = append(endpoint.Issues, fmt.Sprintf("hint %s is not an address of %s; clients may connect to it before the target is resolved", hint.Address, endpoint.Target))
+		}
+		if !hint.Reachable {
+			endpoint.Issues = append(endpoint.Issues, fmt.Sprintf("hint %s is not reachable: %s", hint.Address, hint.Error))
+		}
+	}
+
+	if endpoint.ECH != nil && !endpoint.ECH.Accepted {
+		issue := "the server didn't accept the advertised ECH configs"
+		if endpoint.ECH.Error != "" {
+			issue += ": " + endpoint.ECH.Error
+		}
+		if len(endpoint.ECH.RetryConfigs) > 0 {
+			issue += fmt.Sprintf("; it offered %d retry config(s), so the published configs are probably stale", len(endpoint.ECH.RetryConfigs))
+		}
+		endpoint.Issues = append(endpoint.Issues, issue)
+	}
+}
+
+// compareAltSvc compares the HTTP/3 advertisement of the HTTPS records with
+// the Alt-Svc header of the origin, which clients that don't query HTTPS
+// records rely on.
+func compareAltSvc(result *HTTPSRecordCheckResponse, altSvc *HTTPSAltSvcCheck) {
+	result.AltSvc = altSvc
+	if altSvc.Error != "" {
+		result.Issues = append(result.Issues, fmt.Sprintf("the HTTP request to %s failed: %s", altSvc.URL, altSvc.Error))
+		return
+	}
+
+	recordHTTP3 := false
+	for _, endpoint := range result.Endpoints {
+		for _, check := range endpoint.Protocols {
+			recordHTTP3 = recordHTTP3 || isHTTP3(check.Protocol)
+		}
+	}
+
+	altSvcHTTP3 := slices.ContainsFunc(altSvc.Protocols, isHTTP3)
+
+	switch {
+	case altSvcHTTP3 && !recordHTTP3:
+		result.Issues = append(result.Issues, "the origin advertises HTTP/3 in its Alt-Svc header but not in its HTTPS records, so clients only use HTTP/3 after a first connection over TCP")
+	case recordHTTP3 && !altSvcHTTP3:
+		result.Issues = append(result.Issues, "the HTTPS records advertise HTTP/3 but the origin's Alt-Svc header doesn't, so clients that don't query HTTPS records never use HTTP/3")
+	}
+}
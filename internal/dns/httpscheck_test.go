@@ -0,0 +1,135 @@
+package dns
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startTLSServer starts a TLS server on localhost that completes handshakes
+// selecting one of the given protocols, and returns its port.
+func startTLSServer(t *testing.T, protocols ...string) int {
+	t.Helper()
+
+	cert, _ := testCertificate(t)
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   protocols,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_ = conn.(*tls.Conn).Handshake()
+				_ = conn.Close()
+			}()
+		}
+	}()
+
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestHTTPSQueryName(t *testing.T) {
+	assert.Equal(t, "example.com.", httpsQueryName("example.com", 443))
+	assert.Equal(t, "_8443._https.example.com.", httpsQueryName("example.com", 8443))
+}
+
+func TestCheckHTTPSRecords(t *testing.T) {
+	port := startTLSServer(t, "h2", "http/1.1")
+	ech := base64.StdEncoding.EncodeToString(echConfigListOf(echConfig(t, echConfigVersion, 1, "public.example.com")))
+
+	servers := []string{startZoneServer(t, mustRRs(t,
+		"example.com. 300 IN HTTPS 0 svc.example.net.",
+		fmt.Sprintf("svc.example.net. 300 IN HTTPS 1 . alpn=h2,h3 port=%d ipv4hint=127.0.0.1,127.0.0.2 ech=%s", port, ech),
+		"svc.example.net. 300 IN A 127.0.0.1",
+		"example.org. 300 IN HTTPS 0 .",
+	))}
+
+	t.Run("service behind an alias", func(t *testing.T) {
+		result := checkHTTPSRecords(context.Background(), "example.com", 443, servers, time.Second)
+
+		assert.Equal(t, []string{"svc.example.net."}, result.AliasChain)
+		assert.Len(t, result.Records, 2)
+		require.Len(t, result.Endpoints, 1)
+
+		endpoint := result.Endpoints[0]
+		assert.Equal(t, "svc.example.net.", endpoint.Target)
+		assert.Equal(t, port, endpoint.Port)
+		assert.Equal(t, []string{"127.0.0.1"}, endpoint.Addresses)
+
+		require.Len(t, endpoint.Protocols, 3)
+		assert.Equal(t, "h2", endpoint.Protocols[0].Protocol)
+		assert.True(t, endpoint.Protocols[0].Negotiated)
+		assert.Equal(t, "h3", endpoint.Protocols[1].Protocol)
+		assert.Equal(t, transportQUIC, endpoint.Protocols[1].Transport)
+		assert.False(t, endpoint.Protocols[1].Negotiated)
+		assert.Equal(t, defaultHTTPSALPN, endpoint.Protocols[2].Protocol)
+		assert.True(t, endpoint.Protocols[2].Negotiated)
+
+		require.Len(t, endpoint.Hints, 2)
+		assert.Equal(t, HTTPSHintCheck{Address: "127.0.0.1", InDNS: true, Reachable: true}, endpoint.Hints[0])
+		assert.False(t, endpoint.Hints[1].InDNS)
+		assert.False(t, endpoint.Hints[1].Reachable)
+
+		require.NotNil(t, endpoint.ECH)
+		assert.False(t, endpoint.ECH.Accepted)
+		assert.NotEmpty(t, endpoint.ECH.Error)
+
+		assert.Contains(t, endpoint.Issues[0], "h3 is advertised, but the QUIC handshake offering it failed")
+		assert.Contains(t, endpoint.Issues, "hint 127.0.0.2 is not an address of svc.example.net.; clients may connect to it before the target is resolved")
+		assert.Contains(t, endpoint.Issues[len(endpoint.Issues)-1], "the server didn't accept the advertised ECH configs")
+	})
+
+	t.Run("service not available", func(t *testing.T) {
+		result := checkHTTPSRecords(context.Background(), "example.org", 443, servers, time.Second)
+		assert.Empty(t, result.Endpoints)
+		assert.Equal(t, []string{`the AliasMode record at example.org. has the target ".", which means the service is not available`}, result.Issues)
+	})
+
+	t.Run("no records", func(t *testing.T) {
+		result := checkHTTPSRecords(context.Background(), "example.net", 8443, servers, time.Second)
+		assert.Equal(t, "_8443._https.example.net.", result.QueryName)
+		assert.Empty(t, result.Records)
+		assert.Contains(t, result.Issues[0], "no HTTPS records found at _8443._https.example.net.")
+	})
+}
+
+func TestCompareAltSvc(t *testing.T) {
+	withProtocols := func(protocols ...string) *HTTPSRecordCheckResponse {
+		endpoint := HTTPSEndpointCheck{}
+		for _, protocol := range protocols {
+			endpoint.Protocols = append(endpoint.Protocols, HTTPSProtocolCheck{Protocol: protocol})
+		}
+		return &HTTPSRecordCheckResponse{Endpoints: []HTTPSEndpointCheck{endpoint}}
+	}
+
+	result := withProtocols("h2")
+	compareAltSvc(result, &HTTPSAltSvcCheck{Protocols: []string{"h3"}})
+	assert.Contains(t, result.Issues[0], "advertises HTTP/3 in its Alt-Svc header but not in its HTTPS records")
+
+	result = withProtocols("h3", "h2")
+	compareAltSvc(result, &HTTPSAltSvcCheck{})
+	assert.Contains(t, result.Issues[0], "the HTTPS records advertise HTTP/3 but the origin's Alt-Svc header doesn't")
+
+	result = withProtocols("h3-29", "h2")
+	compareAltSvc(result, &HTTPSAltSvcCheck{Protocols: []string{"h3"}})
+	assert.Empty(t, result.Issues)
+
+	result = withProtocols("h2")
+	compareAltSvc(result, &HTTPSAltSvcCheck{URL: "https://example.com:443/", Error: "request failed"})
+	assert.Equal(t, []string{"the HTTP request to https://example.com:443/ failed: request failed"}, result.Issues)
+}
@@ -0,0 +1,431 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/miekg/dns"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/domainname"
+	resp "github.com/patrickdappollonio/mcp-domaintools/internal/response"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/utils"
+	"golang.org/x/sync/errgroup"
+)
+
+// auditConcurrency limits how many authoritative servers are queried at once.
+const auditConcurrency = 8
+
+// nameserverAuditParams represents the parameters for nameserver audits.
+type nameserverAuditParams struct {
+	Domain     string `json:"domain"`
+	RecordType string `json:"record_type"`
+}
+
+// NameserverCheck represents the audit result for one address of one
+// authoritative nameserver.
+type NameserverCheck struct {
+	Nameserver    string   `json:"nameserver"`
+	Address       string   `json:"address"`
+	IPVersion     string   `json:"ip_version"`
+	Reachable     bool     `json:"reachable"`
+	Authoritative bool     `json:"authoritative"`
+	Lame          bool     `json:"lame"`
+	RTT           float64  `json:"rtt_ms"`
+	SOASerial     uint32   `json:"soa_serial,omitempty"`
+	NameServers   []string `json:"nameservers,omitempty"`
+	Rcode         string   `json:"rcode,omitempty"`
+	Records       []string `json:"records,omitempty"`
+	Issues        []string `json:"issues,omitempty"`
+	Error         string   `json:"error,omitempty"`
+}
+
+// NameserverAuditResponse represents the complete nameserver audit response.
+type NameserverAuditResponse struct {
+	Zone        string              `json:"zone"`
+	DomainName  *domainname.Name    `json:"domain_name,omitempty"`
+	Alias       string              `json:"alias,omitempty"`
+	RecordType  string              `json:"record_type"`
+	NameServers []string            `json:"nameservers"`
+	Consistent  bool                `json:"consistent"`
+	Serials     map[string][]string `json:"serials"`
+	Servers     []NameserverCheck   `json:"servers"`
+	Issues      []string            `json:"issues,omitempty"`
+	Timestamp   string              `json:"timestamp"`
+}
+
+// HandleNameserverAudit queries every authoritative nameserver of a zone
+// directly, over IPv4 and IPv6, and compares their answers.
+func HandleNameserverAudit(ctx context.Context, request mcp.CallToolRequest, config *QueryConfig) (*mcp.CallToolResult, error) {
+	var params nameserverAuditParams
+	if err := request.BindArguments(&params); err != nil {
+		return nil, fmt.Errorf("failed to parse tool input: %w", utils.ParseJSONUnmarshalError(err))
+	}
+
+	// Validate required parameters
+	if params.Domain == "" {
+		return nil, fmt.Errorf("parameter \"domain\" is required")
+	}
+
+	// Set default record type if not provided
+	if params.RecordType == "" {
+		params.RecordType = "A"
+	}
+
+	// Validate and normalize the domain name
+	name, err := domainname.Parse(params.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	recordType, err := ConvertToQType(params.RecordType)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := auditNameservers(ctx, name.FQDN(), recordType, config)
+	if err != nil {
+		return nil, fmt.Errorf("nameserver audit failed: %w", err)
+	}
+	result.DomainName = name
+
+	return resp.JSON(result)
+}
+
+// auditNameservers finds the zone's nameservers and queries each of their
+// addresses for the zone's SOA and NS records and the chosen record type.
+func auditNameservers(ctx context.Context, domain string, qtype uint16, config *QueryConfig) (*NameserverAuditResponse, error) {
+	zone, alias, err := findZoneApex(ctx, domain, config)
+	if err != nil {
+		return nil, err
+	}
+
+	nameservers, err := lookupNameServers(ctx, zone, config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve every nameserver to its IPv4 and IPv6 addresses
+	var checks []NameserverCheck
+	for _, ns := range nameservers {
+		ipv4, ipv6 := lookupAddresses(ctx, ns, config)
+		if len(ipv4) == 0 && len(ipv6) == 0 {
+			checks = append(checks, NameserverCheck{
+				Nameserver: ns,
+				Error:      "nameserver hostname does not resolve to any address",
+				Issues:     []string{"unresolvable"},
+			})
+			continue
+		}
+
+		for _, address := range ipv4 {
+			checks = append(checks, NameserverCheck{Nameserver: ns, Address: address, IPVersion: "ipv4"})
+		}
+		for _, address := range ipv6 {
+			checks = append(checks, NameserverCheck{Nameserver: ns, Address: address, IPVersion: "ipv6"})
+		}
+	}
+
+	var eg errgroup.Group
+	eg.SetLimit(auditConcurrency)
+	for i := range checks {
+		if checks[i].Address == "" {
+			continue
+		}
+		eg.Go(func() error {
+			queryAuthoritativeServer(ctx, zone, domain, qtype, &checks[i], config)
+			return nil
+		})
+	}
+	_ = eg.Wait()
+
+	result := &NameserverAuditResponse{
+		Zone:        zone,
+		Alias:       alias,
+		RecordType:  dns.TypeToString[qtype],
+		NameServers: nameservers,
+		Serials:     make(map[string][]string),
+		Servers:     checks,
+		Timestamp:   time.Now().Format(time.RFC3339),
+	}
+	compareNameserverChecks(result)
+
+	return result, nil
+}
+
+// queryAuthoritativeServer sends non-recursive SOA, NS and record queries to a
+// single server address and records the outcome in check.
+func queryAuthoritativeServer(ctx context.Context, zone, domain string, qtype uint16, check *NameserverCheck, config *QueryConfig) {
+	address := serverAddress(check.Address)
+
+	query := func(name string, qtype uint16) (*dns.Msg, time.Duration, error) {
+		m := new(dns.Msg)
+		m.SetQuestion(name, qtype)
+		m.RecursionDesired = false
+		m.SetEdns0(4096, false)
+		answered, err := exchangeWithTCPFallback(ctx, m, address, transportUDP, config.Timeout)
+		if err != nil {
+			return nil, 0, err
+		}
+		return answered.Response, answered.RTT, nil
+	}
+
+	// SOA query: reachability, authority and serial
+	soaResponse, rtt, err := query(zone, dns.TypeSOA)
+	if err != nil {
+		check.Error = err.Error()
+		check.Issues = append(check.Issues, "unreachable")
+		return
+	}
+
+	check.Reachable = true
+	check.RTT = float64(rtt.Microseconds()) / 1000
+	check.Authoritative = soaResponse.Authoritative
+
+	// A serial of 0 is valid, so the presence of the SOA is tracked apart
+	soaFound := false
+	for _, rr := range soaResponse.Answer {
+		if soa, ok := rr.(*dns.SOA); ok && strings.EqualFold(soa.Header().Name, zone) {
+			check.SOASerial = soa.Serial
+			soaFound = true
+		}
+	}
+
+	if soaResponse.Rcode != dns.RcodeSuccess || !soaResponse.Authoritative {
+		check.Lame = true
+		check.Issues = append(check.Issues, fmt.Sprintf("lame: answered SOA query with %s, authoritative=%t", dns.RcodeToString[soaResponse.Rcode], soaResponse.Authoritative))
+		return
+	}
+
+	if !soaFound {
+		check.Lame = true
+		check.Issues = append(check.Issues, fmt.Sprintf("lame: no SOA record for %s in the answer", zone))
+		return
+	}
+
+	// NS query: the server's view of the zone's nameservers
+	nsResponse, _, err := query(zone, dns.TypeNS)
+	if err != nil {
+		check.Issues = append(check.Issues, fmt.Sprintf("NS query failed: %v", err))
+	} else {
+		check.NameServers = nameServerTargets(nsResponse.Answer, zone)
+		if !nsResponse.Authoritative {
+			check.Issues = append(check.Issues, "NS answer is missing the AA bit")
+		}
+	}
+
+	// Record query: the chosen record type at the queried name
+	recordResponse, _, err := query(domain, qtype)
+	if err != nil {
+		check.Issues = append(check.Issues, fmt.Sprintf("%s query failed: %v", dns.TypeToString[qtype], err))
+		return
+	}
+
+	check.Rcode = dns.RcodeToString[recordResponse.Rcode]
+	check.Records = normalizedRecords(recordResponse.Answer)
+	if !recordResponse.Authoritative {
+		check.Authoritative = false
+		check.Issues = append(check.Issues, fmt.Sprintf("%s answer is missing the AA bit", dns.TypeToString[qtype]))
+	}
+}
+
+// compareNameserverChecks compares the answers of every responsive server and
+// flags those that disagree with the majority.
+func compareNameserverChecks(result *NameserverAuditResponse) {
+	serialCounts := make(map[uint32]int)
+	nsSetCounts := make(map[string]int)
+	recordCounts := make(map[string]int)
+
+	for _, check := range result.Servers {
+		if !check.Reachable || check.Lame {
+			continue
+		}
+
+		label := check.Nameserver + " (" + check.Address + ")"
+		serial := strconv.FormatUint(uint64(check.SOASerial), 10)
+		result.Serials[serial] = append(result.Serials[serial], label)
+
+		serialCounts[check.SOASerial]++
+		nsSetCounts[strings.Join(check.NameServers, " ")]++
+		recordCounts[check.Rcode+" "+strings.Join(check.Records, "\n")]++
+	}
+
+	expectedSerial := mostCommon(serialCounts)
+	expectedNSSet := mostCommon(nsSetCounts)
+	expectedRecords := mostCommon(recordCounts)
+
+	result.Consistent = true
+	for i := range result.Servers {
+		check := &result.Servers[i]
+		label := check.Nameserver
+		if check.Address != "" {
+			label += " (" + check.Address + ")"
+		}
+
+		if !check.Reachable || check.Lame {
+			result.Consistent = false
+			result.Issues = append(result.Issues, fmt.Sprintf("%s: %s", label, strings.Join(check.Issues, "; ")))
+			continue
+		}
+
+		if check.SOASerial != expectedSerial {
+			check.Issues = append(check.Issues, fmt.Sprintf("out of sync: SOA serial %d, most servers have %d", check.SOASerial, expectedSerial))
+		}
+
+		if strings.Join(check.NameServers, " ") != expectedNSSet {
+			check.Issues = append(check.Issues, "NS set differs from the other servers")
+		}
+
+		if !slices.Equal(check.NameServers, result.NameServers) {
+			check.Issues = append(check.Issues, "NS set differs from the one returned by the recursive resolvers")
+		}
+
+		if check.Rcode+" "+strings.Join(check.Records, "\n") != expectedRecords {
+			check.Issues = append(check.Issues, fmt.Sprintf("%s answer differs from the other servers", result.RecordType))
+		}
+
+		if len(check.Issues) > 0 {
+			result.Consistent = false
+			result.Issues = append(result.Issues, fmt.Sprintf("%s: %s", label, strings.Join(check.Issues, "; ")))
+		}
+	}
+}
+
+// mostCommon returns the key with the highest count. Ties are broken by
+// choosing the greatest key, so that newer SOA serials win.
+func mostCommon[K uint32 | string](counts map[K]int) K {
+	var best K
+	bestCount := -1
+	for key, count := range counts {
+		if count > bestCount || (count == bestCount && key > best) {
+			best = key
+			bestCount = count
+		}
+	}
+	return best
+}
+
+// findZoneApex returns the zone that contains the given name, using the SOA
+// records returned by the local resolvers, and the target of the name when
+// it's an alias.
+func findZoneApex(ctx context.Context, domain string, config *QueryConfig) (string, string, error) {
+	servers, err := getSystemDNSServers(ctx)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get DNS servers: %w", err)
+	}
+
+	return findZoneApexWithServers(ctx, domain, servers, config.Timeout)
+}
+
+// findZoneApexWithServers returns the zone that contains the given name and
+// the target of the name when it's an alias. Resolvers follow a CNAME when
+// answering, so the SOA they return may belong to the target's zone: only an
+// SOA owned by the name or one of its parents is accepted. An alias can't be
+// a zone apex, so the zone that holds it is the one containing its parent.
+func findZoneApexWithServers(ctx context.Context, domain string, servers []string, timeout time.Duration) (string, string, error) {
+	var alias string
+	name := domain
+	for {
+		m := new(dns.Msg)
+		m.SetQuestion(name, dns.TypeSOA)
+		m.RecursionDesired = true
+
+		answered, err := queryServers(ctx, m, servers, timeout)
+		if err != nil {
+			return "", "", err
+		}
+		response := answered.Response
+
+		if hop, ok := findAlias(name, response.Answer); ok && hop.Type == "CNAME" {
+			if name == domain {
+				alias = hop.Target
+			}
+		} else if zone, ok := zoneApexFromResponse(response, name); ok {
+			return zone, alias, nil
+		}
+
+		if name == "." {
+			return "", "", fmt.Errorf("could not find the zone containing %s: no SOA record returned (%s)", domain, dns.RcodeToString[response.Rcode])
+		}
+		name = ancestorName(name, dns.CountLabel(name)-1)
+	}
+}
+
+// zoneApexFromResponse returns the owner of the SOA record in the answer or
+// authority section of a response to an SOA query for name, which is the zone
+// that contains the name. An SOA owned by anything other than the name or one
+// of its parents belongs to an alias target and is ignored.
+func zoneApexFromResponse(response *dns.Msg, name string) (string, bool) {
+	for _, section := range [][]dns.RR{response.Answer, response.Ns} {
+		for _, rr := range section {
+			if soa, ok := rr.(*dns.SOA); ok && dns.IsSubDomain(soa.Header().Name, name) {
+				return dns.Fqdn(soa.Header().Name), true
+			}
+		}
+	}
+	return "", false
+}
+
+// lookupNameServers returns the sorted NS targets for a zone as seen by the
+// local resolvers.
+func lookupNameServers(ctx context.Context, zone string, config *QueryConfig) ([]string, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(zone, dns.TypeNS)
+	m.RecursionDesired = true
+
+	response, err := exchangeWithSystemServers(ctx, m, config)
+	if err != nil {
+		return nil, err
+	}
+
+	nameservers := nameServerTargets(response.Answer, zone)
+	if len(nameservers) == 0 {
+		return nil, fmt.Errorf("no NS records found for zone %s", zone)
+	}
+
+	return nameservers, nil
+}
+
+// nameServerTargets returns the sorted, lowercased NS targets for the zone
+// found in the given records.
+func nameServerTargets(records []dns.RR, zone string) []string {
+	var targets []string
+	for _, rr := range records {
+		if ns, ok := rr.(*dns.NS); ok && strings.EqualFold(ns.Header().Name, zone) {
+			targets = append(targets, dns.CanonicalName(ns.Ns))
+		}
+	}
+	slices.Sort(targets)
+	return slices.Compact(targets)
+}
+
+// lookupAddresses resolves a hostname to its IPv4 and IPv6 addresses using
+// the local resolvers. Lookup failures result in empty lists.
+func lookupAddresses(ctx context.Context, name string, config *QueryConfig) ([]string, []string) {
+	var ipv4, ipv6 []string
+
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		m := new(dns.Msg)
+		m.SetQuestion(dns.Fqdn(name), qtype)
+		m.RecursionDesired = true
+
+		response, err := exchangeWithSystemServers(ctx, m, config)
+		if err != nil {
+			continue
+		}
+
+		for _, rr := range response.Answer {
+			switch rec := rr.(type) {
+			case *dns.A:
+				ipv4 = append(ipv4, rec.A.String())
+			case *dns.AAAA:
+				ipv6 = append(ipv6, rec.AAAA.String())
+			}
+		}
+	}
+
+	return ipv4, ipv6
+}
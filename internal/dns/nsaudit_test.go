@@ -0,0 +1,207 @@
+package dns
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMostCommon(t *testing.T) {
+	tests := []struct {
+		name   string
+		counts map[uint32]int
+		want   uint32
+	}{
+		{name: "single", counts: map[uint32]int{2024010101: 3}, want: 2024010101},
+		{name: "majority", counts: map[uint32]int{2024010101: 3, 2024010102: 1}, want: 2024010101},
+		{name: "tie picks the larger serial", counts: map[uint32]int{2024010101: 2, 2024010102: 2}, want: 2024010102},
+		{name: "serial zero", counts: map[uint32]int{0: 2, 7: 1}, want: 0},
+		{name: "empty", counts: map[uint32]int{}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, mostCommon(tt.counts))
+		})
+	}
+
+	assert.Equal(t, "b", mostCommon(map[string]int{"a": 1, "b": 1}))
+}
+
+func TestCompareNameserverChecks(t *testing.T) {
+	nameservers := []string{"ns1.example.com.", "ns2.example.com."}
+	healthy := func(ns, address string, serial uint32) NameserverCheck {
+		return NameserverCheck{
+			Nameserver:    ns,
+			Address:       address,
+			Reachable:     true,
+			Authoritative: true,
+			SOASerial:     serial,
+			NameServers:   nameservers,
+			Rcode:         "NOERROR",
+			Records:       []string{"example.com.\t300\tIN\tA\t192.0.2.1"},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		servers    []NameserverCheck
+		consistent bool
+		issues     []string
+	}{
+		{
+			name: "all servers agree",
+			servers: []NameserverCheck{
+				healthy("ns1.example.com.", "192.0.2.53", 2024010101),
+				healthy("ns2.example.com.", "198.51.100.53", 2024010101),
+			},
+			consistent: true,
+		},
+		{
+			name: "serial zero is a valid serial",
+			servers: []NameserverCheck{
+				healthy("ns1.example.com.", "192.0.2.53", 0),
+				healthy("ns2.example.com.", "198.51.100.53", 0),
+			},
+			consistent: true,
+		},
+		{
+			name: "a tie favors the newer serial",
+			servers: []NameserverCheck{
+				healthy("ns1.example.com.", "192.0.2.53", 2024010101),
+				healthy("ns2.example.com.", "198.51.100.53", 2024010102),
+			},
+			issues: []string{"ns1.example.com. (192.0.2.53): out of sync: SOA serial 2024010101, most servers have 2024010102"},
+		},
+		{
+			name: "differing answers and NS sets",
+			servers: []NameserverCheck{
+				healthy("ns1.example.com.", "192.0.2.53", 1),
+				healthy("ns1.example.com.", "2001:db8::53", 1),
+				func() NameserverCheck {
+					check := healthy("ns2.example.com.", "198.51.100.53", 1)
+					check.NameServers = []string{"ns2.example.com."}
+					check.Records = nil
+					return check
+				}(),
+			},
+			issues: []string{"ns2.example.com. (198.51.100.53): NS set differs from the other servers; NS set differs from the one returned by the recursive resolvers; A answer differs from the other servers"},
+		},
+		{
+			name: "lame and unresolvable servers",
+			servers: []NameserverCheck{
+				healthy("ns1.example.com.", "192.0.2.53", 1),
+				{Nameserver: "ns2.example.com.", Address: "198.51.100.53", Reachable: true, Lame: true, Issues: []string{"lame: no SOA record for example.com. in the answer"}},
+				{Nameserver: "ns3.example.com.", Issues: []string{"unresolvable"}},
+			},
+			issues: []string{
+				"ns2.example.com. (198.51.100.53): lame: no SOA record for example.com. in the answer",
+				"ns3.example.com.: unresolvable",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &NameserverAuditResponse{
+				RecordType:  "A",
+				NameServers: nameservers,
+				Serials:     make(map[string][]string),
+				Servers:     tt.servers,
+			}
+
+			compareNameserverChecks(result)
+
+			assert.Equal(t, tt.consistent, result.Consistent)
+			assert.Equal(t, tt.issues, result.Issues)
+		})
+	}
+
+	t.Run("serials are grouped", func(t *testing.T) {
+		result := &NameserverAuditResponse{
+			NameServers: nameservers,
+			Serials:     make(map[string][]string),
+			Servers: []NameserverCheck{
+				healthy("ns1.example.com.", "192.0.2.53", 0),
+				healthy("ns2.example.com.", "198.51.100.53", 0),
+			},
+		}
+
+		compareNameserverChecks(result)
+
+		require.Contains(t, result.Serials, "0")
+		assert.Equal(t, []string{"ns1.example.com. (192.0.2.53)", "ns2.example.com. (198.51.100.53)"}, result.Serials["0"])
+	})
+}
+
+func TestFindZoneApexWithServers(t *testing.T) {
+	// Responses of a recursive resolver to SOA queries, which follow aliases
+	// and return the SOA of the zone that answered in the authority section
+	responses := map[string]struct{ answer, ns []dns.RR }{
+		"example.com.":      {answer: mustRRs(t, "example.com. 300 IN SOA ns1.example.com. hostmaster.example.com. 1 7200 3600 1209600 300")},
+		"mail.example.com.": {ns: mustRRs(t, "example.com. 300 IN SOA ns1.example.com. hostmaster.example.com. 1 7200 3600 1209600 300")},
+		"www.example.com.": {
+			answer: mustRRs(t, "www.example.com. 300 IN CNAME edge.cdn.example.net."),
+			ns:     mustRRs(t, "cdn.example.net. 60 IN SOA ns1.cdn.example.net. ops.cdn.example.net. 1 7200 3600 1209600 60"),
+		},
+		"example.org.": {answer: mustRRs(t, "example.org. 300 IN SOA ns1.example.org. hostmaster.example.org. 1 7200 3600 1209600 300")},
+		"www.example.org.": {answer: mustRRs(t,
+			"www.example.org. 300 IN CNAME example.org.",
+			"example.org. 300 IN SOA ns1.example.org. hostmaster.example.org. 1 7200 3600 1209600 300",
+		)},
+	}
+
+	address := startTestDNSServer(t, "udp", func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.RecursionAvailable = true
+
+		response, ok := responses[r.Question[0].Name]
+		if !ok {
+			m.Rcode = dns.RcodeNameError
+		}
+		m.Answer, m.Ns = response.answer, response.ns
+		_ = w.WriteMsg(m)
+	})
+
+	tests := []struct {
+		name  string
+		zone  string
+		alias string
+	}{
+		{name: "example.com.", zone: "example.com."},
+		{name: "mail.example.com.", zone: "example.com."},
+		{name: "www.example.com.", zone: "example.com.", alias: "edge.cdn.example.net."},
+		{name: "www.example.org.", zone: "example.org.", alias: "example.org."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zone, alias, err := findZoneApexWithServers(context.Background(), tt.name, []string{address}, 2*time.Second)
+			require.NoError(t, err)
+			assert.Equal(t, tt.zone, zone)
+			assert.Equal(t, tt.alias, alias)
+		})
+	}
+
+	t.Run("no zone", func(t *testing.T) {
+		_, _, err := findZoneApexWithServers(context.Background(), "www.example.invalid.", []string{address}, 2*time.Second)
+		require.Error(t, err)
+	})
+}
+
+func TestZoneApexFromResponse(t *testing.T) {
+	response := new(dns.Msg)
+	response.Ns = mustRRs(t, "cdn.example.net. 60 IN SOA ns1.cdn.example.net. ops.cdn.example.net. 1 7200 3600 1209600 60")
+
+	_, ok := zoneApexFromResponse(response, "www.example.com.")
+	assert.False(t, ok)
+
+	zone, ok := zoneApexFromResponse(response, "edge.cdn.example.net.")
+	require.True(t, ok)
+	assert.Equal(t, "cdn.example.net.", zone)
+}
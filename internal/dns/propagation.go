@@ -0,0 +1,308 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/miekg/dns"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/domainname"
+	resp "github.com/patrickdappollonio/mcp-domaintools/internal/response"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/utils"
+	"golang.org/x/sync/errgroup"
+)
+
+// maxPropagationResolvers limits how many resolvers a single propagation
+// check can query.
+const maxPropagationResolvers = 32
+
+// propagationConcurrency limits how many resolvers are queried at once.
+const propagationConcurrency = 8
+
+// systemResolversKeyword expands to every OS-defined DNS server.
+const systemResolversKeyword = "system"
+
+// defaultPropagationResolvers is the set of resolvers queried when none are
+// provided: the system resolvers plus well-known public resolvers.
+var defaultPropagationResolvers = []string{
+	systemResolversKeyword,
+	"8.8.8.8",
+	"1.1.1.1",
+	"9.9.9.9",
+	"208.67.222.222",
+	"https://cloudflare-dns.com/dns-query",
+	"https://dns.google/dns-query",
+}
+
+// propagationParams represents the parameters for propagation checks.
+type propagationParams struct {
+	Domain     string   `json:"domain"`
+	RecordType string   `json:"record_type"`
+	Resolvers  []string `json:"resolvers"`
+}
+
+// PropagationResult represents the answer from a single resolver.
+type PropagationResult struct {
+	Resolver    string           `json:"resolver"`
+	Transport   string           `json:"transport"`
+	Rcode       string           `json:"rcode,omitempty"`
+	Answer      []map[string]any `json:"answer,omitempty"`
+	MinTTL      uint32           `json:"min_ttl"`
+	RTT         float64          `json:"rtt_ms"`
+	Error       string           `json:"error,omitempty"`
+	fingerprint string
+}
+
+// PropagationGroup represents a set of resolvers that returned the same answer.
+type PropagationGroup struct {
+	Rcode     string   `json:"rcode"`
+	Records   []string `json:"records"`
+	Resolvers []string `json:"resolvers"`
+}
+
+// PropagationResponse represents the complete propagation check response.
+type PropagationResponse struct {
+	Domain            string              `json:"domain"`
+	DomainName        *domainname.Name    `json:"domain_name,omitempty"`
+	RecordType        string              `json:"record_type"`
+	Consistent        bool                `json:"consistent"`
+	AgreeingResolvers []string            `json:"agreeing_resolvers"`
+	Groups            []PropagationGroup  `json:"groups"`
+	FailedResolvers   []string            `json:"failed_resolvers,omitempty"`
+	Results           []PropagationResult `json:"results"`
+	Timestamp         string              `json:"timestamp"`
+}
+
+// HandlePropagationCheck sends the same question to several resolvers at
+// once and reports where their answers agree or differ.
+func HandlePropagationCheck(ctx context.Context, request mcp.CallToolRequest, config *QueryConfig) (*mcp.CallToolResult, error) {
+	var params propagationParams
+	if err := request.BindArguments(&params); err != nil {
+		return nil, fmt.Errorf("failed to parse tool input: %w", utils.ParseJSONUnmarshalError(err))
+	}
+
+	// Validate required parameters
+	if params.Domain == "" {
+		return nil, fmt.Errorf("parameter \"domain\" is required")
+	}
+
+	// Set default record type if not provided
+	if params.RecordType == "" {
+		params.RecordType = "A"
+	}
+
+	// Set default resolvers if not provided
+	if len(params.Resolvers) == 0 {
+		params.Resolvers = defaultPropagationResolvers
+	}
+
+	// Validate and normalize the domain name
+	name, err := domainname.Parse(params.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	recordType, err := ConvertToQType(params.RecordType)
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err := parseResolverTargets(ctx, params.Resolvers)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(targets) > maxPropagationResolvers {
+		return nil, fmt.Errorf("too many resolvers: %d provided, maximum is %d", len(targets), maxPropagationResolvers)
+	}
+
+	result := checkPropagation(ctx, name.FQDN(), recordType, targets, config)
+	result.DomainName = name
+	return resp.JSON(result)
+}
+
+// parseResolverTargets converts resolver specifications into resolver targets.
+// Supported forms are "system", "https://..." for DNS-over-HTTPS, "tcp://host"
+// and "udp://host" with an optional port, and a bare host or host:port which
+// defaults to UDP.
+func parseResolverTargets(ctx context.Context, specs []string) ([]resolverTarget, error) {
+	var targets []resolverTarget
+	seen := make(map[string]bool)
+
+	add := func(target resolverTarget) {
+		key := target.Transport + "/" + target.Address
+		if !seen[key] {
+			seen[key] = true
+			targets = append(targets, target)
+		}
+	}
+
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		lower := strings.ToLower(spec)
+
+		switch {
+		case spec == "":
+			continue
+
+		case lower == systemResolversKeyword:
+			servers, err := getSystemDNSServers(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get DNS servers: %w", err)
+			}
+			for _, server := range servers {
+				add(resolverTarget{Name: "system:" + server, Transport: transportUDP, Address: serverAddress(server)})
+			}
+
+		case strings.Contains(lower, "://"):
+			target, err := parseResolverURL(spec)
+			if err != nil {
+				return nil, err
+			}
+			add(target)
+
+		default:
+			add(resolverTarget{Name: spec, Transport: transportUDP, Address: serverAddress(spec)})
+		}
+	}
+
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no resolvers to query")
+	}
+
+	return targets, nil
+}
+
+// checkPropagation queries every resolver concurrently and groups the
+// resolvers by the answer they returned.
+func checkPropagation(ctx context.Context, domain string, qtype uint16, targets []resolverTarget, config *QueryConfig) *PropagationResponse {
+	results := make([]PropagationResult, len(targets))
+
+	var eg errgroup.Group
+	eg.SetLimit(propagationConcurrency)
+
+	for i, target := range targets {
+		eg.Go(func() error {
+			results[i] = queryResolver(ctx, domain, qtype, target, config.Timeout)
+			return nil
+		})
+	}
+	_ = eg.Wait()
+
+	response := &PropagationResponse{
+		Domain:            domain,
+		RecordType:        dns.TypeToString[qtype],
+		AgreeingResolvers: make([]string, 0),
+		Groups:            make([]PropagationGroup, 0),
+		Results:           results,
+		Timestamp:         time.Now().Format(time.RFC3339),
+	}
+
+	// Group resolvers that returned identical answers
+	groupIndex := make(map[string]int)
+	for _, result := range results {
+		if result.Error != "" {
+			response.FailedResolvers = append(response.FailedResolvers, result.Resolver)
+			continue
+		}
+
+		idx, exists := groupIndex[result.fingerprint]
+		if !exists {
+			idx = len(response.Groups)
+			groupIndex[result.fingerprint] = idx
+			response.Groups = append(response.Groups, PropagationGroup{
+				Rcode:   result.Rcode,
+				Records: splitFingerprint(result.fingerprint),
+			})
+		}
+		response.Groups[idx].Resolvers = append(response.Groups[idx].Resolvers, result.Resolver)
+	}
+
+	// Largest group first, so it reads as the consensus answer
+	slices.SortStableFunc(response.Groups, func(a, b PropagationGroup) int {
+		return len(b.Resolvers) - len(a.Resolvers)
+	})
+
+	response.Consistent = len(response.Groups) == 1
+	if len(response.Groups) > 0 {
+		response.AgreeingResolvers = response.Groups[0].Resolvers
+	}
+
+	return response
+}
+
+// queryResolver sends the question to a single resolver and summarizes its answer.
+func queryResolver(ctx context.Context, domain string, qtype uint16, target resolverTarget, timeout time.Duration) PropagationResult {
+	result := PropagationResult{
+		Resolver:  target.Name,
+		Transport: target.Transport,
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion(domain, qtype)
+	m.RecursionDesired = true
+
+	response, _, rtt, err := exchangeWithResolver(ctx, m, target, timeout)
+	result.RTT = float64(rtt.Microseconds()) / 1000
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Rcode = dns.RcodeToString[response.Rcode]
+	result.Answer = formatResourceRecords(response.Answer)
+	result.MinTTL = minTTL(response.Answer)
+	result.fingerprint = answerFingerprint(response)
+	return result
+}
+
+// fingerprintSeparator separates records within an answer fingerprint.
+const fingerprintSeparator = "\n"
+
+// answerFingerprint builds a comparable representation of a response made of
+// the rcode and the normalized answer records, so that resolvers with
+// different cache ages still compare as equal.
+func answerFingerprint(response *dns.Msg) string {
+	records := normalizedRecords(response.Answer)
+	return dns.RcodeToString[response.Rcode] + fingerprintSeparator + strings.Join(records, fingerprintSeparator)
+}
+
+// normalizedRecords returns the sorted presentation form of the given records
+// with their TTLs removed and owner names lowercased.
+func normalizedRecords(records []dns.RR) []string {
+	normalized := make([]string, 0, len(records))
+	for _, rr := range records {
+		rr = dns.Copy(rr)
+		rr.Header().Ttl = 0
+		rr.Header().Name = dns.CanonicalName(rr.Header().Name)
+		normalized = append(normalized, strings.Join(strings.Fields(rr.String()), " "))
+	}
+	slices.Sort(normalized)
+	return normalized
+}
+
+// splitFingerprint returns the records contained in an answer fingerprint.
+func splitFingerprint(fingerprint string) []string {
+	parts := strings.Split(fingerprint, fingerprintSeparator)
+	records := make([]string, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		if part != "" {
+			records = append(records, part)
+		}
+	}
+	return records
+}
+
+// minTTL returns the lowest TTL among the given records, or zero if there are none.
+func minTTL(records []dns.RR) uint32 {
+	var lowest uint32
+	for i, rr := range records {
+		if ttl := rr.Header().Ttl; i == 0 || ttl < lowest {
+			lowest = ttl
+		}
+	}
+	return lowest
+}
@@ -0,0 +1,70 @@
+package dns
+
+import (
+	"context"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseResolverTargets(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("supported forms", func(t *testing.T) {
+		targets, err := parseResolverTargets(ctx, []string{
+			"8.8.8.8",
+			"1.1.1.1:5353",
+			"tcp://9.9.9.9",
+			"udp://[2001:4860:4860::8888]:53",
+			"2606:4700:4700::1111",
+			"https://dns.google/dns-query",
+			"8.8.8.8:53",
+		})
+		require.NoError(t, err)
+
+		expected := []resolverTarget{
+			{Name: "8.8.8.8", Transport: transportUDP, Address: "8.8.8.8:53"},
+			{Name: "1.1.1.1:5353", Transport: transportUDP, Address: "1.1.1.1:5353"},
+			{Name: "tcp://9.9.9.9", Transport: transportTCP, Address: "9.9.9.9:53"},
+			{Name: "udp://[2001:4860:4860::8888]:53", Transport: transportUDP, Address: "[2001:4860:4860::8888]:53"},
+			{Name: "2606:4700:4700::1111", Transport: transportUDP, Address: "[2606:4700:4700::1111]:53"},
+			{Name: "https://dns.google/dns-query", Transport: transportDoH, Address: "https://dns.google/dns-query"},
+		}
+		assert.Equal(t, expected, targets)
+	})
+
+	t.Run("unsupported scheme", func(t *testing.T) {
+		_, err := parseResolverTargets(ctx, []string{"ftp://example.com"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unsupported resolver")
+	})
+
+	t.Run("no resolvers", func(t *testing.T) {
+		_, err := parseResolverTargets(ctx, []string{" "})
+		require.Error(t, err)
+	})
+}
+
+func TestAnswerFingerprint(t *testing.T) {
+	newResponse := func(t *testing.T, records ...string) *dns.Msg {
+		t.Helper()
+		m := new(dns.Msg)
+		for _, record := range records {
+			rr, err := dns.NewRR(record)
+			require.NoError(t, err)
+			m.Answer = append(m.Answer, rr)
+		}
+		return m
+	}
+
+	a := newResponse(t, "example.com. 300 IN A 192.0.2.1", "example.com. 300 IN A 192.0.2.2")
+	b := newResponse(t, "EXAMPLE.com. 60 IN A 192.0.2.2", "example.com. 60 IN A 192.0.2.1")
+	c := newResponse(t, "example.com. 300 IN A 192.0.2.3")
+
+	assert.Equal(t, answerFingerprint(a), answerFingerprint(b))
+	assert.NotEqual(t, answerFingerprint(a), answerFingerprint(c))
+	assert.Equal(t, []string{"example.com. 0 IN A 192.0.2.1", "example.com. 0 IN A 192.0.2.2"}, splitFingerprint(answerFingerprint(a)))
+	assert.Equal(t, uint32(60), minTTL(b.Answer))
+}
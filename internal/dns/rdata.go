@@ -0,0 +1,146 @@
+package dns
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/miekg/dns"
+)
+
+// recordDataString returns the record data of a resource record in
+// presentation format, without the owner name, TTL, class and type.
+func recordDataString(rr dns.RR) string {
+	return strings.TrimPrefix(rr.String(), rr.Header().String())
+}
+
+// recordFields returns the record data of a resource record field by field,
+// keyed by the field names of the record type, so every record type is
+// decoded without losing fields.
+func recordFields(rr dns.RR) map[string]any {
+	fields := map[string]any{}
+
+	v := reflect.ValueOf(rr)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fields
+	}
+
+	addStructFields(fields, v.Elem())
+	return fields
+}
+
+// addStructFields adds the exported fields of a record struct to the map,
+// flattening embedded record types such as SVCB inside HTTPS.
+func addStructFields(fields map[string]any, v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || field.Type == reflect.TypeOf(dns.RR_Header{}) {
+			continue
+		}
+
+		value := v.Field(i)
+
+		if field.Anonymous && value.Kind() == reflect.Struct {
+			addStructFields(fields, value)
+			continue
+		}
+
+		// Type bitmaps in NSEC, NSEC3 and CSYNC list record types
+		if bitmap, ok := value.Interface().([]uint16); ok && field.Name == "TypeBitMap" {
+			fields[fieldName(field.Name)] = typeNames(bitmap)
+			continue
+		}
+
+		fields[fieldName(field.Name)] = fieldValue(value)
+	}
+}
+
+// fieldValue converts a record data field into a JSON-serializable value.
+func fieldValue(v reflect.Value) any {
+	switch value := v.Interface().(type) {
+	case net.IP:
+		return value.String()
+	case net.IPNet:
+		return value.String()
+	case []dns.SVCBKeyValue:
+		params := make([]map[string]string, 0, len(value))
+		for _, kv := range value {
+			params = append(params, map[string]string{
+				"key":   kv.Key().String(),
+				"value": kv.String(),
+			})
+		}
+		return params
+	case []dns.EDNS0:
+		options := make([]map[string]any, 0, len(value))
+		for _, o := range value {
+			options = append(options, formatEDNSOption(o))
+		}
+		return options
+	}
+
+	switch v.Kind() {
+	case reflect.Slice:
+		// Byte slices are serialized as base64 by encoding/json already
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface()
+		}
+
+		items := make([]any, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			items = append(items, fieldValue(v.Index(i)))
+		}
+		return items
+
+	case reflect.Struct:
+		nested := map[string]any{}
+		for i := 0; i < v.NumField(); i++ {
+			if field := v.Type().Field(i); field.IsExported() {
+				nested[fieldName(field.Name)] = fieldValue(v.Field(i))
+			}
+		}
+		return nested
+
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return fieldValue(v.Elem())
+	}
+
+	return v.Interface()
+}
+
+// fieldName converts a Go field name into a camel case JSON key, so "Mx"
+// becomes "mx", "TypeCovered" becomes "typeCovered" and "AAAA" becomes "aaaa".
+func fieldName(name string) string {
+	runes := []rune(name)
+
+	upper := 0
+	for upper < len(runes) && unicode.IsUpper(runes[upper]) {
+		upper++
+	}
+
+	// Keep the last capital of a leading acronym when it starts a new word
+	if upper > 1 && upper < len(runes) {
+		upper--
+	}
+
+	for i := 0; i < upper; i++ {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+
+	return string(runes)
+}
+
+// typeNames converts record type numbers into their names, using the
+// RFC 3597 generic form for unknown types.
+func typeNames(types []uint16) []string {
+	names := make([]string, 0, len(types))
+	for _, t := range types {
+		names = append(names, dns.Type(t).String())
+	}
+	return names
+}
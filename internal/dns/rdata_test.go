@@ -0,0 +1,97 @@
+package dns
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFieldName(t *testing.T) {
+	tests := map[string]string{
+		"Mx":          "mx",
+		"TypeCovered": "typeCovered",
+		"AAAA":        "aaaa",
+		"A":           "a",
+		"PublicKey":   "publicKey",
+		"SignerName":  "signerName",
+	}
+
+	for name, want := range tests {
+		assert.Equal(t, want, fieldName(name), name)
+	}
+}
+
+func TestFormatResourceRecords(t *testing.T) {
+	records := mustRRs(t,
+		"example.com. 300 IN DNSKEY 257 3 13 mdsswUyr3DPW132mOi8V9xESWE8jTo0dxCjjnopKl+GqJxpVXckHAeF+KkxLbxILfDLUT0rAK9iUzy1L53eKGQ==",
+		"_443._tcp.example.com. 300 IN TLSA 3 1 1 0C72AC70B745AC19998811B131D662C9AC69DBDBE7CB23E5B514B56664C5D3D6",
+		"example.com. 300 IN NAPTR 100 10 \"S\" \"SIP+D2U\" \"\" _sip._udp.example.com.",
+		"example.com. 300 IN HTTPS 1 . alpn=h2,h3 ipv4hint=192.0.2.1",
+		"example.com. 300 IN NSEC next.example.com. A MX RRSIG NSEC",
+		"example.com. 300 IN AAAA 2001:db8::1",
+	)
+
+	formatted := formatResourceRecords(records)
+	require.Len(t, formatted, len(records))
+
+	// The presentation data must keep every field, not just the first one
+	assert.Equal(t, "257 3 13 mdsswUyr3DPW132mOi8V9xESWE8jTo0dxCjjnopKl+GqJxpVXckHAeF+KkxLbxILfDLUT0rAK9iUzy1L53eKGQ==", formatted[0]["data"])
+	assert.Equal(t, "IN", formatted[0]["class"])
+
+	dnskey := formatted[0]["rdata"].(map[string]any)
+	assert.Equal(t, uint16(257), dnskey["flags"])
+	assert.Equal(t, uint8(13), dnskey["algorithm"])
+
+	tlsa := formatted[1]["rdata"].(map[string]any)
+	assert.Equal(t, uint8(3), tlsa["usage"])
+	assert.Equal(t, uint8(1), tlsa["matchingType"])
+	assert.Equal(t, "0C72AC70B745AC19998811B131D662C9AC69DBDBE7CB23E5B514B56664C5D3D6", tlsa["certificate"])
+
+	naptr := formatted[2]["rdata"].(map[string]any)
+	assert.Equal(t, "SIP+D2U", naptr["service"])
+	assert.Equal(t, "_sip._udp.example.com.", naptr["replacement"])
+
+	https := formatted[3]["rdata"].(map[string]any)
+	assert.Equal(t, ".", https["target"])
+	assert.Equal(t, []map[string]string{
+		{"key": "alpn", "value": "h2,h3"},
+		{"key": "ipv4hint", "value": "192.0.2.1"},
+	}, https["value"])
+
+	nsec := formatted[4]["rdata"].(map[string]any)
+	assert.Equal(t, []string{"A", "MX", "RRSIG", "NSEC"}, nsec["typeBitMap"])
+
+	assert.Equal(t, "2001:db8::1", formatted[5]["rdata"].(map[string]any)["aaaa"])
+}
+
+func TestCreateDNSResponseSections(t *testing.T) {
+	m := new(dns.Msg)
+	m.SetQuestion("www.example.com.", dns.TypeA)
+
+	response := new(dns.Msg)
+	response.SetReply(m)
+	response.Authoritative = true
+	response.Ns = mustRRs(t, "example.com. 300 IN NS ns1.example.com.")
+	response.Extra = mustRRs(t, "ns1.example.com. 300 IN A 192.0.2.53")
+	response.SetEdns0(1232, false)
+
+	result := createDNSResponse(response)
+	assert.Equal(t, true, result["authoritative"])
+	assert.Equal(t, "QUERY", result["opcode"])
+	assert.Equal(t, response.Id, result["id"])
+	assert.Len(t, result["authority"], 1)
+
+	// The OPT pseudo-record is decoded separately from the additional section
+	assert.Len(t, result["additional"], 1)
+	assert.Contains(t, result, "edns")
+
+	wire, err := response.Pack()
+	require.NoError(t, err)
+
+	addMessageFormats(result, response, wire, outputParams{IncludeText: true, IncludeWire: true})
+	assert.Contains(t, result["text"], ";; AUTHORITY SECTION:")
+	assert.Equal(t, base64.StdEncoding.EncodeToString(wire), result["wire"])
+}
@@ -0,0 +1,388 @@
+package dns
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/domainname"
+	resp "github.com/patrickdappollonio/mcp-domaintools/internal/response"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/utils"
+)
+
+// Files read to inspect the system resolver configuration.
+const (
+	resolvConfPath = "/etc/resolv.conf"
+	hostsPath      = "/etc/hosts"
+	nsswitchPath   = "/etc/nsswitch.conf"
+)
+
+// Defaults and limits of the system stub resolver, as documented in resolv.conf(5).
+const (
+	defaultNdots       = 1
+	defaultTimeout     = 5
+	defaultAttempts    = 2
+	maxStubNameservers = 3
+)
+
+// maxHostsEntries limits how many matching hosts file entries are reported.
+const maxHostsEntries = 50
+
+// resolverConfigParams represents the parameters for resolver configuration inspection.
+type resolverConfigParams struct {
+	Name string `json:"name"`
+}
+
+// ResolvConf represents the contents of resolv.conf.
+type ResolvConf struct {
+	Path        string   `json:"path"`
+	Nameservers []string `json:"nameservers"`
+	Search      []string `json:"search"`
+	Ndots       int      `json:"ndots"`
+	Timeout     int      `json:"timeout_seconds"`
+	Attempts    int      `json:"attempts"`
+	Rotate      bool     `json:"rotate"`
+	Options     []string `json:"options,omitempty"`
+}
+
+// HostsEntry represents a line of the hosts file.
+type HostsEntry struct {
+	Line    int      `json:"line"`
+	Address string   `json:"address"`
+	Names   []string `json:"names"`
+}
+
+// ResolverConfigResponse represents the complete resolver configuration response.
+type ResolverConfigResponse struct {
+	Name            string           `json:"name,omitempty"`
+	DomainName      *domainname.Name `json:"domain_name,omitempty"`
+	ResolvConf      *ResolvConf      `json:"resolv_conf,omitempty"`
+	HostsOrder      []string         `json:"hosts_order,omitempty"`
+	HostsEntries    []HostsEntry     `json:"hosts_entries,omitempty"`
+	QueryNames      []string         `json:"query_names,omitempty"`
+	SelectedServers []string         `json:"selected_servers"`
+	SelectionSource string           `json:"selection_source,omitempty"`
+	SelectionReason string           `json:"selection_reason"`
+	SkippedServers  []string         `json:"skipped_servers,omitempty"`
+	Errors          []string         `json:"errors,omitempty"`
+	Issues          []string         `json:"issues,omitempty"`
+	Timestamp       string           `json:"timestamp"`
+}
+
+// HandleResolverConfig reports the name resolution configuration of the
+// host: resolv.conf, the hosts file, the nsswitch order and the DNS servers
+// the other tools pick when querying the system resolvers.
+func HandleResolverConfig(ctx context.Context, request mcp.CallToolRequest, config *QueryConfig) (*mcp.CallToolResult, error) {
+	var params resolverConfigParams
+	if err := request.BindArguments(&params); err != nil {
+		return nil, fmt.Errorf("failed to parse tool input: %w", utils.ParseJSONUnmarshalError(err))
+	}
+
+	// Validate and normalize the name, keeping its trailing dot, which makes
+	// the resolver skip the search domains
+	var domainName *domainname.Name
+	name := strings.TrimSpace(params.Name)
+	if name != "" {
+		parsed, err := domainname.Parse(name)
+		if err != nil {
+			return nil, err
+		}
+
+		fqdn := strings.HasSuffix(name, ".")
+		domainName, name = parsed, parsed.ASCII
+		if fqdn {
+			name += "."
+		}
+	}
+
+	result := &ResolverConfigResponse{
+		Name:            name,
+		DomainName:      domainName,
+		SelectedServers: make([]string, 0),
+		Timestamp:       time.Now().Format(time.RFC3339),
+	}
+
+	if conf, err := readResolvConf(resolvConfPath); err != nil {
+		result.Errors = append(result.Errors, err.Error())
+	} else {
+		result.ResolvConf = conf
+	}
+
+	if order, err := readHostsOrder(nsswitchPath); err != nil {
+		result.Errors = append(result.Errors, err.Error())
+	} else {
+		result.HostsOrder = order
+	}
+
+	if name != "" {
+		if entries, err := readHostsEntries(hostsPath, name); err != nil {
+			result.Errors = append(result.Errors, err.Error())
+		} else {
+			result.HostsEntries = entries
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, config.Timeout)
+	defer cancel()
+
+	discovery, err := discoverSystemDNSServers(ctx)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+	}
+
+	explainServerSelection(result, discovery)
+	inspectResolverConfig(result)
+	return resp.JSON(result)
+}
+
+// readResolvConf reads and parses a resolv.conf file.
+func readResolvConf(path string) (*ResolvConf, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer f.Close()
+
+	conf, err := parseResolvConf(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	conf.Path = path
+	return conf, nil
+}
+
+// parseResolvConf parses resolv.conf contents. As in the system resolver,
+// the last "domain" or "search" line wins and later options override
+// earlier ones.
+func parseResolvConf(r io.Reader) (*ResolvConf, error) {
+	conf := &ResolvConf{
+		Nameservers: make([]string, 0),
+		Search:      make([]string, 0),
+		Ndots:       defaultNdots,
+		Timeout:     defaultTimeout,
+		Attempts:    defaultAttempts,
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") || strings.HasPrefix(fields[0], ";") {
+			continue
+		}
+
+		switch fields[0] {
+		case "nameserver":
+			if len(fields) > 1 {
+				conf.Nameservers = append(conf.Nameservers, fields[1])
+			}
+		case "domain":
+			if len(fields) > 1 {
+				conf.Search = []string{fields[1]}
+			}
+		case "search":
+			conf.Search = slices.Clone(fields[1:])
+		case "options":
+			for _, option := range fields[1:] {
+				conf.Options = append(conf.Options, option)
+
+				key, value, _ := strings.Cut(option, ":")
+				n, _ := strconv.Atoi(value)
+				switch key {
+				case "ndots":
+					conf.Ndots = min(max(n, 0), 15)
+				case "timeout":
+					conf.Timeout = max(n, 1)
+				case "attempts":
+					conf.Attempts = max(n, 1)
+				case "rotate":
+					conf.Rotate = true
+				}
+			}
+		}
+	}
+
+	return conf, scanner.Err()
+}
+
+// readHostsOrder reads the sources of the "hosts" database from nsswitch.conf.
+func readHostsOrder(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer f.Close()
+
+	order, err := parseHostsOrder(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	return order, nil
+}
+
+// parseHostsOrder returns the sources and actions listed for the "hosts"
+// database in nsswitch.conf, such as "files", "dns" or "[NOTFOUND=return]".
+func parseHostsOrder(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		database, sources, ok := strings.Cut(line, ":")
+		if ok && strings.TrimSpace(database) == "hosts" {
+			return strings.Fields(sources), nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, fmt.Errorf("no hosts database configured")
+}
+
+// readHostsEntries reads the entries of a hosts file that match a name.
+func readHostsEntries(path, name string) ([]HostsEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer f.Close()
+
+	entries, err := parseHostsEntries(f, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	return entries, nil
+}
+
+// parseHostsEntries returns the hosts file entries that list the given name,
+// ignoring case and a trailing dot.
+func parseHostsEntries(r io.Reader, name string) ([]HostsEntry, error) {
+	name = strings.TrimSuffix(name, ".")
+	entries := make([]HostsEntry, 0)
+
+	scanner := bufio.NewScanner(r)
+	for number := 1; scanner.Scan(); number++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		matches := slices.ContainsFunc(fields[1:], func(host string) bool {
+			return strings.EqualFold(strings.TrimSuffix(host, "."), name)
+		})
+		if matches && len(entries) < maxHostsEntries {
+			entries = append(entries, HostsEntry{Line: number, Address: fields[0], Names: fields[1:]})
+		}
+	}
+
+	return entries, scanner.Err()
+}
+
+// searchNames returns the names the stub resolver queries for a name, in
+// order: names with at least ndots dots are tried as-is first, and the
+// others after the search domains. A trailing dot disables the search list.
+func searchNames(name string, search []string, ndots int) []string {
+	if strings.HasSuffix(name, ".") {
+		return []string{name}
+	}
+
+	names := make([]string, 0, len(search)+1)
+	for _, domain := range search {
+		names = append(names, name+"."+strings.TrimSuffix(domain, ".")+".")
+	}
+
+	if strings.Count(name, ".") >= ndots {
+		return append([]string{name + "."}, names...)
+	}
+
+	return append(names, name+".")
+}
+
+// explainServerSelection reports the servers picked by the other tools when
+// they query the system resolvers, and why those were picked.
+func explainServerSelection(result *ResolverConfigResponse, discovery *systemDNSDiscovery) {
+	if discovery == nil {
+		result.SelectionReason = "no DNS servers could be discovered: the Go resolver dialed no servers and " + resolvConfPath + " lists none, so tools using the system resolvers will fail"
+		return
+	}
+
+	result.SelectedServers = discovery.Servers
+	result.SelectionSource = discovery.Source
+	result.SkippedServers = discovery.Skipped
+
+	switch discovery.Source {
+	case systemDNSSourceGoResolver:
+		result.SelectionReason = "servers dialed by Go's built-in resolver during a test lookup, which reads " + resolvConfPath + " on Unix systems and the network adapter settings on Windows"
+	case systemDNSSourceResolvConf:
+		result.SelectionReason = "the Go resolver dialed no usable servers, so the nameservers in " + resolvConfPath + " are used as a fallback"
+	}
+
+	if len(discovery.Skipped) > 0 {
+		result.SelectionReason += fmt.Sprintf("; the loopback servers %s were skipped", strings.Join(discovery.Skipped, ", "))
+	}
+}
+
+// inspectResolverConfig flags the configuration problems that commonly look
+// like DNS failures.
+func inspectResolverConfig(result *ResolverConfigResponse) {
+	conf := result.ResolvConf
+
+	if conf != nil {
+		if len(conf.Nameservers) == 0 {
+			result.Issues = append(result.Issues, fmt.Sprintf("%s lists no nameservers, so the system resolver falls back to the local host", conf.Path))
+		}
+
+		if len(conf.Nameservers) > maxStubNameservers {
+			result.Issues = append(result.Issues, fmt.Sprintf("%s lists %d nameservers, but the system resolver only uses the first %d and ignores %s", conf.Path, len(conf.Nameservers), maxStubNameservers, strings.Join(conf.Nameservers[maxStubNameservers:], ", ")))
+		}
+
+		for _, server := range conf.Nameservers {
+			if ip := net.ParseIP(server); ip != nil && ip.IsLoopback() {
+				result.Issues = append(result.Issues, fmt.Sprintf("nameserver %s is a local stub resolver (such as systemd-resolved or dnsmasq); the upstream servers it forwards to are configured elsewhere", server))
+			}
+		}
+
+		if conf.Ndots > defaultNdots {
+			result.Issues = append(result.Issues, fmt.Sprintf("ndots is %d, so names with fewer dots are first tried with every search domain, which slows down lookups and can return unexpected answers", conf.Ndots))
+		}
+	}
+
+	if len(result.HostsOrder) > 0 && !slices.Contains(result.HostsOrder, "dns") && !slices.Contains(result.HostsOrder, "resolve") {
+		result.Issues = append(result.Issues, fmt.Sprintf("the hosts database in %s doesn't use DNS (%s)", nsswitchPath, strings.Join(result.HostsOrder, " ")))
+	}
+
+	if result.Name == "" {
+		return
+	}
+
+	if conf != nil {
+		result.QueryNames = searchNames(result.Name, conf.Search, conf.Ndots)
+		if len(result.QueryNames) > 1 && result.QueryNames[0] != result.Name+"." {
+			result.Issues = append(result.Issues, fmt.Sprintf("%s has fewer than %d dot(s), so it's tried as %s before the name itself; add a trailing dot to skip the search domains", result.Name, conf.Ndots, result.QueryNames[0]))
+		}
+	}
+
+	if len(result.HostsEntries) > 0 {
+		addresses := make([]string, 0, len(result.HostsEntries))
+		for _, entry := range result.HostsEntries {
+			addresses = append(addresses, entry.Address)
+		}
+
+		issue := fmt.Sprintf("%s is listed in %s with %s", result.Name, hostsPath, strings.Join(addresses, ", "))
+		if files, network := slices.Index(result.HostsOrder, "files"), slices.Index(result.HostsOrder, "dns"); files >= 0 && (network < 0 || files < network) {
+			issue += ", which takes precedence over DNS for applications using the system resolver"
+		}
+		result.Issues = append(result.Issues, issue)
+	}
+}
@@ -0,0 +1,118 @@
+package dns
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseResolvConf(t *testing.T) {
+	conf, err := parseResolvConf(strings.NewReader(`# Generated by NetworkManager
+domain old.example
+search corp.example.com example.com
+nameserver 192.0.2.53
+; a comment
+nameserver 2001:db8::53
+options ndots:5 timeout:2 attempts:3 rotate edns0
+`))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"192.0.2.53", "2001:db8::53"}, conf.Nameservers)
+	assert.Equal(t, []string{"corp.example.com", "example.com"}, conf.Search)
+	assert.Equal(t, 5, conf.Ndots)
+	assert.Equal(t, 2, conf.Timeout)
+	assert.Equal(t, 3, conf.Attempts)
+	assert.True(t, conf.Rotate)
+	assert.Equal(t, []string{"ndots:5", "timeout:2", "attempts:3", "rotate", "edns0"}, conf.Options)
+
+	t.Run("defaults", func(t *testing.T) {
+		conf, err := parseResolvConf(strings.NewReader("nameserver 127.0.0.53\n"))
+		require.NoError(t, err)
+		assert.Equal(t, defaultNdots, conf.Ndots)
+		assert.Equal(t, defaultTimeout, conf.Timeout)
+		assert.Equal(t, defaultAttempts, conf.Attempts)
+		assert.Empty(t, conf.Search)
+	})
+}
+
+func TestParseHostsOrder(t *testing.T) {
+	order, err := parseHostsOrder(strings.NewReader("passwd: files systemd\nhosts:          files mdns4_minimal [NOTFOUND=return] dns # comment\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"files", "mdns4_minimal", "[NOTFOUND=return]", "dns"}, order)
+
+	_, err = parseHostsOrder(strings.NewReader("passwd: files\n"))
+	assert.Error(t, err)
+}
+
+func TestParseHostsEntries(t *testing.T) {
+	entries, err := parseHostsEntries(strings.NewReader(`127.0.0.1 localhost
+# 192.0.2.1 api.example.com
+192.0.2.2 API.example.com. api
+2001:db8::2 api.example.com # staging
+`), "api.example.com")
+	require.NoError(t, err)
+
+	require.Len(t, entries, 2)
+	assert.Equal(t, HostsEntry{Line: 3, Address: "192.0.2.2", Names: []string{"API.example.com.", "api"}}, entries[0])
+	assert.Equal(t, 4, entries[1].Line)
+	assert.Equal(t, "2001:db8::2", entries[1].Address)
+}
+
+func TestSearchNames(t *testing.T) {
+	search := []string{"corp.example.com", "example.com."}
+
+	assert.Equal(t, []string{"db.corp.example.com.", "db.example.com.", "db."}, searchNames("db", search, 1))
+	assert.Equal(t, []string{"api.example.org.", "api.example.org.corp.example.com.", "api.example.org.example.com."}, searchNames("api.example.org", search, 1))
+	assert.Equal(t, []string{"api.example.org.corp.example.com.", "api.example.org.example.com.", "api.example.org."}, searchNames("api.example.org", search, 5))
+	assert.Equal(t, []string{"api.example.org."}, searchNames("api.example.org.", search, 5))
+}
+
+func TestInspectResolverConfig(t *testing.T) {
+	result := &ResolverConfigResponse{
+		Name: "api.example.org",
+		ResolvConf: &ResolvConf{
+			Path:        resolvConfPath,
+			Nameservers: []string{"127.0.0.53", "192.0.2.1", "192.0.2.2", "192.0.2.3"},
+			Search:      []string{"corp.example.com"},
+			Ndots:       5,
+		},
+		HostsOrder:   []string{"files", "dns"},
+		HostsEntries: []HostsEntry{{Line: 3, Address: "192.0.2.9", Names: []string{"api.example.org"}}},
+	}
+
+	inspectResolverConfig(result)
+
+	assert.Equal(t, []string{"api.example.org.corp.example.com.", "api.example.org."}, result.QueryNames)
+	assert.Equal(t, []string{
+		"/etc/resolv.conf lists 4 nameservers, but the system resolver only uses the first 3 and ignores 192.0.2.3",
+		"nameserver 127.0.0.53 is a local stub resolver (such as systemd-resolved or dnsmasq); the upstream servers it forwards to are configured elsewhere",
+		"ndots is 5, so names with fewer dots are first tried with every search domain, which slows down lookups and can return unexpected answers",
+		"api.example.org has fewer than 5 dot(s), so it's tried as api.example.org.corp.example.com. before the name itself; add a trailing dot to skip the search domains",
+		"api.example.org is listed in /etc/hosts with 192.0.2.9, which takes precedence over DNS for applications using the system resolver",
+	}, result.Issues)
+}
+
+func TestExplainServerSelection(t *testing.T) {
+	t.Run("go resolver", func(t *testing.T) {
+		result := &ResolverConfigResponse{}
+		explainServerSelection(result, &systemDNSDiscovery{
+			Servers: []string{"192.0.2.53"},
+			Source:  systemDNSSourceGoResolver,
+			Skipped: []string{"127.0.0.1"},
+		})
+
+		assert.Equal(t, []string{"192.0.2.53"}, result.SelectedServers)
+		assert.Equal(t, systemDNSSourceGoResolver, result.SelectionSource)
+		assert.Contains(t, result.SelectionReason, "Go's built-in resolver")
+		assert.Contains(t, result.SelectionReason, "the loopback servers 127.0.0.1 were skipped")
+	})
+
+	t.Run("nothing found", func(t *testing.T) {
+		result := &ResolverConfigResponse{SelectedServers: []string{}}
+		explainServerSelection(result, nil)
+		assert.Empty(t, result.SelectedServers)
+		assert.Contains(t, result.SelectionReason, "no DNS servers could be discovered")
+	})
+}
@@ -0,0 +1,186 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/miekg/dns"
+	resp "github.com/patrickdappollonio/mcp-domaintools/internal/response"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/utils"
+	"golang.org/x/sync/errgroup"
+)
+
+// maxReverseAddresses limits how many addresses a CIDR range may expand to.
+const maxReverseAddresses = 256
+
+// reverseConcurrency limits how many PTR queries are in flight at once.
+const reverseConcurrency = 16
+
+// reverseLookupParams represents the parameters for reverse DNS lookups.
+type reverseLookupParams struct {
+	Target string `json:"target"`
+}
+
+// ReverseLookupResult represents the PTR lookup for a single address.
+type ReverseLookupResult struct {
+	IP        string   `json:"ip"`
+	ArpaName  string   `json:"arpa_name"`
+	Hostnames []string `json:"hostnames"`
+	TTL       uint32   `json:"ttl,omitempty"`
+	Rcode     string   `json:"rcode,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// ReverseLookupResponse represents the complete reverse DNS lookup response.
+type ReverseLookupResponse struct {
+	Target        string                `json:"target"`
+	AddressCount  int                   `json:"address_count"`
+	ResolvedCount int                   `json:"resolved_count"`
+	Results       []ReverseLookupResult `json:"results"`
+	Timestamp     string                `json:"timestamp"`
+}
+
+// HandleReverseLookup resolves the PTR records for an IP address or every
+// address in a small CIDR range.
+func HandleReverseLookup(ctx context.Context, request mcp.CallToolRequest, config *QueryConfig) (*mcp.CallToolResult, error) {
+	var params reverseLookupParams
+	if err := request.BindArguments(&params); err != nil {
+		return nil, fmt.Errorf("failed to parse tool input: %w", utils.ParseJSONUnmarshalError(err))
+	}
+
+	// Validate required parameters
+	target := strings.TrimSpace(params.Target)
+	if target == "" {
+		return nil, fmt.Errorf("parameter \"target\" is required")
+	}
+
+	addresses, err := expandReverseTarget(target)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get DNS servers once, rather than once per address
+	servers, err := getSystemDNSServers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get DNS servers: %w", err)
+	}
+
+	results := make([]ReverseLookupResult, len(addresses))
+
+	var eg errgroup.Group
+	eg.SetLimit(reverseConcurrency)
+	for i, address := range addresses {
+		eg.Go(func() error {
+			results[i] = lookupPTR(ctx, address, servers, config)
+			return nil
+		})
+	}
+	_ = eg.Wait()
+
+	response := &ReverseLookupResponse{
+		Target:       target,
+		AddressCount: len(addresses),
+		Results:      results,
+		Timestamp:    time.Now().Format(time.RFC3339),
+	}
+
+	for _, result := range results {
+		if len(result.Hostnames) > 0 {
+			response.ResolvedCount++
+		}
+	}
+
+	return resp.JSON(response)
+}
+
+// expandReverseTarget parses an IP address or CIDR range and returns every
+// address it contains, up to maxReverseAddresses.
+func expandReverseTarget(target string) ([]netip.Addr, error) {
+	if !strings.Contains(target, "/") {
+		addr, err := netip.ParseAddr(target)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP address %q: %w", target, err)
+		}
+		return []netip.Addr{addr.Unmap()}, nil
+	}
+
+	prefix, err := netip.ParsePrefix(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR range %q: %w", target, err)
+	}
+	prefix = prefix.Masked()
+
+	hostBits := prefix.Addr().BitLen() - prefix.Bits()
+	if hostBits > 8 {
+		return nil, fmt.Errorf("CIDR range %q is too large: at most %d addresses can be looked up at once (/%d for IPv4, /%d for IPv6)",
+			target, maxReverseAddresses, 32-8, 128-8)
+	}
+
+	addresses := make([]netip.Addr, 0, 1<<hostBits)
+	for addr := prefix.Addr(); addr.IsValid() && prefix.Contains(addr); addr = addr.Next() {
+		addresses = append(addresses, addr)
+	}
+
+	return addresses, nil
+}
+
+// reverseName builds the in-addr.arpa or ip6.arpa name for an address.
+func reverseName(addr netip.Addr) string {
+	var b strings.Builder
+
+	if addr.Is4() {
+		octets := addr.As4()
+		for i := len(octets) - 1; i >= 0; i-- {
+			fmt.Fprintf(&b, "%d.", octets[i])
+		}
+		b.WriteString("in-addr.arpa.")
+		return b.String()
+	}
+
+	// IPv6 addresses are reversed one nibble at a time
+	const hexDigits = "0123456789abcdef"
+	bytes := addr.As16()
+	for i := len(bytes) - 1; i >= 0; i-- {
+		b.WriteByte(hexDigits[bytes[i]&0x0f])
+		b.WriteByte('.')
+		b.WriteByte(hexDigits[bytes[i]>>4])
+		b.WriteByte('.')
+	}
+	b.WriteString("ip6.arpa.")
+	return b.String()
+}
+
+// lookupPTR queries the given DNS servers for the PTR records of an address.
+func lookupPTR(ctx context.Context, addr netip.Addr, servers []string, config *QueryConfig) ReverseLookupResult {
+	result := ReverseLookupResult{
+		IP:        addr.String(),
+		ArpaName:  reverseName(addr),
+		Hostnames: make([]string, 0),
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion(result.ArpaName, dns.TypePTR)
+	m.RecursionDesired = true
+
+	response, err := exchangeWithServers(ctx, m, servers, config)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Rcode = dns.RcodeToString[response.Rcode]
+	for _, rr := range response.Answer {
+		if ptr, ok := rr.(*dns.PTR); ok {
+			result.Hostnames = append(result.Hostnames, ptr.Ptr)
+			if result.TTL == 0 || ptr.Header().Ttl < result.TTL {
+				result.TTL = ptr.Header().Ttl
+			}
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,70 @@
+package dns
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReverseName(t *testing.T) {
+	tests := []struct {
+		address  string
+		expected string
+	}{
+		{"192.0.2.1", "1.2.0.192.in-addr.arpa."},
+		{"2001:db8::567:89ab", "b.a.9.8.7.6.5.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa."},
+		{"::ffff:192.0.2.1", "1.2.0.192.in-addr.arpa."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			addresses, err := expandReverseTarget(tt.address)
+			require.NoError(t, err)
+			require.Len(t, addresses, 1)
+
+			name := reverseName(addresses[0])
+			assert.Equal(t, tt.expected, name)
+
+			// Cross-check against the miekg/dns implementation
+			expected, err := dns.ReverseAddr(addresses[0].String())
+			require.NoError(t, err)
+			assert.Equal(t, expected, name)
+		})
+	}
+}
+
+func TestExpandReverseTarget(t *testing.T) {
+	t.Run("ipv4 range", func(t *testing.T) {
+		addresses, err := expandReverseTarget("192.0.2.9/30")
+		require.NoError(t, err)
+
+		expected := []netip.Addr{
+			netip.MustParseAddr("192.0.2.8"),
+			netip.MustParseAddr("192.0.2.9"),
+			netip.MustParseAddr("192.0.2.10"),
+			netip.MustParseAddr("192.0.2.11"),
+		}
+		assert.Equal(t, expected, addresses)
+	})
+
+	t.Run("largest ipv6 range", func(t *testing.T) {
+		addresses, err := expandReverseTarget("2001:db8::/120")
+		require.NoError(t, err)
+		assert.Len(t, addresses, maxReverseAddresses)
+	})
+
+	t.Run("range too large", func(t *testing.T) {
+		_, err := expandReverseTarget("10.0.0.0/16")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "too large")
+	})
+
+	t.Run("invalid address", func(t *testing.T) {
+		_, err := expandReverseTarget("example.com")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid IP address")
+	})
+}
@@ -0,0 +1,252 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/miekg/dns"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/domainname"
+	resp "github.com/patrickdappollonio/mcp-domaintools/internal/response"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/utils"
+	"golang.org/x/sync/errgroup"
+)
+
+// snapshotConcurrency limits how many snapshot queries are sent at once.
+const snapshotConcurrency = 8
+
+// snapshotApexTypes are the record types queried at the domain itself.
+var snapshotApexTypes = []string{"A", "AAAA", "CNAME", "MX", "NS", "SOA", "TXT", "CAA", "SRV", "HTTPS", "SVCB", "DS", "DNSKEY"}
+
+// snapshotSubdomainTypes are the record types queried at each subdomain.
+var snapshotSubdomainTypes = []string{"A", "AAAA", "CNAME", "MX", "TXT", "HTTPS"}
+
+// defaultSnapshotSubdomains are the well-known subdomains included in a
+// snapshot when none are given.
+var defaultSnapshotSubdomains = []string{"www", "mail", "_dmarc", "_mta-sts", "mta-sts", "_smtp._tls"}
+
+// dnsSnapshotParams represents the parameters for DNS snapshots.
+type dnsSnapshotParams struct {
+	Domain     string   `json:"domain"`
+	Subdomains []string `json:"subdomains"`
+	ZoneFile   bool     `json:"zone_file"`
+}
+
+// SnapshotName holds every record found at one name of a snapshot.
+type SnapshotName struct {
+	Name    string           `json:"name"`
+	Exists  bool             `json:"exists"`
+	Types   []string         `json:"types,omitempty"`
+	Records []map[string]any `json:"records,omitempty"`
+	Errors  []string         `json:"errors,omitempty"`
+}
+
+// DNSSnapshotResponse represents the complete DNS snapshot response.
+type DNSSnapshotResponse struct {
+	Domain      string           `json:"domain"`
+	DomainName  *domainname.Name `json:"domain_name,omitempty"`
+	Names       []SnapshotName   `json:"names"`
+	RecordCount int              `json:"record_count"`
+	QueryCount  int              `json:"query_count"`
+	ZoneFile    string           `json:"zone_file,omitempty"`
+	Timestamp   string           `json:"timestamp"`
+}
+
+// snapshotQuery is a single name and record type queried for a snapshot,
+// along with its outcome.
+type snapshotQuery struct {
+	name     string
+	qtype    uint16
+	response *dns.Msg
+	err      error
+}
+
+// HandleDNSSnapshot queries a domain and a list of well-known subdomains for
+// all common record types concurrently and merges the results into a report.
+func HandleDNSSnapshot(ctx context.Context, request mcp.CallToolRequest, config *QueryConfig) (*mcp.CallToolResult, error) {
+	var params dnsSnapshotParams
+	if err := request.BindArguments(&params); err != nil {
+		return nil, fmt.Errorf("failed to parse tool input: %w", utils.ParseJSONUnmarshalError(err))
+	}
+
+	// Validate required parameters
+	if params.Domain == "" {
+		return nil, fmt.Errorf("parameter \"domain\" is required")
+	}
+
+	// Validate and normalize the domain name
+	name, err := domainname.Parse(params.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	// An empty list skips the subdomains, while a missing one uses the defaults
+	requested := params.Subdomains
+	if requested == nil {
+		requested = defaultSnapshotSubdomains
+	}
+
+	subdomains := make([]string, 0, len(requested))
+	for _, subdomain := range requested {
+		// Subdomains are relative to the domain, so a leading dot is tolerated
+		parsed, err := domainname.Parse(strings.TrimPrefix(strings.TrimSpace(subdomain), "."))
+		if err != nil {
+			return nil, fmt.Errorf("invalid subdomain: %w", err)
+		}
+		subdomains = append(subdomains, parsed.ASCII)
+	}
+
+	// Get DNS servers once, rather than once per query
+	servers, err := getSystemDNSServers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get DNS servers: %w", err)
+	}
+
+	result, err := takeSnapshot(ctx, name.FQDN(), subdomains, servers, config.Timeout, params.ZoneFile)
+	if err != nil {
+		return nil, err
+	}
+
+	result.DomainName = name
+	return resp.JSON(result)
+}
+
+// takeSnapshot queries the domain and its subdomains on the given servers and
+// merges the answers by name, optionally rendering them as a zone file.
+func takeSnapshot(ctx context.Context, domain string, subdomains []string, servers []string, timeout time.Duration, zoneFile bool) (*DNSSnapshotResponse, error) {
+	names := []string{domain}
+	for _, subdomain := range subdomains {
+		name := dns.Fqdn(subdomain + "." + domain)
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+
+	var queries []*snapshotQuery
+	for _, name := range names {
+		types := snapshotSubdomainTypes
+		if name == domain {
+			types = snapshotApexTypes
+		}
+
+		for _, recordType := range types {
+			qtype, err := ConvertToQType(recordType)
+			if err != nil {
+				return nil, err
+			}
+			queries = append(queries, &snapshotQuery{name: name, qtype: qtype})
+		}
+	}
+
+	var eg errgroup.Group
+	eg.SetLimit(snapshotConcurrency)
+	for _, q := range queries {
+		eg.Go(func() error {
+			m := new(dns.Msg)
+			m.SetQuestion(q.name, q.qtype)
+			m.RecursionDesired = true
+			m.SetEdns0(4096, false)
+
+			answered, err := queryServers(ctx, m, servers, timeout)
+			if err != nil {
+				q.err = err
+				return nil
+			}
+
+			q.response = answered.Response
+			return nil
+		})
+	}
+	_ = eg.Wait()
+
+	result := &DNSSnapshotResponse{
+		Domain:     domain,
+		QueryCount: len(queries),
+		Timestamp:  time.Now().Format(time.RFC3339),
+	}
+
+	var zoneRecords []dns.RR
+	for _, name := range names {
+		entry, records := mergeSnapshotName(name, queries)
+		result.Names = append(result.Names, entry)
+		result.RecordCount += len(records)
+		zoneRecords = append(zoneRecords, records...)
+	}
+
+	if zoneFile {
+		result.ZoneFile = renderZoneFile(domain, zoneRecords, result.Timestamp)
+	}
+
+	return result, nil
+}
+
+// mergeSnapshotName merges the answers of every query sent for one name,
+// keeping only the records owned by that name and dropping duplicates, such
+// as a CNAME returned for several record types.
+func mergeSnapshotName(name string, queries []*snapshotQuery) (SnapshotName, []dns.RR) {
+	entry := SnapshotName{Name: name}
+
+	var records []dns.RR
+	seen := make(map[string]bool)
+
+	for _, q := range queries {
+		if q.name != name {
+			continue
+		}
+
+		if q.err != nil {
+			entry.Errors = append(entry.Errors, fmt.Sprintf("%s query failed: %s", dns.TypeToString[q.qtype], q.err))
+			continue
+		}
+
+		switch q.response.Rcode {
+		case dns.RcodeSuccess:
+			// A successful answer, even without data, means the name exists
+			entry.Exists = true
+		case dns.RcodeNameError:
+			continue
+		default:
+			entry.Errors = append(entry.Errors, fmt.Sprintf("%s query returned %s", dns.TypeToString[q.qtype], dns.RcodeToString[q.response.Rcode]))
+			continue
+		}
+
+		for _, rr := range q.response.Answer {
+			if !strings.EqualFold(rr.Header().Name, name) {
+				continue
+			}
+
+			key := dns.TypeToString[rr.Header().Rrtype] + " " + recordDataString(rr)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			records = append(records, rr)
+
+			typeName := dns.TypeToString[rr.Header().Rrtype]
+			if !slices.Contains(entry.Types, typeName) {
+				entry.Types = append(entry.Types, typeName)
+			}
+		}
+	}
+
+	entry.Records = formatResourceRecords(records)
+	return entry, records
+}
+
+// renderZoneFile renders the records as a zone file fragment.
+func renderZoneFile(domain string, records []dns.RR, timestamp string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "; DNS snapshot of %s taken at %s\n", domain, timestamp)
+	fmt.Fprintf(&sb, "$ORIGIN %s\n", domain)
+
+	for _, rr := range records {
+		sb.WriteString(rr.String())
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,108 @@
+package dns
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startZoneServer starts a UDP DNS server on localhost that answers queries
+// from the given records, following CNAMEs within them and returning
+// NXDOMAIN for names that own no records.
+func startZoneServer(t *testing.T, records []dns.RR) string {
+	t.Helper()
+
+	return startTestDNSServer(t, "udp", func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.RecursionAvailable = true
+
+		name, qtype := r.Question[0].Name, r.Question[0].Qtype
+		for range 8 {
+			var exists bool
+			var target string
+			for _, rr := range records {
+				if !strings.EqualFold(rr.Header().Name, name) {
+					continue
+				}
+				exists = true
+
+				switch rr.Header().Rrtype {
+				case qtype:
+					m.Answer = append(m.Answer, rr)
+				case dns.TypeCNAME:
+					m.Answer = append(m.Answer, rr)
+					target = rr.(*dns.CNAME).Target
+				}
+			}
+
+			if !exists {
+				m.Rcode = dns.RcodeNameError
+			}
+			if target == "" {
+				break
+			}
+			name = target
+		}
+
+		_ = w.WriteMsg(m)
+	})
+}
+
+func TestTakeSnapshot(t *testing.T) {
+	address := startZoneServer(t, mustRRs(t,
+		"example.com. 300 IN A 192.0.2.1",
+		"example.com. 300 IN MX 10 mail.example.com.",
+		`example.com. 300 IN TXT "v=spf1 -all"`,
+		"example.com. 300 IN NS ns1.example.net.",
+		"www.example.com. 300 IN CNAME cdn.example.net.",
+		"cdn.example.net. 60 IN A 198.51.100.7",
+		"mail.example.com. 300 IN A 192.0.2.25",
+		`_dmarc.example.com. 300 IN TXT "v=DMARC1; p=reject"`,
+	))
+
+	result, err := takeSnapshot(context.Background(), "example.com.", []string{"www", "mail", "_dmarc", "missing", "www"}, []string{address}, 2*time.Second, true)
+	require.NoError(t, err)
+
+	assert.Equal(t, len(snapshotApexTypes)+4*len(snapshotSubdomainTypes), result.QueryCount)
+	require.Len(t, result.Names, 5)
+
+	apex := result.Names[0]
+	assert.True(t, apex.Exists)
+	assert.Equal(t, []string{"A", "MX", "NS", "TXT"}, apex.Types)
+	assert.Empty(t, apex.Errors)
+
+	// The CNAME is returned for every type but listed once, without the
+	// records of its out-of-zone target
+	www := result.Names[1]
+	assert.Equal(t, "www.example.com.", www.Name)
+	assert.Equal(t, []string{"CNAME"}, www.Types)
+	require.Len(t, www.Records, 1)
+	assert.Equal(t, "cdn.example.net.", www.Records[0]["data"])
+
+	assert.Equal(t, []string{"A"}, result.Names[2].Types)
+	assert.Equal(t, []string{"TXT"}, result.Names[3].Types)
+
+	missing := result.Names[4]
+	assert.False(t, missing.Exists)
+	assert.Empty(t, missing.Records)
+
+	assert.Equal(t, 7, result.RecordCount)
+	assert.Contains(t, result.ZoneFile, "$ORIGIN example.com.\n")
+	assert.Contains(t, result.ZoneFile, "www.example.com.\t300\tIN\tCNAME\tcdn.example.net.\n")
+	assert.NotContains(t, result.ZoneFile, "198.51.100.7")
+
+	// The zone file fragment must parse back into the same records
+	parser := dns.NewZoneParser(strings.NewReader(result.ZoneFile), "", "")
+	var parsed int
+	for _, ok := parser.Next(); ok; _, ok = parser.Next() {
+		parsed++
+	}
+	require.NoError(t, parser.Err())
+	assert.Equal(t, result.RecordCount, parsed)
+}
@@ -0,0 +1,345 @@
+package dns
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"slices"
+
+	"github.com/miekg/dns"
+)
+
+// Modes of an SVCB record, given by its priority (RFC 9460 section 2.4).
+const (
+	svcbModeAlias   = "alias"
+	svcbModeService = "service"
+)
+
+// echConfigVersion is the version of the ECHConfig structure defined by the
+// Encrypted Client Hello specification. Configs with other versions are
+// skipped by clients.
+const echConfigVersion = 0xfe0d
+
+// hpkeKEMNames, hpkeKDFNames and hpkeAEADNames are the HPKE algorithm
+// identifiers used in ECH configs (RFC 9180 section 7).
+var (
+	hpkeKEMNames = map[uint16]string{
+		0x0010: "DHKEM(P-256, HKDF-SHA256)",
+		0x0011: "DHKEM(P-384, HKDF-SHA384)",
+		0x0012: "DHKEM(P-521, HKDF-SHA512)",
+		0x0020: "DHKEM(X25519, HKDF-SHA256)",
+		0x0021: "DHKEM(X448, HKDF-SHA512)",
+	}
+	hpkeKDFNames = map[uint16]string{
+		0x0001: "HKDF-SHA256",
+		0x0002: "HKDF-SHA384",
+		0x0003: "HKDF-SHA512",
+	}
+	hpkeAEADNames = map[uint16]string{
+		0x0001: "AES-128-GCM",
+		0x0002: "AES-256-GCM",
+		0x0003: "ChaCha20Poly1305",
+		0xffff: "Export-only",
+	}
+)
+
+// SVCBRecord represents the decoded data of an SVCB or HTTPS record.
+type SVCBRecord struct {
+	Priority      uint16         `json:"priority"`
+	Mode          string         `json:"mode"`
+	Target        string         `json:"target"`
+	Mandatory     []string       `json:"mandatory,omitempty"`
+	ALPN          []string       `json:"alpn,omitempty"`
+	NoDefaultALPN bool           `json:"no_default_alpn,omitempty"`
+	Port          uint16         `json:"port,omitempty"`
+	IPv4Hint      []string       `json:"ipv4hint,omitempty"`
+	IPv6Hint      []string       `json:"ipv6hint,omitempty"`
+	ECH           *ECHConfigList `json:"ech,omitempty"`
+	DoHPath       string         `json:"dohpath,omitempty"`
+	OHTTP         bool           `json:"ohttp,omitempty"`
+	OtherParams   []SVCBParam    `json:"other_params,omitempty"`
+	Issues        []string       `json:"issues,omitempty"`
+}
+
+// SVCBParam represents a service parameter without a dedicated field.
+type SVCBParam struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// ECHConfigList represents the decoded ECHConfigList of the "ech" parameter.
+type ECHConfigList struct {
+	Configs []ECHConfig `json:"configs"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// ECHConfig represents a single Encrypted Client Hello configuration. Only
+// the version is set for configs of an unknown version.
+type ECHConfig struct {
+	Version           uint16           `json:"version"`
+	Supported         bool             `json:"supported"`
+	ConfigID          uint8            `json:"config_id"`
+	KEMID             uint16           `json:"kem_id,omitempty"`
+	KEM               string           `json:"kem,omitempty"`
+	PublicKey         string           `json:"public_key,omitempty"`
+	CipherSuites      []ECHCipherSuite `json:"cipher_suites,omitempty"`
+	MaximumNameLength uint8            `json:"maximum_name_length,omitempty"`
+	PublicName        string           `json:"public_name,omitempty"`
+	Extensions        []uint16         `json:"extensions,omitempty"`
+}
+
+// ECHCipherSuite represents an HPKE KDF and AEAD pair offered by an ECH config.
+type ECHCipherSuite struct {
+	KDFID  uint16 `json:"kdf_id"`
+	KDF    string `json:"kdf"`
+	AEADID uint16 `json:"aead_id"`
+	AEAD   string `json:"aead"`
+}
+
+// svcbData returns the SVCB data of an SVCB or HTTPS record, which share
+// the same format.
+func svcbData(rr dns.RR) (*dns.SVCB, bool) {
+	switch record := rr.(type) {
+	case *dns.SVCB:
+		return record, true
+	case *dns.HTTPS:
+		return &record.SVCB, true
+	}
+	return nil, false
+}
+
+// decodeSVCB decodes the service parameters of an SVCB or HTTPS record and
+// flags the combinations clients reject or ignore.
+func decodeSVCB(record *dns.SVCB) SVCBRecord {
+	decoded := SVCBRecord{
+		Priority: record.Priority,
+		Mode:     svcbModeService,
+		Target:   record.Target,
+	}
+
+	if record.Priority == 0 {
+		decoded.Mode = svcbModeAlias
+		if len(record.Value) > 0 {
+			decoded.Issues = append(decoded.Issues, "AliasMode records (priority 0) must not have service parameters, so clients ignore them")
+		}
+	}
+
+	var mandatory []dns.SVCBKey
+	present := make([]dns.SVCBKey, 0, len(record.Value))
+
+	for _, kv := range record.Value {
+		present = append(present, kv.Key())
+
+		switch value := kv.(type) {
+		case *dns.SVCBMandatory:
+			mandatory = value.Code
+			for _, key := range value.Code {
+				decoded.Mandatory = append(decoded.Mandatory, key.String())
+			}
+		case *dns.SVCBAlpn:
+			decoded.ALPN = value.Alpn
+		case *dns.SVCBNoDefaultAlpn:
+			decoded.NoDefaultALPN = true
+		case *dns.SVCBPort:
+			decoded.Port = value.Port
+		case *dns.SVCBIPv4Hint:
+			for _, ip := range value.Hint {
+				decoded.IPv4Hint = append(decoded.IPv4Hint, ip.String())
+			}
+		case *dns.SVCBIPv6Hint:
+			for _, ip := range value.Hint {
+				decoded.IPv6Hint = append(decoded.IPv6Hint, ip.String())
+			}
+		case *dns.SVCBECHConfig:
+			decoded.ECH = decodeECHConfigList(value.ECH)
+			if decoded.ECH.Error != "" {
+				decoded.Issues = append(decoded.Issues, "ech: "+decoded.ECH.Error)
+			}
+		case *dns.SVCBDoHPath:
+			decoded.DoHPath = value.Template
+		case *dns.SVCBOhttp:
+			decoded.OHTTP = true
+		default:
+			decoded.OtherParams = append(decoded.OtherParams, SVCBParam{
+				Key:   kv.Key().String(),
+				Value: kv.String(),
+			})
+		}
+	}
+
+	for _, key := range mandatory {
+		if key == dns.SVCB_MANDATORY {
+			decoded.Issues = append(decoded.Issues, "mandatory must not list itself")
+		} else if !slices.Contains(present, key) {
+			decoded.Issues = append(decoded.Issues, fmt.Sprintf("mandatory lists %s, but the record doesn't have it", key))
+		}
+	}
+
+	if decoded.NoDefaultALPN && len(decoded.ALPN) == 0 {
+		decoded.Issues = append(decoded.Issues, "no-default-alpn is set without alpn, so the record advertises no protocol")
+	}
+
+	return decoded
+}
+
+// decodeECHConfigList decodes an ECHConfigList, keeping the configs decoded
+// before any error.
+func decodeECHConfigList(data []byte) *ECHConfigList {
+	list := &ECHConfigList{Configs: make([]ECHConfig, 0)}
+
+	configs, err := parseECHConfigList(data)
+	list.Configs = append(list.Configs, configs...)
+	if err != nil {
+		list.Error = err.Error()
+	}
+
+	return list
+}
+
+// parseECHConfigList parses the wire format of an ECHConfigList: a
+// length-prefixed list of versioned ECHConfig structures.
+func parseECHConfigList(data []byte) ([]ECHConfig, error) {
+	r := &echReader{data: data}
+	list := &echReader{data: r.vector16()}
+	if r.short || len(r.data) > 0 {
+		return nil, fmt.Errorf("malformed ECHConfigList: the length prefix doesn't match the data")
+	}
+
+	var configs []ECHConfig
+	for len(list.data) > 0 {
+		config := ECHConfig{Version: list.uint16()}
+		contents := list.vector16()
+		if list.short {
+			return configs, fmt.Errorf("malformed ECHConfig %d: truncated", len(configs)+1)
+		}
+
+		// Clients skip the configs of versions they don't know
+		if config.Version == echConfigVersion {
+			if err := parseECHConfigContents(&config, contents); err != nil {
+				return configs, fmt.Errorf("malformed ECHConfig %d: %w", len(configs)+1, err)
+			}
+		}
+
+		configs = append(configs, config)
+	}
+
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("the ECHConfigList is empty")
+	}
+
+	if !slices.ContainsFunc(configs, func(config ECHConfig) bool { return config.Supported }) {
+		return configs, fmt.Errorf("no ECHConfig has the supported version %#04x", echConfigVersion)
+	}
+
+	return configs, nil
+}
+
+// parseECHConfigContents parses the contents of an ECHConfig of the
+// supported version into the config.
+func parseECHConfigContents(config *ECHConfig, contents []byte) error {
+	r := &echReader{data: contents}
+
+	config.ConfigID = r.uint8()
+	config.KEMID = r.uint16()
+	publicKey := r.vector16()
+	suites := &echReader{data: r.vector16()}
+	config.MaximumNameLength = r.uint8()
+	publicName := r.vector8()
+	extensions := &echReader{data: r.vector16()}
+
+	if r.short {
+		return fmt.Errorf("truncated")
+	}
+	if len(r.data) > 0 {
+		return fmt.Errorf("%d bytes of trailing data", len(r.data))
+	}
+
+	config.Supported = true
+	config.KEM = hpkeName(hpkeKEMNames, config.KEMID)
+	config.PublicKey = base64.StdEncoding.EncodeToString(publicKey)
+	config.PublicName = string(publicName)
+
+	for len(suites.data) > 0 {
+		suite := ECHCipherSuite{KDFID: suites.uint16(), AEADID: suites.uint16()}
+		if suites.short {
+			return fmt.Errorf("truncated cipher suites")
+		}
+
+		suite.KDF = hpkeName(hpkeKDFNames, suite.KDFID)
+		suite.AEAD = hpkeName(hpkeAEADNames, suite.AEADID)
+		config.CipherSuites = append(config.CipherSuites, suite)
+	}
+
+	for len(extensions.data) > 0 {
+		extension := extensions.uint16()
+		extensions.vector16()
+		if extensions.short {
+			return fmt.Errorf("truncated extensions")
+		}
+		config.Extensions = append(config.Extensions, extension)
+	}
+
+	if len(config.CipherSuites) == 0 {
+		return fmt.Errorf("no cipher suites")
+	}
+	if config.PublicName == "" {
+		return fmt.Errorf("empty public name")
+	}
+
+	return nil
+}
+
+// hpkeName returns the name of an HPKE algorithm identifier, or its
+// hexadecimal value when unknown.
+func hpkeName(names map[uint16]string, id uint16) string {
+	if name, ok := names[id]; ok {
+		return name
+	}
+	return fmt.Sprintf("%#04x", id)
+}
+
+// echReader reads the big-endian integers and length-prefixed vectors of the
+// TLS presentation language. Reading past the end marks the reader as short
+// and returns zero values from then on.
+type echReader struct {
+	data  []byte
+	short bool
+}
+
+// read returns the next n bytes.
+func (r *echReader) read(n int) []byte {
+	if r.short || len(r.data) < n {
+		r.short = true
+		return nil
+	}
+
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+// uint8 reads a single byte.
+func (r *echReader) uint8() uint8 {
+	if b := r.read(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+// uint16 reads a big-endian 16-bit integer.
+func (r *echReader) uint16() uint16 {
+	if b := r.read(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+// vector8 reads a vector with a one-byte length prefix.
+func (r *echReader) vector8() []byte {
+	return r.read(int(r.uint8()))
+}
+
+// vector16 reads a vector with a two-byte length prefix.
+func (r *echReader) vector16() []byte {
+	return r.read(int(r.uint16()))
+}
@@ -0,0 +1,153 @@
+package dns
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// echConfig builds an ECHConfig of the given version using an X25519 key,
+// AES-128-GCM and ChaCha20Poly1305.
+func echConfig(t *testing.T, version uint16, configID uint8, publicName string) []byte {
+	t.Helper()
+
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	publicKey := key.PublicKey().Bytes()
+
+	contents := []byte{configID}
+	contents = binary.BigEndian.AppendUint16(contents, 0x0020)
+	contents = binary.BigEndian.AppendUint16(contents, uint16(len(publicKey)))
+	contents = append(contents, publicKey...)
+	contents = binary.BigEndian.AppendUint16(contents, 8)
+	contents = binary.BigEndian.AppendUint16(contents, 0x0001)
+	contents = binary.BigEndian.AppendUint16(contents, 0x0001)
+	contents = binary.BigEndian.AppendUint16(contents, 0x0001)
+	contents = binary.BigEndian.AppendUint16(contents, 0x0003)
+	contents = append(contents, 0, byte(len(publicName)))
+	contents = append(contents, publicName...)
+	contents = binary.BigEndian.AppendUint16(contents, 0)
+
+	config := binary.BigEndian.AppendUint16(nil, version)
+	config = binary.BigEndian.AppendUint16(config, uint16(len(contents)))
+	return append(config, contents...)
+}
+
+// echConfigListOf wraps ECHConfigs into an ECHConfigList.
+func echConfigListOf(configs ...[]byte) []byte {
+	var data []byte
+	for _, config := range configs {
+		data = append(data, config...)
+	}
+	return append(binary.BigEndian.AppendUint16(nil, uint16(len(data))), data...)
+}
+
+func TestParseECHConfigList(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		configs, err := parseECHConfigList(echConfigListOf(
+			echConfig(t, 0xfe0a, 1, "old.example.com"),
+			echConfig(t, echConfigVersion, 7, "cloudflare-ech.com"),
+		))
+		require.NoError(t, err)
+		require.Len(t, configs, 2)
+
+		assert.Equal(t, ECHConfig{Version: 0xfe0a}, configs[0])
+
+		config := configs[1]
+		assert.True(t, config.Supported)
+		assert.Equal(t, uint8(7), config.ConfigID)
+		assert.Equal(t, "DHKEM(X25519, HKDF-SHA256)", config.KEM)
+		assert.Equal(t, "cloudflare-ech.com", config.PublicName)
+		assert.Len(t, config.PublicKey, 44)
+		assert.Equal(t, []ECHCipherSuite{
+			{KDFID: 1, KDF: "HKDF-SHA256", AEADID: 1, AEAD: "AES-128-GCM"},
+			{KDFID: 1, KDF: "HKDF-SHA256", AEADID: 3, AEAD: "ChaCha20Poly1305"},
+		}, config.CipherSuites)
+	})
+
+	t.Run("only unknown versions", func(t *testing.T) {
+		configs, err := parseECHConfigList(echConfigListOf(echConfig(t, 0xfe0a, 1, "example.com")))
+		assert.Len(t, configs, 1)
+		assert.EqualError(t, err, "no ECHConfig has the supported version 0xfe0d")
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		list := echConfigListOf(echConfig(t, echConfigVersion, 1, "example.com"))
+		_, err := parseECHConfigList(list[:len(list)-4])
+		assert.EqualError(t, err, "malformed ECHConfigList: the length prefix doesn't match the data")
+
+		// A consistent list whose config is cut short
+		config := echConfig(t, echConfigVersion, 1, "example.com")
+		_, err = parseECHConfigList(echConfigListOf(config[:len(config)-4]))
+		assert.EqualError(t, err, "malformed ECHConfig 1: truncated")
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		_, err := parseECHConfigList([]byte{0, 0})
+		assert.EqualError(t, err, "the ECHConfigList is empty")
+	})
+}
+
+func TestDecodeSVCB(t *testing.T) {
+	ech := base64.StdEncoding.EncodeToString(echConfigListOf(echConfig(t, echConfigVersion, 3, "public.example.com")))
+
+	records := mustRRs(t,
+		"example.com. 300 IN HTTPS 1 . alpn=h3,h2 port=8443 ipv4hint=192.0.2.1,192.0.2.2 ipv6hint=2001:db8::1 ech="+ech,
+		"example.com. 300 IN HTTPS 0 cdn.example.net. alpn=h2",
+		"_dns.example.com. 300 IN SVCB 1 dns.example.com. alpn=h2 dohpath=/dns-query{?dns} key65000=foo",
+		"example.com. 300 IN HTTPS 2 . no-default-alpn",
+	)
+
+	svcb, ok := svcbData(records[0])
+	require.True(t, ok)
+
+	decoded := decodeSVCB(svcb)
+	assert.Equal(t, svcbModeService, decoded.Mode)
+	assert.Equal(t, ".", decoded.Target)
+	assert.Equal(t, []string{"h3", "h2"}, decoded.ALPN)
+	assert.Equal(t, uint16(8443), decoded.Port)
+	assert.Equal(t, []string{"192.0.2.1", "192.0.2.2"}, decoded.IPv4Hint)
+	assert.Equal(t, []string{"2001:db8::1"}, decoded.IPv6Hint)
+	require.NotNil(t, decoded.ECH)
+	require.Len(t, decoded.ECH.Configs, 1)
+	assert.Equal(t, "public.example.com", decoded.ECH.Configs[0].PublicName)
+	assert.Equal(t, uint8(3), decoded.ECH.Configs[0].ConfigID)
+	assert.Empty(t, decoded.Issues)
+
+	svcb, _ = svcbData(records[1])
+	decoded = decodeSVCB(svcb)
+	assert.Equal(t, svcbModeAlias, decoded.Mode)
+	assert.Equal(t, []string{"AliasMode records (priority 0) must not have service parameters, so clients ignore them"}, decoded.Issues)
+
+	svcb, _ = svcbData(records[2])
+	decoded = decodeSVCB(svcb)
+	assert.Equal(t, "/dns-query{?dns}", decoded.DoHPath)
+	assert.Equal(t, []SVCBParam{{Key: "key65000", Value: "foo"}}, decoded.OtherParams)
+
+	svcb, _ = svcbData(records[3])
+	decoded = decodeSVCB(svcb)
+	assert.True(t, decoded.NoDefaultALPN)
+	assert.Equal(t, []string{"no-default-alpn is set without alpn, so the record advertises no protocol"}, decoded.Issues)
+
+	t.Run("formatted records", func(t *testing.T) {
+		formatted := formatResourceRecords(records)
+		assert.Equal(t, []string{"h3", "h2"}, formatted[0]["svcb"].(SVCBRecord).ALPN)
+
+		_, ok := formatResourceRecords(mustRRs(t, "example.com. 300 IN A 192.0.2.1"))[0]["svcb"]
+		assert.False(t, ok)
+	})
+
+	t.Run("malformed ech", func(t *testing.T) {
+		record := &dns.SVCB{Priority: 1, Target: ".", Value: []dns.SVCBKeyValue{&dns.SVCBECHConfig{ECH: []byte{0, 5, 1}}}}
+		decoded := decodeSVCB(record)
+		require.NotNil(t, decoded.ECH)
+		assert.Empty(t, decoded.ECH.Configs)
+		assert.Equal(t, []string{"ech: malformed ECHConfigList: the length prefix doesn't match the data"}, decoded.Issues)
+	})
+}
@@ -0,0 +1,320 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/miekg/dns"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/domainname"
+	resp "github.com/patrickdappollonio/mcp-domaintools/internal/response"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/utils"
+)
+
+// maxTraceHops limits how many referrals a trace will follow before giving up.
+const maxTraceHops = 16
+
+// nameServer is a nameserver hostname paired with the address used to reach it.
+// The address is empty when the server was not accompanied by glue.
+type nameServer struct {
+	Name    string
+	Address string
+}
+
+// rootServers lists the IPv4 addresses of the DNS root servers.
+var rootServers = []nameServer{
+	{Name: "a.root-servers.net.", Address: "198.41.0.4"},
+	{Name: "b.root-servers.net.", Address: "170.247.170.2"},
+	{Name: "c.root-servers.net.", Address: "192.33.4.12"},
+	{Name: "d.root-servers.net.", Address: "199.7.91.13"},
+	{Name: "e.root-servers.net.", Address: "192.203.230.10"},
+	{Name: "f.root-servers.net.", Address: "192.5.5.241"},
+	{Name: "g.root-servers.net.", Address: "192.112.36.4"},
+	{Name: "h.root-servers.net.", Address: "198.97.190.53"},
+	{Name: "i.root-servers.net.", Address: "192.36.148.17"},
+	{Name: "j.root-servers.net.", Address: "192.58.128.30"},
+	{Name: "k.root-servers.net.", Address: "193.0.14.129"},
+	{Name: "l.root-servers.net.", Address: "199.7.83.42"},
+	{Name: "m.root-servers.net.", Address: "202.12.27.33"},
+}
+
+// traceParams represents the parameters for DNS trace queries.
+type traceParams struct {
+	Domain     string `json:"domain"`
+	RecordType string `json:"record_type"`
+}
+
+// TraceGlue represents a glue address returned alongside a referral.
+type TraceGlue struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Address string `json:"address"`
+}
+
+// TraceReferral represents the delegation returned by a server.
+type TraceReferral struct {
+	Zone        string      `json:"zone"`
+	NameServers []string    `json:"nameservers"`
+	Glue        []TraceGlue `json:"glue,omitempty"`
+}
+
+// TraceHop represents a single step in the delegation trace.
+type TraceHop struct {
+	Zone          string         `json:"zone"`
+	Server        string         `json:"server"`
+	ServerAddress string         `json:"server_address"`
+	RTT           float64        `json:"rtt_ms"`
+	Rcode         string         `json:"rcode"`
+	Authoritative bool           `json:"authoritative"`
+	TCPFallback   bool           `json:"tcp_fallback,omitempty"`
+	Referral      *TraceReferral `json:"referral,omitempty"`
+	Errors        []string       `json:"errors,omitempty"`
+}
+
+// TraceResponse represents the complete DNS trace response.
+type TraceResponse struct {
+	Domain     string           `json:"domain"`
+	DomainName *domainname.Name `json:"domain_name,omitempty"`
+	RecordType string           `json:"record_type"`
+	Hops       []TraceHop       `json:"hops"`
+	Answer     map[string]any   `json:"answer,omitempty"`
+	Completed  bool             `json:"completed"`
+	Error      string           `json:"error,omitempty"`
+	Timestamp  string           `json:"timestamp"`
+}
+
+// HandleDNSTrace follows the delegation chain for a domain from the root
+// servers down to its authoritative servers, similar to "dig +trace".
+func HandleDNSTrace(ctx context.Context, request mcp.CallToolRequest, config *QueryConfig) (*mcp.CallToolResult, error) {
+	var params traceParams
+	if err := request.BindArguments(&params); err != nil {
+		return nil, fmt.Errorf("failed to parse tool input: %w", utils.ParseJSONUnmarshalError(err))
+	}
+
+	// Validate required parameters
+	if params.Domain == "" {
+		return nil, fmt.Errorf("parameter \"domain\" is required")
+	}
+
+	// Set default record type if not provided
+	if params.RecordType == "" {
+		params.RecordType = "A"
+	}
+
+	// Validate and normalize the domain name
+	name, err := domainname.Parse(params.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	recordType, err := ConvertToQType(params.RecordType)
+	if err != nil {
+		return nil, err
+	}
+
+	result := traceDelegation(ctx, name.FQDN(), recordType, config)
+	result.DomainName = name
+	return resp.JSON(result)
+}
+
+// traceDelegation walks the delegation chain for the given name, starting at
+// the root servers and following referrals until an authoritative answer is
+// received or the trace cannot continue.
+func traceDelegation(ctx context.Context, domain string, qtype uint16, config *QueryConfig) *TraceResponse {
+	result := &TraceResponse{
+		Domain:     domain,
+		RecordType: dns.TypeToString[qtype],
+		Hops:       make([]TraceHop, 0),
+		Timestamp:  time.Now().Format(time.RFC3339),
+	}
+
+	zone := "."
+	servers := rootServers
+
+	for range maxTraceHops {
+		// Create a non-recursive query, as a resolver would send it
+		m := new(dns.Msg)
+		m.SetQuestion(domain, qtype)
+		m.RecursionDesired = false
+		m.SetEdns0(4096, false)
+
+		response, hop := exchangeWithNameServers(ctx, m, servers, config)
+		hop.Zone = zone
+		if response == nil {
+			result.Hops = append(result.Hops, hop)
+			result.Error = fmt.Sprintf("no nameserver for zone %q responded", zone)
+			return result
+		}
+
+		referral := findReferral(response, domain, zone)
+		hop.Referral = referral
+		result.Hops = append(result.Hops, hop)
+
+		// An answer, an authoritative response or an error code ends the trace
+		if len(response.Answer) > 0 || response.Authoritative || response.Rcode != dns.RcodeSuccess {
+			result.Answer = createDNSResponse(response)
+			result.Completed = response.Authoritative
+			if !response.Authoritative {
+				result.Error = fmt.Sprintf("server %s answered for zone %q without the authoritative bit set", hop.Server, zone)
+			}
+			return result
+		}
+
+		if referral == nil {
+			result.Answer = createDNSResponse(response)
+			result.Error = fmt.Sprintf("server %s returned neither an answer nor a referral below zone %q (lame delegation)", hop.Server, zone)
+			return result
+		}
+
+		zone = referral.Zone
+		servers = referralNameServers(referral)
+	}
+
+	result.Error = fmt.Sprintf("trace exceeded the maximum of %d referrals", maxTraceHops)
+	return result
+}
+
+// exchangeWithNameServers sends the message to each nameserver in turn until
+// one of them responds. Nameservers without a known address are resolved using
+// the local resolvers, and truncated UDP responses are retried over TCP. It
+// returns the response, if any, and the hop details.
+func exchangeWithNameServers(ctx context.Context, m *dns.Msg, servers []nameServer, config *QueryConfig) (*dns.Msg, TraceHop) {
+	var hop TraceHop
+
+	for _, server := range servers {
+		if err := ctx.Err(); err != nil {
+			hop.Errors = append(hop.Errors, err.Error())
+			return nil, hop
+		}
+
+		address := server.Address
+		if address == "" {
+			resolved, err := resolveNameServerAddress(ctx, server.Name, config)
+			if err != nil {
+				hop.Errors = append(hop.Errors, fmt.Sprintf("%s: %v", server.Name, err))
+				continue
+			}
+			address = resolved
+		}
+
+		answered, err := exchangeWithTCPFallback(ctx, m, serverAddress(address), transportUDP, config.Timeout)
+		if err != nil {
+			hop.Errors = append(hop.Errors, fmt.Sprintf("%s (%s): %v", server.Name, address, err))
+			continue
+		}
+
+		hop.Server = server.Name
+		hop.ServerAddress = address
+		hop.RTT = float64(answered.RTT.Microseconds()) / 1000
+		hop.Rcode = dns.RcodeToString[answered.Response.Rcode]
+		hop.Authoritative = answered.Response.Authoritative
+		hop.TCPFallback = answered.TCPFallback
+		return answered.Response, hop
+	}
+
+	return nil, hop
+}
+
+// resolveNameServerAddress looks up the first IPv4 address of a nameserver
+// using the local resolvers.
+func resolveNameServerAddress(ctx context.Context, name string, config *QueryConfig) (string, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(name), dns.TypeA)
+	m.RecursionDesired = true
+
+	response, err := exchangeWithSystemServers(ctx, m, config)
+	if err != nil {
+		return "", err
+	}
+
+	for _, rr := range response.Answer {
+		if a, ok := rr.(*dns.A); ok {
+			return a.A.String(), nil
+		}
+	}
+
+	return "", fmt.Errorf("no IPv4 address found")
+}
+
+// findReferral extracts the delegation from the authority section of a
+// response. Only delegations that are closer to the queried name than the
+// current zone are considered, so upward referrals are ignored.
+func findReferral(response *dns.Msg, domain, zone string) *TraceReferral {
+	var referral *TraceReferral
+
+	for _, rr := range response.Ns {
+		ns, ok := rr.(*dns.NS)
+		if !ok {
+			continue
+		}
+
+		owner := dns.Fqdn(ns.Header().Name)
+		if !dns.IsSubDomain(owner, domain) || dns.CountLabel(owner) <= dns.CountLabel(zone) {
+			continue
+		}
+
+		if referral == nil {
+			referral = &TraceReferral{Zone: owner}
+		}
+
+		if strings.EqualFold(owner, referral.Zone) {
+			referral.NameServers = append(referral.NameServers, ns.Ns)
+		}
+	}
+
+	if referral == nil {
+		return nil
+	}
+
+	// Collect any glue for the referred nameservers
+	for _, rr := range response.Extra {
+		var address string
+		switch rec := rr.(type) {
+		case *dns.A:
+			address = rec.A.String()
+		case *dns.AAAA:
+			address = rec.AAAA.String()
+		default:
+			continue
+		}
+
+		for _, ns := range referral.NameServers {
+			if strings.EqualFold(rr.Header().Name, ns) {
+				referral.Glue = append(referral.Glue, TraceGlue{
+					Name:    rr.Header().Name,
+					Type:    dns.TypeToString[rr.Header().Rrtype],
+					Address: address,
+				})
+				break
+			}
+		}
+	}
+
+	return referral
+}
+
+// referralNameServers builds the list of nameservers to query next from a
+// referral, preferring servers that came with IPv4 glue.
+func referralNameServers(referral *TraceReferral) []nameServer {
+	var withGlue, withoutGlue []nameServer
+
+	for _, ns := range referral.NameServers {
+		var address string
+		for _, glue := range referral.Glue {
+			if strings.EqualFold(glue.Name, ns) && glue.Type == "A" {
+				address = glue.Address
+				break
+			}
+		}
+
+		if address != "" {
+			withGlue = append(withGlue, nameServer{Name: ns, Address: address})
+		} else {
+			withoutGlue = append(withoutGlue, nameServer{Name: ns})
+		}
+	}
+
+	return append(withGlue, withoutGlue...)
+}
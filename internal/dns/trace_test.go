@@ -0,0 +1,174 @@
+package dns
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindReferral(t *testing.T) {
+	// response builds a response with the given authority and additional
+	// sections
+	response := func(authoritative bool, answer, ns, extra []dns.RR) *dns.Msg {
+		m := new(dns.Msg)
+		m.SetQuestion("www.example.com.", dns.TypeA)
+		m.Authoritative = authoritative
+		m.Answer, m.Ns, m.Extra = answer, ns, extra
+		return m
+	}
+
+	tests := []struct {
+		name     string
+		response *dns.Msg
+		zone     string
+		want     *TraceReferral
+	}{
+		{
+			name: "referral with glue",
+			response: response(false, nil,
+				mustRRs(t,
+					"example.com. 172800 IN NS ns1.example.com.",
+					"example.com. 172800 IN NS ns2.example.com.",
+				),
+				mustRRs(t,
+					"ns1.example.com. 172800 IN A 192.0.2.53",
+					"ns1.example.com. 172800 IN AAAA 2001:db8::53",
+					"ns2.example.com. 172800 IN A 198.51.100.53",
+					"unrelated.example.net. 172800 IN A 203.0.113.1",
+				),
+			),
+			zone: "com.",
+			want: &TraceReferral{
+				Zone:        "example.com.",
+				NameServers: []string{"ns1.example.com.", "ns2.example.com."},
+				Glue: []TraceGlue{
+					{Name: "ns1.example.com.", Type: "A", Address: "192.0.2.53"},
+					{Name: "ns1.example.com.", Type: "AAAA", Address: "2001:db8::53"},
+					{Name: "ns2.example.com.", Type: "A", Address: "198.51.100.53"},
+				},
+			},
+		},
+		{
+			name: "referral without glue",
+			response: response(false, nil,
+				mustRRs(t,
+					"example.com. 172800 IN NS ns1.dns-provider.net.",
+					"example.com. 172800 IN NS ns2.dns-provider.net.",
+				),
+				nil,
+			),
+			zone: "com.",
+			want: &TraceReferral{
+				Zone:        "example.com.",
+				NameServers: []string{"ns1.dns-provider.net.", "ns2.dns-provider.net."},
+			},
+		},
+		{
+			name: "out-of-bailiwick NS records are ignored",
+			response: response(false, nil,
+				mustRRs(t,
+					"example.net. 172800 IN NS ns1.example.net.",
+					"com. 172800 IN NS a.gtld-servers.net.",
+					"example.com. 172800 IN NS ns1.example.com.",
+				),
+				mustRRs(t, "ns1.example.net. 172800 IN A 203.0.113.53"),
+			),
+			zone: "com.",
+			want: &TraceReferral{
+				Zone:        "example.com.",
+				NameServers: []string{"ns1.example.com."},
+			},
+		},
+		{
+			name: "upward referral",
+			response: response(false, nil,
+				mustRRs(t, ". 518400 IN NS a.root-servers.net."),
+				mustRRs(t, "a.root-servers.net. 518400 IN A 198.41.0.4"),
+			),
+			zone: "com.",
+		},
+		{
+			name: "authoritative final answer",
+			response: response(true,
+				mustRRs(t, "www.example.com. 300 IN A 192.0.2.1"),
+				mustRRs(t, "example.com. 172800 IN NS ns1.example.com."),
+				mustRRs(t, "ns1.example.com. 172800 IN A 192.0.2.53"),
+			),
+			zone: "example.com.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, findReferral(tt.response, "www.example.com.", tt.zone))
+		})
+	}
+}
+
+func TestReferralNameServers(t *testing.T) {
+	tests := []struct {
+		name     string
+		referral *TraceReferral
+		want     []nameServer
+	}{
+		{
+			name: "servers with IPv4 glue come first",
+			referral: &TraceReferral{
+				Zone:        "example.com.",
+				NameServers: []string{"ns1.dns-provider.net.", "ns2.example.com.", "ns3.example.com."},
+				Glue: []TraceGlue{
+					{Name: "ns2.example.com.", Type: "A", Address: "198.51.100.53"},
+					{Name: "ns3.example.com.", Type: "AAAA", Address: "2001:db8::53"},
+				},
+			},
+			want: []nameServer{
+				{Name: "ns2.example.com.", Address: "198.51.100.53"},
+				{Name: "ns1.dns-provider.net."},
+				{Name: "ns3.example.com."},
+			},
+		},
+		{
+			name: "no glue",
+			referral: &TraceReferral{
+				Zone:        "example.com.",
+				NameServers: []string{"ns1.dns-provider.net.", "ns2.dns-provider.net."},
+			},
+			want: []nameServer{
+				{Name: "ns1.dns-provider.net."},
+				{Name: "ns2.dns-provider.net."},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, referralNameServers(tt.referral))
+		})
+	}
+}
+
+func TestExchangeWithNameServers(t *testing.T) {
+	records := mustRRs(t,
+		`example.com. 300 IN TXT "first"`,
+		`example.com. 300 IN TXT "second"`,
+	)
+	address := startTruncatingServer(t, records)
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.com.", dns.TypeTXT)
+	m.RecursionDesired = false
+
+	servers := []nameServer{{Name: "ns1.example.com.", Address: address}}
+	response, hop := exchangeWithNameServers(context.Background(), m, servers, &QueryConfig{Timeout: 2 * time.Second})
+	require.NotNil(t, response)
+
+	assert.False(t, response.Truncated)
+	assert.Len(t, response.Answer, 2)
+	assert.True(t, hop.TCPFallback)
+	assert.Equal(t, "ns1.example.com.", hop.Server)
+	assert.Empty(t, hop.Errors)
+}
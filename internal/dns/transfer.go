@@ -0,0 +1,324 @@
+package dns
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/miekg/dns"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/domainname"
+	resp "github.com/patrickdappollonio/mcp-domaintools/internal/response"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/utils"
+)
+
+// defaultTransferRecordLimit is the default maximum number of records
+// returned by a zone transfer.
+const defaultTransferRecordLimit = 10000
+
+// tsigAlgorithms maps the accepted TSIG algorithm names to their DNS names.
+var tsigAlgorithms = map[string]string{
+	"hmac-sha1":   dns.HmacSHA1,
+	"hmac-sha224": dns.HmacSHA224,
+	"hmac-sha256": dns.HmacSHA256,
+	"hmac-sha384": dns.HmacSHA384,
+	"hmac-sha512": dns.HmacSHA512,
+}
+
+// zoneTransferParams represents the parameters for zone transfers.
+type zoneTransferParams struct {
+	Zone          string `json:"zone"`
+	Server        string `json:"server"`
+	TransferType  string `json:"transfer_type"`
+	Serial        *int64 `json:"serial"`
+	MaxRecords    *int   `json:"max_records"`
+	TSIGKeyName   string `json:"tsig_key_name"`
+	TSIGAlgorithm string `json:"tsig_algorithm"`
+	TSIGSecret    string `json:"tsig_secret"`
+}
+
+// ZoneTransferChange represents one incremental change in an IXFR response.
+type ZoneTransferChange struct {
+	FromSerial uint32           `json:"from_serial"`
+	ToSerial   uint32           `json:"to_serial"`
+	Deleted    []map[string]any `json:"deleted"`
+	Added      []map[string]any `json:"added"`
+}
+
+// ZoneTransferResponse represents the complete zone transfer response.
+type ZoneTransferResponse struct {
+	Zone         string               `json:"zone"`
+	DomainName   *domainname.Name     `json:"domain_name,omitempty"`
+	Server       string               `json:"server"`
+	TransferType string               `json:"transfer_type"`
+	Serial       uint32               `json:"serial"`
+	RecordCount  int                  `json:"record_count"`
+	Truncated    bool                 `json:"truncated"`
+	TSIGKey      string               `json:"tsig_key,omitempty"`
+	Incremental  bool                 `json:"incremental,omitempty"`
+	UpToDate     bool                 `json:"up_to_date,omitempty"`
+	Changes      []ZoneTransferChange `json:"changes,omitempty"`
+	Records      []map[string]any     `json:"records,omitempty"`
+	Duration     float64              `json:"duration_ms"`
+	Timestamp    string               `json:"timestamp"`
+}
+
+// HandleZoneTransfer requests a full (AXFR) or incremental (IXFR) zone
+// transfer from a primary nameserver, optionally signed with TSIG.
+func HandleZoneTransfer(ctx context.Context, request mcp.CallToolRequest, config *QueryConfig) (*mcp.CallToolResult, error) {
+	var params zoneTransferParams
+	if err := request.BindArguments(&params); err != nil {
+		return nil, fmt.Errorf("failed to parse tool input: %w", utils.ParseJSONUnmarshalError(err))
+	}
+
+	// Validate required parameters
+	if params.Zone == "" {
+		return nil, fmt.Errorf("parameter \"zone\" is required")
+	}
+
+	if params.Server == "" {
+		return nil, fmt.Errorf("parameter \"server\" is required")
+	}
+
+	// Validate and normalize the zone name
+	name, err := domainname.Parse(params.Zone)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set default transfer type if not provided
+	transferType := strings.ToUpper(params.TransferType)
+	if transferType == "" {
+		transferType = "AXFR"
+	}
+
+	if transferType != "AXFR" && transferType != "IXFR" {
+		return nil, fmt.Errorf("unsupported transfer type %q: must be AXFR or IXFR", params.TransferType)
+	}
+
+	if transferType == "IXFR" && params.Serial == nil {
+		return nil, fmt.Errorf("parameter \"serial\" is required for IXFR transfers")
+	}
+
+	if params.Serial != nil && (*params.Serial < 0 || *params.Serial > 0xFFFFFFFF) {
+		return nil, fmt.Errorf("parameter \"serial\" must be between 0 and 4294967295")
+	}
+
+	// Set default record limit if not provided
+	limit := defaultTransferRecordLimit
+	if params.MaxRecords != nil && *params.MaxRecords > 0 {
+		limit = *params.MaxRecords
+	}
+
+	zone := name.FQDN()
+
+	// Build the transfer request
+	m := new(dns.Msg)
+	if transferType == "IXFR" {
+		m.SetIxfr(zone, uint32(*params.Serial), ".", ".")
+	} else {
+		m.SetAxfr(zone)
+	}
+
+	t := &dns.Transfer{
+		DialTimeout:  config.Timeout,
+		ReadTimeout:  config.Timeout,
+		WriteTimeout: config.Timeout,
+	}
+
+	// Configure TSIG if a key was provided
+	if params.TSIGKeyName != "" || params.TSIGSecret != "" {
+		keyName, algorithm, err := configureTSIG(t, params)
+		if err != nil {
+			return nil, err
+		}
+		m.SetTsig(keyName, algorithm, 300, time.Now().Unix())
+	}
+
+	result, err := performZoneTransfer(ctx, t, m, serverAddress(params.Server), limit)
+	if err != nil {
+		return nil, fmt.Errorf("zone transfer failed: %w", err)
+	}
+
+	result.Zone = zone
+	result.DomainName = name
+	result.Server = params.Server
+	result.TransferType = transferType
+	if params.TSIGKeyName != "" {
+		result.TSIGKey = dns.Fqdn(params.TSIGKeyName)
+	}
+
+	return resp.JSON(result)
+}
+
+// configureTSIG validates the TSIG parameters and registers the secret on the
+// transfer. It returns the canonical key name and algorithm.
+func configureTSIG(t *dns.Transfer, params zoneTransferParams) (string, string, error) {
+	if params.TSIGKeyName == "" || params.TSIGSecret == "" {
+		return "", "", fmt.Errorf("parameters \"tsig_key_name\" and \"tsig_secret\" must be provided together")
+	}
+
+	if _, err := base64.StdEncoding.DecodeString(params.TSIGSecret); err != nil {
+		return "", "", fmt.Errorf("parameter \"tsig_secret\" must be base64 encoded: %w", err)
+	}
+
+	algorithmName := strings.TrimSuffix(strings.ToLower(params.TSIGAlgorithm), ".")
+	if algorithmName == "" {
+		algorithmName = "hmac-sha256"
+	}
+
+	algorithm, ok := tsigAlgorithms[algorithmName]
+	if !ok {
+		return "", "", fmt.Errorf("unsupported TSIG algorithm %q", params.TSIGAlgorithm)
+	}
+
+	keyName := dns.CanonicalName(params.TSIGKeyName)
+	t.TsigSecret = map[string]string{keyName: params.TSIGSecret}
+
+	return keyName, algorithm, nil
+}
+
+// performZoneTransfer runs the transfer and collects up to limit records.
+// The connection is closed if the context is canceled mid-transfer.
+func performZoneTransfer(ctx context.Context, t *dns.Transfer, m *dns.Msg, address string, limit int) (*ZoneTransferResponse, error) {
+	start := time.Now()
+
+	dialer := &net.Dialer{Timeout: t.DialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", address, err)
+	}
+	t.Conn = &dns.Conn{Conn: conn}
+
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.Close()
+	})
+	defer stop()
+
+	envelopes, err := t.In(m, address)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	result := &ZoneTransferResponse{
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	var records []dns.RR
+	var transferErr error
+	for envelope := range envelopes {
+		if envelope.Error != nil {
+			transferErr = envelope.Error
+			continue
+		}
+
+		for _, rr := range envelope.RR {
+			if len(records) >= limit {
+				result.Truncated = true
+				break
+			}
+			records = append(records, rr)
+		}
+
+		// Stop reading once the limit is reached; closing the connection
+		// makes the transfer goroutine exit and close the channel
+		if result.Truncated {
+			_ = conn.Close()
+		}
+	}
+
+	if transferErr != nil && !result.Truncated {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, transferErr
+	}
+
+	result.Duration = float64(time.Since(start).Microseconds()) / 1000
+	result.RecordCount = len(records)
+	if len(records) > 0 {
+		if soa, ok := records[0].(*dns.SOA); ok {
+			result.Serial = soa.Serial
+		}
+	}
+
+	if m.Question[0].Qtype == dns.TypeIXFR && !result.Truncated {
+		incremental, upToDate, changes := parseIXFR(records)
+		result.Incremental = incremental
+		result.UpToDate = upToDate
+		if incremental {
+			result.Changes = changes
+			return result, nil
+		}
+	}
+
+	result.Records = formatResourceRecords(records)
+	return result, nil
+}
+
+// parseIXFR splits an IXFR response into its incremental changes. It reports
+// whether the response was incremental, whether the server indicated that the
+// requested serial is already current, and the list of changes.
+//
+// An incremental response is a sequence of the current SOA, then for every
+// change the old SOA followed by deleted records and the new SOA followed by
+// added records, and finally the current SOA again (RFC 1995 section 4).
+func parseIXFR(records []dns.RR) (bool, bool, []ZoneTransferChange) {
+	if len(records) <= 1 {
+		return false, true, nil
+	}
+
+	if _, ok := records[1].(*dns.SOA); !ok {
+		// The server fell back to a full zone transfer
+		return false, false, nil
+	}
+
+	var changes []ZoneTransferChange
+	i := 1
+	for i < len(records)-1 {
+		from, ok := records[i].(*dns.SOA)
+		if !ok {
+			break
+		}
+		i++
+
+		change := ZoneTransferChange{
+			FromSerial: from.Serial,
+			Deleted:    []map[string]any{},
+			Added:      []map[string]any{},
+		}
+
+		var deleted []dns.RR
+		for i < len(records) && records[i].Header().Rrtype != dns.TypeSOA {
+			deleted = append(deleted, records[i])
+			i++
+		}
+
+		if i >= len(records) {
+			break
+		}
+
+		to, ok := records[i].(*dns.SOA)
+		if !ok {
+			break
+		}
+		change.ToSerial = to.Serial
+		i++
+
+		var added []dns.RR
+		for i < len(records) && records[i].Header().Rrtype != dns.TypeSOA {
+			added = append(added, records[i])
+			i++
+		}
+
+		change.Deleted = formatResourceRecords(deleted)
+		change.Added = formatResourceRecords(added)
+		changes = append(changes, change)
+	}
+
+	return true, false, changes
+}
@@ -0,0 +1,165 @@
+package dns
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mustRRs parses the given records in presentation format.
+func mustRRs(t *testing.T, records ...string) []dns.RR {
+	t.Helper()
+
+	rrs := make([]dns.RR, 0, len(records))
+	for _, record := range records {
+		rr, err := dns.NewRR(record)
+		require.NoError(t, err)
+		rrs = append(rrs, rr)
+	}
+	return rrs
+}
+
+// startTransferServer starts a TCP DNS server on localhost that answers
+// zone transfer requests with the given records.
+func startTransferServer(t *testing.T, records []dns.RR, tsigSecret map[string]string) string {
+	t.Helper()
+
+	handler := func(w dns.ResponseWriter, r *dns.Msg) {
+		if tsigSecret != nil && (r.IsTsig() == nil || w.TsigStatus() != nil) {
+			m := new(dns.Msg)
+			m.SetRcode(r, dns.RcodeRefused)
+			_ = w.WriteMsg(m)
+			return
+		}
+
+		ch := make(chan *dns.Envelope, 1)
+		tr := new(dns.Transfer)
+		ch <- &dns.Envelope{RR: records}
+		close(ch)
+		_ = tr.Out(w, r, ch)
+	}
+
+	return startTestDNSServer(t, "tcp", handler, func(server *dns.Server) { server.TsigSecret = tsigSecret })
+}
+
+func TestPerformZoneTransfer(t *testing.T) {
+	zone := mustRRs(t,
+		"example.com. 3600 IN SOA ns1.example.com. hostmaster.example.com. 2024010101 7200 3600 1209600 300",
+		"example.com. 3600 IN NS ns1.example.com.",
+		"ns1.example.com. 3600 IN A 192.0.2.53",
+		"www.example.com. 300 IN A 192.0.2.80",
+		"example.com. 3600 IN SOA ns1.example.com. hostmaster.example.com. 2024010101 7200 3600 1209600 300",
+	)
+
+	t.Run("full transfer", func(t *testing.T) {
+		address := startTransferServer(t, zone, nil)
+
+		m := new(dns.Msg)
+		m.SetAxfr("example.com.")
+		tr := &dns.Transfer{DialTimeout: time.Second, ReadTimeout: time.Second, WriteTimeout: time.Second}
+
+		result, err := performZoneTransfer(context.Background(), tr, m, address, defaultTransferRecordLimit)
+		require.NoError(t, err)
+
+		assert.Equal(t, uint32(2024010101), result.Serial)
+		assert.Equal(t, 5, result.RecordCount)
+		assert.False(t, result.Truncated)
+		require.Len(t, result.Records, 5)
+		assert.Equal(t, "www.example.com.", result.Records[3]["name"])
+		assert.Equal(t, "192.0.2.80", result.Records[3]["data"])
+	})
+
+	t.Run("record limit", func(t *testing.T) {
+		address := startTransferServer(t, zone, nil)
+
+		m := new(dns.Msg)
+		m.SetAxfr("example.com.")
+		tr := &dns.Transfer{DialTimeout: time.Second, ReadTimeout: time.Second, WriteTimeout: time.Second}
+
+		result, err := performZoneTransfer(context.Background(), tr, m, address, 2)
+		require.NoError(t, err)
+
+		assert.True(t, result.Truncated)
+		assert.Equal(t, 2, result.RecordCount)
+	})
+
+	t.Run("tsig signed transfer", func(t *testing.T) {
+		secret := map[string]string{"transfer-key.": "c2VjcmV0LXNlY3JldC1zZWNyZXQ="}
+		address := startTransferServer(t, zone, secret)
+
+		m := new(dns.Msg)
+		m.SetAxfr("example.com.")
+		tr := &dns.Transfer{DialTimeout: time.Second, ReadTimeout: time.Second, WriteTimeout: time.Second}
+
+		keyName, algorithm, err := configureTSIG(tr, zoneTransferParams{
+			TSIGKeyName: "Transfer-Key",
+			TSIGSecret:  secret["transfer-key."],
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "transfer-key.", keyName)
+		assert.Equal(t, dns.HmacSHA256, algorithm)
+		m.SetTsig(keyName, algorithm, 300, time.Now().Unix())
+
+		result, err := performZoneTransfer(context.Background(), tr, m, address, defaultTransferRecordLimit)
+		require.NoError(t, err)
+		assert.Equal(t, 5, result.RecordCount)
+	})
+}
+
+func TestParseIXFR(t *testing.T) {
+	t.Run("incremental", func(t *testing.T) {
+		records := mustRRs(t,
+			"example.com. 3600 IN SOA ns1.example.com. hostmaster.example.com. 3 7200 3600 1209600 300",
+			"example.com. 3600 IN SOA ns1.example.com. hostmaster.example.com. 1 7200 3600 1209600 300",
+			"www.example.com. 300 IN A 192.0.2.1",
+			"example.com. 3600 IN SOA ns1.example.com. hostmaster.example.com. 2 7200 3600 1209600 300",
+			"www.example.com. 300 IN A 192.0.2.2",
+			"example.com. 3600 IN SOA ns1.example.com. hostmaster.example.com. 2 7200 3600 1209600 300",
+			"example.com. 3600 IN SOA ns1.example.com. hostmaster.example.com. 3 7200 3600 1209600 300",
+			"mail.example.com. 300 IN A 192.0.2.25",
+			"example.com. 3600 IN SOA ns1.example.com. hostmaster.example.com. 3 7200 3600 1209600 300",
+		)
+
+		incremental, upToDate, changes := parseIXFR(records)
+
+		assert.True(t, incremental)
+		assert.False(t, upToDate)
+		require.Len(t, changes, 2)
+		assert.Equal(t, uint32(1), changes[0].FromSerial)
+		assert.Equal(t, uint32(2), changes[0].ToSerial)
+		require.Len(t, changes[0].Deleted, 1)
+		assert.Equal(t, "192.0.2.1", changes[0].Deleted[0]["data"])
+		require.Len(t, changes[0].Added, 1)
+		assert.Equal(t, "192.0.2.2", changes[0].Added[0]["data"])
+		assert.Equal(t, uint32(3), changes[1].ToSerial)
+		assert.Empty(t, changes[1].Deleted)
+		require.Len(t, changes[1].Added, 1)
+	})
+
+	t.Run("up to date", func(t *testing.T) {
+		records := mustRRs(t, "example.com. 3600 IN SOA ns1.example.com. hostmaster.example.com. 3 7200 3600 1209600 300")
+
+		incremental, upToDate, changes := parseIXFR(records)
+
+		assert.False(t, incremental)
+		assert.True(t, upToDate)
+		assert.Empty(t, changes)
+	})
+
+	t.Run("full transfer fallback", func(t *testing.T) {
+		records := mustRRs(t,
+			"example.com. 3600 IN SOA ns1.example.com. hostmaster.example.com. 3 7200 3600 1209600 300",
+			"www.example.com. 300 IN A 192.0.2.1",
+			"example.com. 3600 IN SOA ns1.example.com. hostmaster.example.com. 3 7200 3600 1209600 300",
+		)
+
+		incremental, upToDate, _ := parseIXFR(records)
+
+		assert.False(t, incremental)
+		assert.False(t, upToDate)
+	})
+}
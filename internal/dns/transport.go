@@ -0,0 +1,241 @@
+package dns
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
+)
+
+// Transports supported when querying a resolver.
+const (
+	transportUDP = "udp"
+	transportTCP = "tcp"
+	transportDoH = "doh"
+	transportDoT = "dot"
+	transportDoQ = "doq"
+)
+
+// encryptedDNSPort is the default port for DNS-over-TLS (RFC 7858) and
+// DNS-over-QUIC (RFC 9250).
+const encryptedDNSPort = "853"
+
+// doqNoError is the DOQ_NO_ERROR application error code (RFC 9250 section 4.3).
+const doqNoError = 0
+
+// resolverTarget is a resolver address paired with the transport used to reach it.
+type resolverTarget struct {
+	Name       string
+	Transport  string
+	Address    string
+	ServerName string
+}
+
+// parseResolverURL parses a resolver given as a URL. The scheme selects the
+// transport: https:// for DoH, tls:// for DoT, quic:// for DoQ, and tcp:// or
+// udp:// for plain DNS.
+func parseResolverURL(spec string) (resolverTarget, error) {
+	scheme, rest, _ := strings.Cut(spec, "://")
+	target := resolverTarget{Name: spec}
+
+	switch strings.ToLower(scheme) {
+	case "https":
+		target.Transport = transportDoH
+		target.Address = spec
+		return target, nil
+	case "tls":
+		target.Transport = transportDoT
+	case "quic":
+		target.Transport = transportDoQ
+	case "tcp":
+		target.Transport = transportTCP
+	case "udp":
+		target.Transport = transportUDP
+	default:
+		return target, fmt.Errorf("unsupported resolver %q: use a host, host:port, tcp://, udp://, tls://, quic://, https:// or %q", spec, systemResolversKeyword)
+	}
+
+	rest = strings.TrimSuffix(rest, "/")
+	if rest == "" {
+		return target, fmt.Errorf("resolver %q is missing a host", spec)
+	}
+
+	if target.Transport == transportDoT || target.Transport == transportDoQ {
+		target.Address = hostPortWithDefault(rest, encryptedDNSPort)
+		target.ServerName, _, _ = net.SplitHostPort(target.Address)
+		return target, nil
+	}
+
+	target.Address = serverAddress(rest)
+	return target, nil
+}
+
+// hostPortWithDefault returns the server as a host:port pair, adding the
+// given port when the server does not include one.
+func hostPortWithDefault(server, port string) string {
+	if _, _, err := net.SplitHostPort(server); err == nil {
+		return server
+	}
+	return net.JoinHostPort(strings.Trim(server, "[]"), port)
+}
+
+// exchangeWithResolver sends a DNS message to a single resolver using the
+// resolver's transport and returns the response, the message bytes as
+// received, and the round-trip time.
+func exchangeWithResolver(ctx context.Context, m *dns.Msg, target resolverTarget, timeout time.Duration) (*dns.Msg, []byte, time.Duration, error) {
+	switch target.Transport {
+	case transportDoH:
+		start := time.Now()
+		response, wire, err := exchangeDoH(ctx, m, target.Address, timeout)
+		return response, wire, time.Since(start), err
+
+	case transportDoQ:
+		start := time.Now()
+		response, wire, err := exchangeDoQ(ctx, m, target.Address, &tls.Config{ServerName: target.ServerName}, timeout)
+		return response, wire, time.Since(start), err
+
+	case transportDoT:
+		c := &dns.Client{
+			Net:       "tcp-tls",
+			Timeout:   timeout,
+			TLSConfig: &tls.Config{ServerName: target.ServerName},
+		}
+		return exchangeWire(ctx, c, m, target.Address)
+	}
+
+	c := &dns.Client{
+		Net:     target.Transport,
+		Timeout: timeout,
+	}
+	return exchangeWire(ctx, c, m, target.Address)
+}
+
+// exchangeWire works like dns.Client.ExchangeContext but also returns the
+// response bytes as they were received, since packing the parsed message
+// again can change its compression and record order.
+func exchangeWire(ctx context.Context, c *dns.Client, m *dns.Msg, address string) (*dns.Msg, []byte, time.Duration, error) {
+	conn, err := c.DialContext(ctx, address)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	defer conn.Close()
+
+	if opt := m.IsEdns0(); opt != nil && opt.UDPSize() >= dns.MinMsgSize {
+		conn.UDPSize = opt.UDPSize()
+	}
+
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = 2 * time.Second
+	}
+	deadline := time.Now().Add(timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	_ = conn.SetDeadline(deadline)
+
+	start := time.Now()
+	if err := conn.WriteMsg(m); err != nil {
+		return nil, nil, 0, err
+	}
+
+	_, isPacketConn := conn.Conn.(net.PacketConn)
+	for {
+		wire, err := conn.ReadMsgHeader(nil)
+		if err != nil {
+			return nil, nil, time.Since(start), err
+		}
+
+		response := new(dns.Msg)
+		if err := response.Unpack(wire); err != nil {
+			return nil, nil, time.Since(start), err
+		}
+
+		// A stray UDP datagram with another ID is ignored, as dns.Client does
+		if response.Id != m.Id {
+			if isPacketConn {
+				continue
+			}
+			return nil, nil, time.Since(start), dns.ErrId
+		}
+
+		return response, wire, time.Since(start), nil
+	}
+}
+
+// exchangeDoQ sends a DNS message to a DNS-over-QUIC server. Each query uses
+// its own bidirectional stream carrying a length-prefixed message. The
+// returned bytes are the response as received, so their ID is zero.
+func exchangeDoQ(ctx context.Context, m *dns.Msg, address string, tlsConfig *tls.Config, timeout time.Duration) (*dns.Msg, []byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	tlsConfig = tlsConfig.Clone()
+	tlsConfig.NextProtos = []string{"doq"}
+	tlsConfig.MinVersion = tls.VersionTLS13
+
+	conn, err := quic.DialAddr(ctx, address, tlsConfig, &quic.Config{HandshakeIdleTimeout: timeout})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to %s: %w", address, err)
+	}
+	defer func() {
+		_ = conn.CloseWithError(doqNoError, "")
+	}()
+
+	// The message ID must be zero on DoQ (RFC 9250 section 4.2.1)
+	query := m.Copy()
+	query.Id = 0
+
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to pack DNS query: %w", err)
+	}
+
+	stream, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open stream: %w", err)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = stream.SetDeadline(deadline)
+	}
+
+	frame := make([]byte, 2+len(packed))
+	binary.BigEndian.PutUint16(frame, uint16(len(packed)))
+	copy(frame[2:], packed)
+
+	if _, err := stream.Write(frame); err != nil {
+		return nil, nil, fmt.Errorf("DNS-over-QUIC query failed: %w", err)
+	}
+
+	// Closing the send side signals that the query is complete
+	if err := stream.Close(); err != nil {
+		return nil, nil, fmt.Errorf("DNS-over-QUIC query failed: %w", err)
+	}
+
+	var length [2]byte
+	if _, err := io.ReadFull(stream, length[:]); err != nil {
+		return nil, nil, fmt.Errorf("failed to read DNS response: %w", err)
+	}
+
+	body := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(stream, body); err != nil {
+		return nil, nil, fmt.Errorf("failed to read DNS response: %w", err)
+	}
+
+	response := new(dns.Msg)
+	if err := response.Unpack(body); err != nil {
+		return nil, nil, fmt.Errorf("failed to unpack DNS response: %w", err)
+	}
+
+	// Restore the caller's message ID so the response matches the query
+	response.Id = m.Id
+	return response, body, nil
+}
@@ -0,0 +1,166 @@
+package dns
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"io"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseResolverURL(t *testing.T) {
+	tests := []struct {
+		spec       string
+		transport  string
+		address    string
+		serverName string
+		wantErr    bool
+	}{
+		{spec: "https://dns.google/dns-query", transport: transportDoH, address: "https://dns.google/dns-query"},
+		{spec: "tls://dns.quad9.net", transport: transportDoT, address: "dns.quad9.net:853", serverName: "dns.quad9.net"},
+		{spec: "TLS://1.1.1.1:8853", transport: transportDoT, address: "1.1.1.1:8853", serverName: "1.1.1.1"},
+		{spec: "quic://dns.adguard-dns.com", transport: transportDoQ, address: "dns.adguard-dns.com:853", serverName: "dns.adguard-dns.com"},
+		{spec: "quic://[2001:db8::1]", transport: transportDoQ, address: "[2001:db8::1]:853", serverName: "2001:db8::1"},
+		{spec: "tcp://9.9.9.9", transport: transportTCP, address: "9.9.9.9:53"},
+		{spec: "udp://[2001:db8::1]:5353", transport: transportUDP, address: "[2001:db8::1]:5353"},
+		{spec: "tls://", wantErr: true},
+		{spec: "ftp://example.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			target, err := parseResolverURL(tt.spec)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.transport, target.Transport)
+			assert.Equal(t, tt.address, target.Address)
+			assert.Equal(t, tt.serverName, target.ServerName)
+		})
+	}
+}
+
+func TestParseRemoteServer(t *testing.T) {
+	_, err := parseRemoteServer("8.8.8.8")
+	require.Error(t, err)
+
+	_, err = parseRemoteServer("udp://8.8.8.8")
+	require.Error(t, err)
+
+	target, err := parseRemoteServer("quic://dns.example.net")
+	require.NoError(t, err)
+	assert.Equal(t, transportDoQ, target.Transport)
+}
+
+// testCertificate creates a self-signed certificate for localhost and returns
+// it with a pool that trusts it.
+func testCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+func TestExchangeDoQ(t *testing.T) {
+	cert, pool := testCertificate(t)
+
+	listener, err := quic.ListenAddr("127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"doq"},
+	}, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+
+	// Answer a single query, checking the framing required by RFC 9250
+	go func() {
+		conn, err := listener.Accept(context.Background())
+		if err != nil {
+			return
+		}
+
+		stream, err := conn.AcceptStream(context.Background())
+		if err != nil {
+			return
+		}
+
+		// The client closes its side of the stream after the query
+		payload, err := io.ReadAll(stream)
+		if err != nil || len(payload) < 2 {
+			return
+		}
+
+		query := new(dns.Msg)
+		if err := query.Unpack(payload[2:]); err != nil || query.Id != 0 {
+			return
+		}
+
+		response := new(dns.Msg)
+		response.SetReply(query)
+		response.Answer = append(response.Answer, &dns.A{
+			Hdr: dns.RR_Header{Name: query.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 300},
+			A:   net.ParseIP("192.0.2.1"),
+		})
+
+		packed, err := response.Pack()
+		if err != nil {
+			return
+		}
+
+		frame := binary.BigEndian.AppendUint16(nil, uint16(len(packed)))
+		_, _ = stream.Write(append(frame, packed...))
+		_ = stream.Close()
+	}()
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.com.", dns.TypeA)
+
+	response, wire, err := exchangeDoQ(context.Background(), m, listener.Addr().String(), &tls.Config{
+		ServerName: "localhost",
+		RootCAs:    pool,
+	}, 5*time.Second)
+	require.NoError(t, err)
+
+	assert.Equal(t, m.Id, response.Id)
+	assert.Zero(t, binary.BigEndian.Uint16(wire), "the received bytes keep the DoQ message ID")
+	require.Len(t, response.Answer, 1)
+	assert.Equal(t, "192.0.2.1", response.Answer[0].(*dns.A).A.String())
+}
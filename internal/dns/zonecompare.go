@@ -0,0 +1,431 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/miekg/dns"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/domainname"
+	resp "github.com/patrickdappollonio/mcp-domaintools/internal/response"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/utils"
+	"golang.org/x/sync/errgroup"
+)
+
+// Limits applied when comparing a zone file with live DNS.
+const (
+	zoneCompareConcurrency = 8
+	maxZoneCompareRRSets   = 1000
+)
+
+// authoritativeServersKeyword selects the zone's own authoritative servers
+// as the servers to compare a zone file against.
+const authoritativeServersKeyword = "authoritative"
+
+// Statuses of an RRset compared with live DNS.
+const (
+	rrsetMatch       = "match"
+	rrsetMissing     = "missing"
+	rrsetDifferent   = "different"
+	rrsetTTLMismatch = "ttl_mismatch"
+	rrsetError       = "error"
+)
+
+// zoneCompareSkippedTypes are the record types generated when a zone is
+// signed, which a zone file in source control doesn't usually contain.
+var zoneCompareSkippedTypes = []uint16{dns.TypeNSEC, dns.TypeNSEC3}
+
+// zoneCompareParams represents the parameters for zone comparisons.
+type zoneCompareParams struct {
+	Zone   string `json:"zone"`
+	Origin string `json:"origin"`
+	Server string `json:"server"`
+}
+
+// ZoneCompareRRSet represents the comparison of one RRset of the zone file
+// with the records served live.
+type ZoneCompareRRSet struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Status      string   `json:"status"`
+	Server      string   `json:"server,omitempty"`
+	Rcode       string   `json:"rcode,omitempty"`
+	ExpectedTTL uint32   `json:"expected_ttl"`
+	LiveTTL     uint32   `json:"live_ttl,omitempty"`
+	Expected    []string `json:"expected"`
+	Live        []string `json:"live"`
+	Missing     []string `json:"missing,omitempty"`
+	Extra       []string `json:"extra,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+// ZoneCompareCounts tallies the statuses of compared RRsets.
+type ZoneCompareCounts struct {
+	Matching      int `json:"matching"`
+	Missing       int `json:"missing"`
+	Different     int `json:"different"`
+	TTLMismatches int `json:"ttl_mismatches"`
+	Errors        int `json:"errors"`
+}
+
+// ZoneCompareServer summarizes the comparison of the zone file with a single
+// authoritative server.
+type ZoneCompareServer struct {
+	Server string `json:"server"`
+	InSync bool   `json:"in_sync"`
+	ZoneCompareCounts
+}
+
+// ZoneCompareResponse represents the complete zone comparison response. When
+// comparing against the authoritative servers, every RRset is compared on
+// each server, and each server is summarized in ServerResults.
+type ZoneCompareResponse struct {
+	Origin        string           `json:"origin"`
+	DomainName    *domainname.Name `json:"domain_name,omitempty"`
+	Servers       []string         `json:"servers"`
+	Authoritative bool             `json:"authoritative"`
+	InSync        bool             `json:"in_sync"`
+	RRSetCount    int              `json:"rrset_count"`
+	ZoneCompareCounts
+	ServerResults []ZoneCompareServer `json:"server_results,omitempty"`
+	RRSets        []ZoneCompareRRSet  `json:"rrsets"`
+	Timestamp     string              `json:"timestamp"`
+}
+
+// HandleZoneCompare queries every RRset of a zone file on live DNS servers
+// and reports the records that are missing, extra or different, so that a
+// deployment can be verified against the zone kept in source control.
+func HandleZoneCompare(ctx context.Context, request mcp.CallToolRequest, config *QueryConfig) (*mcp.CallToolResult, error) {
+	var params zoneCompareParams
+	if err := request.BindArguments(&params); err != nil {
+		return nil, fmt.Errorf("failed to parse tool input: %w", utils.ParseJSONUnmarshalError(err))
+	}
+
+	// Validate required parameters
+	if strings.TrimSpace(params.Zone) == "" {
+		return nil, fmt.Errorf("parameter \"zone\" is required")
+	}
+
+	// Validate and normalize the origin, when given
+	var name *domainname.Name
+	origin := strings.TrimSpace(params.Origin)
+	if origin != "" {
+		var err error
+		if name, err = domainname.Parse(origin); err != nil {
+			return nil, err
+		}
+		origin = name.FQDN()
+	}
+
+	records, syntaxIssues := parseZoneText(params.Zone, origin)
+	if len(syntaxIssues) > 0 {
+		return nil, fmt.Errorf("zone file has %d syntax error(s), starting with %s; use the zone_lint tool to see them all", len(syntaxIssues), syntaxIssues[0].Message)
+	}
+
+	apex := zoneOrigin(origin, records)
+	if apex == "" {
+		return nil, fmt.Errorf("cannot determine the zone origin: add an SOA record, a $ORIGIN directive or the origin parameter")
+	}
+
+	rrsets := zoneCompareRRSets(records)
+	if len(rrsets) == 0 {
+		return nil, fmt.Errorf("zone file has no records to compare")
+	}
+
+	if len(rrsets) > maxZoneCompareRRSets {
+		return nil, fmt.Errorf("too many RRsets: zone file has %d, maximum is %d", len(rrsets), maxZoneCompareRRSets)
+	}
+
+	server := strings.TrimSpace(params.Server)
+	authoritative := server == "" || strings.EqualFold(server, authoritativeServersKeyword)
+
+	var targets []resolverTarget
+	var err error
+	if authoritative {
+		targets, err = authoritativeTargets(ctx, apex, records, config)
+	} else {
+		targets, err = parseResolverTargets(ctx, []string{server})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := compareZone(ctx, apex, rrsets, targets, authoritative, config.Timeout)
+	result.DomainName = name
+	return resp.JSON(result)
+}
+
+// zoneCompareRRSets groups the records of a zone file into the RRsets to
+// query, leaving out the records generated by DNSSEC signing.
+func zoneCompareRRSets(records []dns.RR) [][]dns.RR {
+	var rrsets [][]dns.RR
+	for _, rrset := range groupRRSets(records) {
+		if !slices.Contains(zoneCompareSkippedTypes, rrset[0].Header().Rrtype) {
+			rrsets = append(rrsets, rrset)
+		}
+	}
+	return rrsets
+}
+
+// authoritativeTargets returns every address of the zone's nameservers. The
+// nameservers and their addresses come from DNS, falling back to the zone
+// file for a zone that isn't delegated or published yet.
+func authoritativeTargets(ctx context.Context, zone string, records []dns.RR, config *QueryConfig) ([]resolverTarget, error) {
+	nameservers, err := lookupNameServers(ctx, zone, config)
+	if err != nil {
+		nameservers = nameServerTargets(records, zone)
+	}
+
+	var targets []resolverTarget
+	for _, nameserver := range nameservers {
+		ipv4, ipv6 := lookupAddresses(ctx, nameserver, config)
+		addresses := append(ipv4, ipv6...)
+
+		if len(addresses) == 0 {
+			for _, rr := range records {
+				if !strings.EqualFold(rr.Header().Name, nameserver) {
+					continue
+				}
+
+				switch rec := rr.(type) {
+				case *dns.A:
+					addresses = append(addresses, rec.A.String())
+				case *dns.AAAA:
+					addresses = append(addresses, rec.AAAA.String())
+				}
+			}
+		}
+
+		for _, address := range addresses {
+			targets = append(targets, resolverTarget{
+				Name:      fmt.Sprintf("%s (%s)", nameserver, address),
+				Transport: transportUDP,
+				Address:   net.JoinHostPort(address, "53"),
+			})
+		}
+	}
+
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no authoritative servers found for zone %s", zone)
+	}
+
+	return targets, nil
+}
+
+// compareZone queries every RRset concurrently and tallies the results. The
+// first resolver that answers is used for each RRset, while every
+// authoritative server is compared on its own, since a secondary that's out
+// of sync is only found by querying it.
+func compareZone(ctx context.Context, apex string, rrsets [][]dns.RR, targets []resolverTarget, authoritative bool, timeout time.Duration) *ZoneCompareResponse {
+	groups := [][]resolverTarget{targets}
+	if authoritative {
+		groups = make([][]resolverTarget, 0, len(targets))
+		for _, target := range targets {
+			groups = append(groups, []resolverTarget{target})
+		}
+	}
+
+	// Results are ordered by RRset, then by server
+	results := make([]ZoneCompareRRSet, len(rrsets)*len(groups))
+
+	var eg errgroup.Group
+	eg.SetLimit(zoneCompareConcurrency)
+	for i, rrset := range rrsets {
+		for j, group := range groups {
+			k := i*len(groups) + j
+			eg.Go(func() error {
+				results[k] = compareRRSet(ctx, rrset, group, authoritative, timeout)
+				if authoritative {
+					results[k].Server = group[0].Name
+				}
+				return nil
+			})
+		}
+	}
+	_ = eg.Wait()
+
+	response := &ZoneCompareResponse{
+		Origin:        apex,
+		Servers:       make([]string, 0, len(targets)),
+		Authoritative: authoritative,
+		RRSetCount:    len(rrsets),
+		RRSets:        results,
+		Timestamp:     time.Now().Format(time.RFC3339),
+	}
+
+	for _, target := range targets {
+		response.Servers = append(response.Servers, target.Name)
+	}
+
+	for _, result := range results {
+		response.add(result.Status)
+	}
+	response.InSync = response.Matching == len(results)
+
+	if authoritative {
+		for j, group := range groups {
+			server := ZoneCompareServer{Server: group[0].Name}
+			for i := range rrsets {
+				server.add(results[i*len(groups)+j].Status)
+			}
+			server.InSync = server.Matching == len(rrsets)
+			response.ServerResults = append(response.ServerResults, server)
+		}
+	}
+
+	return response
+}
+
+// add counts an RRset with the given status.
+func (c *ZoneCompareCounts) add(status string) {
+	switch status {
+	case rrsetMatch:
+		c.Matching++
+	case rrsetMissing:
+		c.Missing++
+	case rrsetDifferent:
+		c.Different++
+	case rrsetTTLMismatch:
+		c.TTLMismatches++
+	case rrsetError:
+		c.Errors++
+	}
+}
+
+// compareRRSet queries a single RRset and compares the live records with the
+// expected ones. Authoritative servers must serve the TTL from the zone file,
+// while resolvers count it down as the records age in their caches, so only
+// a TTL above the expected one is reported for them.
+func compareRRSet(ctx context.Context, rrset []dns.RR, targets []resolverTarget, authoritative bool, timeout time.Duration) ZoneCompareRRSet {
+	header := rrset[0].Header()
+	result := ZoneCompareRRSet{
+		Name:        header.Name,
+		Type:        dns.TypeToString[header.Rrtype],
+		ExpectedTTL: header.Ttl,
+		Expected:    rrsetData(rrset),
+		Live:        make([]string, 0),
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(header.Name), header.Rrtype)
+	m.RecursionDesired = !authoritative
+	m.SetEdns0(4096, false)
+
+	response, server, err := exchangeWithTargets(ctx, m, targets, timeout)
+	if err != nil {
+		result.Status = rrsetError
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Server = server
+	result.Rcode = dns.RcodeToString[response.Rcode]
+
+	if response.Rcode != dns.RcodeSuccess && response.Rcode != dns.RcodeNameError {
+		result.Status = rrsetError
+		result.Error = fmt.Sprintf("server returned %s", result.Rcode)
+		return result
+	}
+
+	live := liveRRSet(response, header.Name, header.Rrtype, authoritative)
+	result.Live = rrsetData(live)
+	result.LiveTTL = minTTL(live)
+
+	for _, data := range result.Expected {
+		if !slices.Contains(result.Live, data) {
+			result.Missing = append(result.Missing, data)
+		}
+	}
+
+	for _, data := range result.Live {
+		if !slices.Contains(result.Expected, data) {
+			result.Extra = append(result.Extra, data)
+		}
+	}
+
+	switch {
+	case len(live) == 0:
+		result.Status = rrsetMissing
+	case len(result.Missing) > 0 || len(result.Extra) > 0:
+		result.Status = rrsetDifferent
+	case result.LiveTTL != result.ExpectedTTL && (authoritative || result.LiveTTL > result.ExpectedTTL):
+		result.Status = rrsetTTLMismatch
+	default:
+		result.Status = rrsetMatch
+	}
+
+	return result
+}
+
+// liveRRSet returns the records of the given name and type in a response.
+// Authoritative servers answer for the NS records and glue of a delegation
+// with a referral, so those sections are searched too.
+func liveRRSet(response *dns.Msg, name string, rrtype uint16, authoritative bool) []dns.RR {
+	sections := [][]dns.RR{response.Answer}
+	if authoritative && !response.Authoritative {
+		sections = append(sections, response.Ns, response.Extra)
+	}
+
+	for _, section := range sections {
+		var records []dns.RR
+		for _, rr := range section {
+			if rr.Header().Rrtype == rrtype && strings.EqualFold(rr.Header().Name, name) {
+				records = append(records, rr)
+			}
+		}
+
+		if len(records) > 0 {
+			return records
+		}
+	}
+
+	return nil
+}
+
+// rrsetData returns the sorted record data of an RRset in presentation
+// format, with whitespace normalized so the records compare as strings.
+func rrsetData(rrset []dns.RR) []string {
+	data := make([]string, 0, len(rrset))
+	for _, rr := range rrset {
+		data = append(data, strings.Join(strings.Fields(recordDataString(rr)), " "))
+	}
+	slices.Sort(data)
+	return slices.Compact(data)
+}
+
+// exchangeWithTargets sends a DNS message to each target in turn and
+// returns the first response received along with the target's name.
+func exchangeWithTargets(ctx context.Context, m *dns.Msg, targets []resolverTarget, timeout time.Duration) (*dns.Msg, string, error) {
+	var queryErr error
+
+	for _, target := range targets {
+		if err := ctx.Err(); err != nil {
+			return nil, "", fmt.Errorf("DNS query canceled: %w", err)
+		}
+
+		var response *dns.Msg
+		if target.Transport == transportUDP {
+			var answered *serverResponse
+			answered, queryErr = exchangeWithTCPFallback(ctx, m, target.Address, transportUDP, timeout)
+			if queryErr == nil {
+				response = answered.Response
+			}
+		} else {
+			response, _, _, queryErr = exchangeWithResolver(ctx, m, target, timeout)
+		}
+
+		if queryErr == nil && response != nil {
+			return response, target.Name, nil
+		}
+	}
+
+	if queryErr != nil {
+		return nil, "", fmt.Errorf("DNS query failed: %w", queryErr)
+	}
+
+	return nil, "", fmt.Errorf("no response from DNS servers")
+}
@@ -0,0 +1,153 @@
+package dns
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZoneCompareRRSets(t *testing.T) {
+	rrsets := zoneCompareRRSets(mustRRs(t,
+		"example.com. 300 IN A 192.0.2.1",
+		"example.com. 300 IN A 192.0.2.2",
+		"example.com. 300 IN NSEC www.example.com. A RRSIG NSEC",
+		"example.com. 300 IN RRSIG A 13 2 300 20300101000000 20200101000000 12345 example.com. AAAA",
+		"WWW.example.com. 300 IN A 192.0.2.3",
+	))
+
+	require.Len(t, rrsets, 2)
+	assert.Len(t, rrsets[0], 2)
+	assert.Equal(t, "WWW.example.com.", rrsets[1][0].Header().Name)
+}
+
+func TestCompareZone(t *testing.T) {
+	live := []string{
+		"example.com. 3600 IN A 192.0.2.1",
+		"example.com. 3600 IN MX 10 mail.example.com.",
+		"example.com. 3600 IN MX 20 backup.example.com.",
+		"www.example.com. 300 IN A 192.0.2.1",
+		`example.com. 3600 IN TXT "v=spf1 -all"`,
+	}
+	address := startZoneServer(t, mustRRs(t, live...))
+
+	// A secondary that missed the last change to the apex A record
+	stale := append([]string{"example.com. 3600 IN A 192.0.2.9"}, live[1:]...)
+	staleAddress := startZoneServer(t, mustRRs(t, stale...))
+
+	rrsets := zoneCompareRRSets(mustRRs(t,
+		"example.com. 3600 IN A 192.0.2.1",
+		"example.com. 3600 IN MX 10 mail.example.com.",
+		"www.example.com. 3600 IN A 192.0.2.1",
+		`example.com. 3600 IN TXT "v=spf1   -all"`,
+		"api.example.com. 3600 IN AAAA 2001:db8::1",
+	))
+
+	targets := []resolverTarget{
+		{Name: "unreachable", Transport: transportUDP, Address: "127.0.0.1:1"},
+		{Name: "test", Transport: transportUDP, Address: address},
+		{Name: "stale", Transport: transportUDP, Address: staleAddress},
+	}
+
+	byName := func(result *ZoneCompareResponse, server, name, rrtype string) ZoneCompareRRSet {
+		for _, rrset := range result.RRSets {
+			if rrset.Server == server && rrset.Name == name && rrset.Type == rrtype {
+				return rrset
+			}
+		}
+		require.FailNow(t, "rrset not found", "%s %s on %s", name, rrtype, server)
+		return ZoneCompareRRSet{}
+	}
+
+	t.Run("authoritative", func(t *testing.T) {
+		result := compareZone(context.Background(), "example.com.", rrsets, targets, true, time.Second)
+
+		assert.Equal(t, []string{"unreachable", "test", "stale"}, result.Servers)
+		assert.False(t, result.InSync)
+		assert.Equal(t, 5, result.RRSetCount)
+		assert.Len(t, result.RRSets, 15)
+
+		apex := byName(result, "test", "example.com.", "A")
+		assert.Equal(t, rrsetMatch, apex.Status)
+		assert.Equal(t, []string{"192.0.2.1"}, apex.Live)
+
+		// Whitespace differences in the zone file don't matter
+		assert.Equal(t, rrsetMatch, byName(result, "test", "example.com.", "TXT").Status)
+
+		mx := byName(result, "test", "example.com.", "MX")
+		assert.Equal(t, rrsetDifferent, mx.Status)
+		assert.Empty(t, mx.Missing)
+		assert.Equal(t, []string{"20 backup.example.com."}, mx.Extra)
+
+		www := byName(result, "test", "www.example.com.", "A")
+		assert.Equal(t, rrsetTTLMismatch, www.Status)
+		assert.Equal(t, uint32(3600), www.ExpectedTTL)
+		assert.Equal(t, uint32(300), www.LiveTTL)
+
+		api := byName(result, "test", "api.example.com.", "AAAA")
+		assert.Equal(t, rrsetMissing, api.Status)
+		assert.Equal(t, "NXDOMAIN", api.Rcode)
+		assert.Equal(t, []string{"2001:db8::1"}, api.Missing)
+
+		// Every server is compared, so the stale secondary is found
+		staleApex := byName(result, "stale", "example.com.", "A")
+		assert.Equal(t, rrsetDifferent, staleApex.Status)
+		assert.Equal(t, []string{"192.0.2.1"}, staleApex.Missing)
+		assert.Equal(t, []string{"192.0.2.9"}, staleApex.Extra)
+
+		assert.Equal(t, rrsetError, byName(result, "unreachable", "example.com.", "A").Status)
+
+		assert.Equal(t, []ZoneCompareServer{
+			{Server: "unreachable", ZoneCompareCounts: ZoneCompareCounts{Errors: 5}},
+			{Server: "test", ZoneCompareCounts: ZoneCompareCounts{Matching: 2, Missing: 1, Different: 1, TTLMismatches: 1}},
+			{Server: "stale", ZoneCompareCounts: ZoneCompareCounts{Matching: 1, Missing: 1, Different: 2, TTLMismatches: 1}},
+		}, result.ServerResults)
+
+		assert.Equal(t, 3, result.Matching)
+		assert.Equal(t, 3, result.Different)
+		assert.Equal(t, 2, result.TTLMismatches)
+		assert.Equal(t, 2, result.Missing)
+		assert.Equal(t, 5, result.Errors)
+	})
+
+	t.Run("authoritative servers in sync", func(t *testing.T) {
+		result := compareZone(context.Background(), "example.com.", rrsets[:1], targets[1:2], true, time.Second)
+		assert.True(t, result.InSync)
+		require.Len(t, result.ServerResults, 1)
+		assert.True(t, result.ServerResults[0].InSync)
+	})
+
+	t.Run("resolver ttls count down", func(t *testing.T) {
+		result := compareZone(context.Background(), "example.com.", rrsets, targets[:2], false, time.Second)
+		assert.Len(t, result.RRSets, 5)
+		assert.Empty(t, result.ServerResults)
+		assert.Equal(t, rrsetMatch, byName(result, "test", "www.example.com.", "A").Status)
+		assert.Zero(t, result.TTLMismatches)
+	})
+
+	t.Run("no servers respond", func(t *testing.T) {
+		result := compareZone(context.Background(), "example.com.", rrsets[:1], targets[:1], true, time.Second)
+		require.Len(t, result.RRSets, 1)
+		assert.Equal(t, rrsetError, result.RRSets[0].Status)
+		assert.NotEmpty(t, result.RRSets[0].Error)
+		assert.Equal(t, 1, result.Errors)
+	})
+}
+
+func TestLiveRRSet(t *testing.T) {
+	referral := new(dns.Msg)
+	referral.Ns = mustRRs(t,
+		"sub.example.com. 3600 IN NS ns1.sub.example.com.",
+		"sub.example.com. 3600 IN NS ns2.example.net.",
+	)
+	referral.Extra = mustRRs(t, "ns1.sub.example.com. 3600 IN A 192.0.2.53")
+
+	assert.Len(t, liveRRSet(referral, "sub.example.com.", dns.TypeNS, true), 2)
+	assert.Len(t, liveRRSet(referral, "ns1.sub.example.com.", dns.TypeA, true), 1)
+
+	// Resolvers follow delegations, so their authority section doesn't count
+	assert.Empty(t, liveRRSet(referral, "sub.example.com.", dns.TypeNS, false))
+}
@@ -0,0 +1,407 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/miekg/dns"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/domainname"
+	resp "github.com/patrickdappollonio/mcp-domaintools/internal/response"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/utils"
+)
+
+// Limits applied when linting a zone file.
+const (
+	maxZoneLintSyntaxErrors = 20
+	minReasonableTTL        = 60
+	maxReasonableTTL        = 604800
+	maxNegativeCacheTTL     = 86400
+)
+
+// Severities of zone lint issues.
+const (
+	lintError   = "error"
+	lintWarning = "warning"
+)
+
+// parseErrorLine extracts the line and column from a zone parser error.
+var parseErrorLine = regexp.MustCompile(`at line: (\d+):(\d+)$`)
+
+// zoneLintParams represents the parameters for zone file linting.
+type zoneLintParams struct {
+	Zone   string `json:"zone"`
+	Origin string `json:"origin"`
+}
+
+// ZoneLintIssue represents a single problem found in a zone file.
+type ZoneLintIssue struct {
+	Severity string `json:"severity"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Message  string `json:"message"`
+}
+
+// ZoneLintResponse represents the complete zone lint response.
+type ZoneLintResponse struct {
+	Origin       string           `json:"origin"`
+	DomainName   *domainname.Name `json:"domain_name,omitempty"`
+	Valid        bool             `json:"valid"`
+	RecordCount  int              `json:"record_count"`
+	ErrorCount   int              `json:"error_count"`
+	WarningCount int              `json:"warning_count"`
+	Issues       []ZoneLintIssue  `json:"issues"`
+	Records      []map[string]any `json:"records"`
+	Timestamp    string           `json:"timestamp"`
+}
+
+// HandleZoneLint parses zone file text and reports syntax errors and
+// semantic problems, along with the parsed records. Nothing is queried, so
+// the zone can be checked before it is published.
+func HandleZoneLint(_ context.Context, request mcp.CallToolRequest, _ *QueryConfig) (*mcp.CallToolResult, error) {
+	var params zoneLintParams
+	if err := request.BindArguments(&params); err != nil {
+		return nil, fmt.Errorf("failed to parse tool input: %w", utils.ParseJSONUnmarshalError(err))
+	}
+
+	// Validate required parameters
+	if strings.TrimSpace(params.Zone) == "" {
+		return nil, fmt.Errorf("parameter \"zone\" is required")
+	}
+
+	// Validate and normalize the origin, when given
+	var name *domainname.Name
+	origin := strings.TrimSpace(params.Origin)
+	if origin != "" {
+		var err error
+		if name, err = domainname.Parse(origin); err != nil {
+			return nil, err
+		}
+		origin = name.FQDN()
+	}
+
+	result := lintZone(params.Zone, origin)
+	result.DomainName = name
+	return resp.JSON(result)
+}
+
+// lintZone parses the zone text and checks the parsed records.
+func lintZone(text, origin string) *ZoneLintResponse {
+	result := &ZoneLintResponse{
+		Issues:    make([]ZoneLintIssue, 0),
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	records, syntaxIssues := parseZoneText(text, origin)
+	result.Issues = append(result.Issues, syntaxIssues...)
+
+	apex := zoneOrigin(origin, records)
+	if apex == "" {
+		result.Issues = append(result.Issues, ZoneLintIssue{
+			Severity: lintError,
+			Message:  "cannot determine the zone origin: add an SOA record, a $ORIGIN directive or the origin parameter",
+		})
+	} else {
+		result.Issues = append(result.Issues, lintRecords(apex, records)...)
+	}
+
+	result.Origin = apex
+	result.RecordCount = len(records)
+	result.Records = formatResourceRecords(records)
+
+	for _, issue := range result.Issues {
+		if issue.Severity == lintError {
+			result.ErrorCount++
+		} else {
+			result.WarningCount++
+		}
+	}
+	result.Valid = result.ErrorCount == 0
+
+	return result
+}
+
+// zoneOrigin returns the apex of a parsed zone: the given origin or, without
+// one, the owner of the first SOA record. It's empty when neither is known.
+func zoneOrigin(origin string, records []dns.RR) string {
+	if origin != "" {
+		return dns.CanonicalName(origin)
+	}
+
+	for _, rr := range records {
+		if rr.Header().Rrtype == dns.TypeSOA {
+			return dns.CanonicalName(rr.Header().Name)
+		}
+	}
+
+	return ""
+}
+
+// parseZoneText parses zone file text. The parser stops at the first syntax
+// error, so each failing line is reported, blanked out and the text parsed
+// again, to report several errors at once.
+func parseZoneText(text, origin string) ([]dns.RR, []ZoneLintIssue) {
+	lines := strings.Split(text, "\n")
+	var issues []ZoneLintIssue
+
+	for {
+		zp := dns.NewZoneParser(strings.NewReader(strings.Join(lines, "\n")), origin, "")
+
+		var records []dns.RR
+		for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+			records = append(records, rr)
+		}
+
+		err := zp.Err()
+		if err == nil {
+			return records, issues
+		}
+
+		issue := ZoneLintIssue{Severity: lintError, Message: err.Error()}
+		if match := parseErrorLine.FindStringSubmatch(err.Error()); match != nil {
+			issue.Line, _ = strconv.Atoi(match[1])
+			issue.Column, _ = strconv.Atoi(match[2])
+		}
+		issues = append(issues, issue)
+
+		// Stop when the failing line can't be skipped or there are too many errors
+		if issue.Line < 1 || issue.Line > len(lines) || strings.TrimSpace(lines[issue.Line-1]) == "" || len(issues) >= maxZoneLintSyntaxErrors {
+			return records, issues
+		}
+		lines[issue.Line-1] = ""
+	}
+}
+
+// lintRecords checks the parsed records of a zone for semantic problems.
+func lintRecords(apex string, records []dns.RR) []ZoneLintIssue {
+	var issues []ZoneLintIssue
+	issue := func(severity string, rr dns.RR, format string, args ...any) {
+		entry := ZoneLintIssue{Severity: severity, Message: fmt.Sprintf(format, args...)}
+		if rr != nil {
+			entry.Name = rr.Header().Name
+			entry.Type = dns.TypeToString[rr.Header().Rrtype]
+		}
+		issues = append(issues, entry)
+	}
+
+	// Index the records by owner name and by RRset
+	byName := make(map[string][]dns.RR)
+	var names []string
+	rrsets := make(map[string][]dns.RR)
+	var rrsetOrder []string
+	seen := make(map[string]bool)
+
+	for _, rr := range records {
+		owner := dns.CanonicalName(rr.Header().Name)
+
+		if !dns.IsSubDomain(apex, owner) {
+			issue(lintError, rr, "%s is outside of the zone %s", rr.Header().Name, apex)
+			continue
+		}
+
+		canonical := dns.Copy(rr)
+		canonical.Header().Name = owner
+		canonical.Header().Ttl = 0
+		if key := canonical.String(); seen[key] {
+			issue(lintWarning, rr, "duplicate record: %s", strings.Join(strings.Fields(rr.String()), " "))
+			continue
+		}
+		seen[canonical.String()] = true
+
+		if _, ok := byName[owner]; !ok {
+			names = append(names, owner)
+		}
+		byName[owner] = append(byName[owner], rr)
+
+		key := owner + " " + dns.TypeToString[rr.Header().Rrtype]
+		if _, ok := rrsets[key]; !ok {
+			rrsetOrder = append(rrsetOrder, key)
+		}
+		rrsets[key] = append(rrsets[key], rr)
+	}
+
+	hasType := func(name string, qtype uint16) bool {
+		for _, rr := range byName[dns.CanonicalName(name)] {
+			if rr.Header().Rrtype == qtype {
+				return true
+			}
+		}
+		return false
+	}
+
+	// The apex needs exactly one SOA and at least one NS record
+	soaCount := 0
+	for _, rr := range records {
+		if rr.Header().Rrtype != dns.TypeSOA {
+			continue
+		}
+
+		soaCount++
+		if !strings.EqualFold(rr.Header().Name, apex) {
+			issue(lintError, rr, "SOA record at %s is not at the zone apex %s", rr.Header().Name, apex)
+		}
+
+		if soa := rr.(*dns.SOA); soa.Minttl > maxNegativeCacheTTL {
+			issue(lintWarning, rr, "SOA minimum (negative caching TTL) of %d seconds is longer than a day; RFC 2308 recommends 1 to 3 hours", soa.Minttl)
+		}
+	}
+
+	switch {
+	case soaCount == 0:
+		issue(lintError, nil, "zone %s has no SOA record", apex)
+	case soaCount > 1:
+		issue(lintError, nil, "zone %s has %d SOA records, but exactly one is allowed", apex, soaCount)
+	}
+
+	if !hasType(apex, dns.TypeNS) {
+		issue(lintError, nil, "zone %s has no NS records at its apex", apex)
+	}
+
+	// Names below the apex with NS records are delegations
+	var delegations []string
+	for _, name := range names {
+		if name != apex && hasType(name, dns.TypeNS) {
+			delegations = append(delegations, name)
+		}
+	}
+	delegationOf := func(name string) string {
+		for _, delegation := range delegations {
+			if dns.IsSubDomain(delegation, name) {
+				return delegation
+			}
+		}
+		return ""
+	}
+
+	// A CNAME can't coexist with other data or sit at the zone apex
+	for _, name := range names {
+		rrs := byName[name]
+		cnames := 0
+		var others []string
+		for _, rr := range rrs {
+			switch rr.Header().Rrtype {
+			case dns.TypeCNAME:
+				cnames++
+			case dns.TypeRRSIG, dns.TypeNSEC, dns.TypeNSEC3:
+				// DNSSEC records may accompany a CNAME
+			default:
+				others = append(others, dns.TypeToString[rr.Header().Rrtype])
+			}
+		}
+
+		if cnames == 0 {
+			continue
+		}
+
+		cname := rrs[slices.IndexFunc(rrs, func(rr dns.RR) bool { return rr.Header().Rrtype == dns.TypeCNAME })]
+		if name == apex {
+			issue(lintError, cname, "CNAME at the zone apex %s conflicts with the SOA and NS records", name)
+		}
+		if cnames > 1 {
+			issue(lintError, cname, "%s has %d CNAME records, but only one is allowed", name, cnames)
+		}
+		if len(others) > 0 {
+			slices.Sort(others)
+			issue(lintError, cname, "CNAME at %s coexists with other data (%s)", name, strings.Join(slices.Compact(others), ", "))
+		}
+	}
+
+	// Records below a delegation are not authoritative, except for glue
+	glue := make(map[string]bool)
+	for _, delegation := range delegations {
+		for _, rr := range byName[delegation] {
+			if ns, ok := rr.(*dns.NS); ok {
+				glue[dns.CanonicalName(ns.Ns)] = true
+			}
+		}
+	}
+
+	for _, rr := range records {
+		owner := dns.CanonicalName(rr.Header().Name)
+		delegation := delegationOf(owner)
+		if delegation == "" {
+			continue
+		}
+
+		rrtype := rr.Header().Rrtype
+		switch {
+		case owner == delegation && (rrtype == dns.TypeNS || rrtype == dns.TypeDS || rrtype == dns.TypeNSEC || rrtype == dns.TypeRRSIG):
+		case (rrtype == dns.TypeA || rrtype == dns.TypeAAAA) && glue[owner]:
+		default:
+			issue(lintWarning, rr, "%s record at %s is hidden by the delegation of %s and will not be served", dns.TypeToString[rrtype], rr.Header().Name, delegation)
+		}
+	}
+
+	// Delegations to nameservers inside the delegated zone need glue
+	for _, delegation := range delegations {
+		for _, rr := range byName[delegation] {
+			ns, ok := rr.(*dns.NS)
+			if !ok {
+				continue
+			}
+
+			target := dns.CanonicalName(ns.Ns)
+			if dns.IsSubDomain(delegation, target) && !hasType(target, dns.TypeA) && !hasType(target, dns.TypeAAAA) {
+				issue(lintError, rr, "delegation of %s to %s needs glue, but there are no A or AAAA records for it", delegation, ns.Ns)
+			}
+		}
+	}
+
+	// MX, NS and SRV targets must not be aliases, and in-zone targets need addresses
+	for _, rr := range records {
+		var target string
+		switch rec := rr.(type) {
+		case *dns.MX:
+			target = rec.Mx
+		case *dns.NS:
+			target = rec.Ns
+		case *dns.SRV:
+			target = rec.Target
+		default:
+			continue
+		}
+
+		// A single dot means the service is explicitly unavailable
+		if target == "." || !dns.IsSubDomain(apex, dns.CanonicalName(target)) {
+			continue
+		}
+
+		if hasType(target, dns.TypeCNAME) {
+			issue(lintError, rr, "%s target %s is a CNAME, which is not allowed (RFC 2181 section 10.3)", dns.TypeToString[rr.Header().Rrtype], target)
+			continue
+		}
+
+		if delegationOf(dns.CanonicalName(target)) == "" && !hasType(target, dns.TypeA) && !hasType(target, dns.TypeAAAA) {
+			issue(lintWarning, rr, "%s target %s is in the zone but has no A or AAAA records", dns.TypeToString[rr.Header().Rrtype], target)
+		}
+	}
+
+	// Every record of an RRset should share the same TTL (RFC 2181 section 5.2)
+	for _, key := range rrsetOrder {
+		rrs := rrsets[key]
+		ttl := rrs[0].Header().Ttl
+
+		for _, rr := range rrs[1:] {
+			if rr.Header().Ttl != ttl {
+				issue(lintWarning, rrs[0], "records of the %s RRset have different TTLs", key)
+				break
+			}
+		}
+
+		switch {
+		case ttl < minReasonableTTL:
+			issue(lintWarning, rrs[0], "TTL of %d seconds for %s is very low and increases query load", ttl, key)
+		case ttl > maxReasonableTTL:
+			issue(lintWarning, rrs[0], "TTL of %d seconds for %s is longer than a week and slows down changes", ttl, key)
+		}
+	}
+
+	return issues
+}
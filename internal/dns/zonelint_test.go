@@ -0,0 +1,131 @@
+package dns
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lintMessages returns the messages of the issues with the given severity.
+func lintMessages(issues []ZoneLintIssue, severity string) []string {
+	var messages []string
+	for _, issue := range issues {
+		if issue.Severity == severity {
+			messages = append(messages, issue.Message)
+		}
+	}
+	return messages
+}
+
+func TestParseZoneText(t *testing.T) {
+	t.Run("valid zone", func(t *testing.T) {
+		records, issues := parseZoneText("$TTL 3600\n@ IN SOA ns1 hostmaster 1 7200 900 1209600 3600\n@ IN NS ns1\nns1 IN A 192.0.2.1\n", "example.com.")
+		assert.Empty(t, issues)
+		require.Len(t, records, 3)
+		assert.Equal(t, "ns1.example.com.", records[2].Header().Name)
+	})
+
+	t.Run("reports every syntax error with its position", func(t *testing.T) {
+		records, issues := parseZoneText("$TTL 3600\nwww IN A 192.0.2.1\nbad IN A not-an-address\nmx IN MX ten mail\nok IN A 192.0.2.2 extra\nlast IN AAAA 2001:db8::1\n", "example.com.")
+
+		require.Len(t, issues, 3)
+		assert.Equal(t, 3, issues[0].Line)
+		assert.Equal(t, 4, issues[1].Line)
+		assert.Equal(t, 5, issues[2].Line)
+		assert.Positive(t, issues[0].Column)
+		assert.Contains(t, issues[0].Message, "not-an-address")
+
+		// The lines without errors are still parsed
+		require.Len(t, records, 2)
+		assert.Equal(t, "last.example.com.", records[1].Header().Name)
+	})
+
+	t.Run("caps the number of errors", func(t *testing.T) {
+		text := "$TTL 3600\n" + strings.Repeat("bad IN A nope\n", maxZoneLintSyntaxErrors+5)
+		_, issues := parseZoneText(text, "example.com.")
+		assert.Len(t, issues, maxZoneLintSyntaxErrors)
+	})
+}
+
+func TestLintZone(t *testing.T) {
+	t.Run("clean zone", func(t *testing.T) {
+		zone := `$ORIGIN example.com.
+$TTL 3600
+@	IN SOA ns1 hostmaster 2024010101 7200 900 1209600 3600
+@	IN NS ns1
+@	IN NS ns2.example.net.
+@	IN MX 10 mail
+ns1	IN A 192.0.2.1
+mail	IN A 192.0.2.25
+www	IN CNAME @
+sub	IN NS ns.sub
+ns.sub	IN A 192.0.2.53
+`
+		result := lintZone(zone, "")
+		assert.Equal(t, "example.com.", result.Origin)
+		assert.Empty(t, result.Issues)
+		assert.True(t, result.Valid)
+		assert.Equal(t, 9, result.RecordCount)
+		require.Len(t, result.Records, 9)
+		assert.Equal(t, dns.TypeSOA, result.Records[0]["type"])
+	})
+
+	t.Run("semantic problems", func(t *testing.T) {
+		zone := `$TTL 3600
+@	IN NS ns1
+@	IN MX 10 alias
+@	IN CNAME elsewhere.example.net.
+ns1	IN A 192.0.2.1
+alias	IN CNAME www
+alias	IN TXT "not allowed"
+www	IN A 192.0.2.2
+www	IN A 192.0.2.2
+www	300 IN A 192.0.2.3
+srv	IN SRV 10 5 443 noaddr
+other.example.net. IN A 192.0.2.4
+sub	IN NS ns.sub
+sub	IN A 192.0.2.5
+fast	10 IN A 192.0.2.6
+`
+		result := lintZone(zone, "example.com")
+		assert.False(t, result.Valid)
+
+		errors := lintMessages(result.Issues, lintError)
+		assert.Contains(t, errors, "zone example.com. has no SOA record")
+		assert.Contains(t, errors, "other.example.net. is outside of the zone example.com.")
+		assert.Contains(t, errors, "CNAME at the zone apex example.com. conflicts with the SOA and NS records")
+		assert.Contains(t, errors, "CNAME at example.com. coexists with other data (MX, NS)")
+		assert.Contains(t, errors, "CNAME at alias.example.com. coexists with other data (TXT)")
+		assert.Contains(t, errors, "MX target alias.example.com. is a CNAME, which is not allowed (RFC 2181 section 10.3)")
+		assert.Contains(t, errors, "delegation of sub.example.com. to ns.sub.example.com. needs glue, but there are no A or AAAA records for it")
+		assert.Equal(t, len(errors), result.ErrorCount)
+
+		warnings := lintMessages(result.Issues, lintWarning)
+		assert.Contains(t, warnings, "duplicate record: www.example.com. 3600 IN A 192.0.2.2")
+		assert.Contains(t, warnings, "records of the www.example.com. A RRset have different TTLs")
+		assert.Contains(t, warnings, "SRV target noaddr.example.com. is in the zone but has no A or AAAA records")
+		assert.Contains(t, warnings, "A record at sub.example.com. is hidden by the delegation of sub.example.com. and will not be served")
+		assert.Contains(t, warnings, "TTL of 10 seconds for fast.example.com. A is very low and increases query load")
+		assert.Equal(t, len(warnings), result.WarningCount)
+	})
+
+	t.Run("misplaced soa and long negative caching", func(t *testing.T) {
+		zone := "$TTL 3600\nexample.com. IN SOA ns1.example.com. hostmaster.example.com. 1 7200 900 1209600 172800\nexample.com. IN NS ns1.example.com.\nns1.example.com. IN A 192.0.2.1\nsub.example.com. IN SOA ns1.example.com. hostmaster.example.com. 1 7200 900 1209600 3600\n"
+
+		result := lintZone(zone, "")
+		errors := lintMessages(result.Issues, lintError)
+		assert.Contains(t, errors, "zone example.com. has 2 SOA records, but exactly one is allowed")
+		assert.Contains(t, errors, "SOA record at sub.example.com. is not at the zone apex example.com.")
+		assert.Contains(t, lintMessages(result.Issues, lintWarning), "SOA minimum (negative caching TTL) of 172800 seconds is longer than a day; RFC 2308 recommends 1 to 3 hours")
+	})
+
+	t.Run("unknown origin", func(t *testing.T) {
+		result := lintZone("www.example.com. 3600 IN A 192.0.2.1\n", "")
+		require.Len(t, result.Issues, 1)
+		assert.Contains(t, result.Issues[0].Message, "cannot determine the zone origin")
+		assert.Equal(t, 1, result.RecordCount)
+	})
+}
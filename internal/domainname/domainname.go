@@ -0,0 +1,206 @@
+package domainname
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
+)
+
+// Limits on the length of a domain name in its ASCII form (RFC 1035 section
+// 2.3.4). The total excludes the trailing dot.
+const (
+	maxLabelLength = 63
+	maxNameLength  = 253
+)
+
+// profile converts names with the UTS #46 lookup rules, the way browsers do,
+// but keeps accepting underscores, which are common in service names such as
+// _dmarc.example.com or _443._tcp.example.com.
+var profile = idna.New(
+	idna.MapForLookup(),
+	idna.BidiRule(),
+	idna.Transitional(false),
+	idna.StrictDomainName(false),
+	idna.CheckHyphens(false),
+)
+
+// Name is a validated domain name, in its ASCII form (with A-labels, used to
+// query servers) and its Unicode form (with U-labels, used to display it).
+type Name struct {
+	Input             string   `json:"input"`
+	ASCII             string   `json:"ascii"`
+	Unicode           string   `json:"unicode"`
+	PublicSuffix      string   `json:"public_suffix,omitempty"`
+	RegistrableDomain string   `json:"registrable_domain,omitempty"`
+	Notes             []string `json:"notes,omitempty"`
+}
+
+// Parse validates and normalizes a domain name given by a user. Unicode names
+// are converted to punycode with UTS #46, and the name is lowercased and
+// stripped of its trailing dot. URLs, email addresses and host:port pairs
+// pasted by mistake are reduced to their host name, noting it in the result.
+func Parse(input string) (*Name, error) {
+	name := &Name{Input: input}
+
+	host := name.extractHost(strings.TrimSpace(input))
+	if host == "" {
+		return nil, fmt.Errorf("invalid domain name %q: the name is empty", input)
+	}
+
+	if ip := strings.TrimSuffix(strings.TrimPrefix(host, "["), "]"); net.ParseIP(ip) != nil {
+		return nil, fmt.Errorf("invalid domain name %q: %s is an IP address", input, ip)
+	}
+
+	ascii, err := profile.ToASCII(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid domain name %q: %w", input, err)
+	}
+	ascii = strings.TrimSuffix(ascii, ".")
+
+	if err := checkLabels(ascii); err != nil {
+		return nil, fmt.Errorf("invalid domain name %q: %w", input, err)
+	}
+
+	name.ASCII = ascii
+	name.Unicode = ascii
+	if unicode, err := profile.ToUnicode(ascii); err == nil {
+		name.Unicode = unicode
+	}
+
+	// A public suffix such as co.uk has no registrable domain of its own
+	name.PublicSuffix, _ = publicsuffix.PublicSuffix(ascii)
+	if registrable, err := publicsuffix.EffectiveTLDPlusOne(ascii); err == nil {
+		name.RegistrableDomain = registrable
+	}
+
+	return name, nil
+}
+
+// extractHost returns the host name part of the input, recording a note when
+// the input was a URL, an email address or a host:port pair.
+func (n *Name) extractHost(input string) string {
+	host := input
+
+	if strings.Contains(host, "://") {
+		if u, err := url.Parse(host); err == nil && u.Host != "" {
+			n.Notes = append(n.Notes, fmt.Sprintf("used the host name of the URL %q", input))
+			return u.Hostname()
+		}
+	}
+
+	// A slash starts a path after a host name, except in reverse names, since
+	// RFC 2317 classless ones such as 0/25.2.0.192.in-addr.arpa have one in a
+	// label
+	if before, _, found := strings.Cut(host, "/"); found && isDottedHostName(before) && !isReverseName(host) {
+		n.Notes = append(n.Notes, fmt.Sprintf("removed the path from %q", input))
+		host = before
+	}
+
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		n.Notes = append(n.Notes, fmt.Sprintf("used the domain of the email address %q", input))
+		host = host[i+1:]
+	}
+
+	// Only a single colon can separate a port, since IPv6 addresses have several
+	if before, port, found := strings.Cut(host, ":"); found && !strings.Contains(port, ":") {
+		if _, err := strconv.ParseUint(port, 10, 16); err == nil {
+			n.Notes = append(n.Notes, fmt.Sprintf("removed the port %s from %q", port, input))
+			host = before
+		}
+	}
+
+	return host
+}
+
+// isDottedHostName reports whether text is a host name with at least two
+// labels, optionally preceded by a user and followed by a port.
+func isDottedHostName(text string) bool {
+	if i := strings.LastIndex(text, "@"); i >= 0 {
+		text = text[i+1:]
+	}
+	if before, port, found := strings.Cut(text, ":"); found {
+		if _, err := strconv.ParseUint(port, 10, 16); err == nil {
+			text = before
+		}
+	}
+
+	labels := strings.Split(strings.TrimSuffix(text, "."), ".")
+	if len(labels) < 2 {
+		return false
+	}
+
+	for _, label := range labels {
+		// Non-ASCII characters are left for the IDNA conversion to check
+		invalid := strings.ContainsFunc(label, func(r rune) bool {
+			return r < utf8.RuneSelf && !isLabelRune(unicode.ToLower(r))
+		})
+		if label == "" || invalid {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isReverseName reports whether a name is under the IPv4 or IPv6 reverse
+// zones.
+func isReverseName(name string) bool {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	return strings.HasSuffix(name, ".in-addr.arpa") || strings.HasSuffix(name, ".ip6.arpa")
+}
+
+// checkLabels validates the labels of an ASCII name: their length, and their
+// characters, which are letters, digits, hyphens and underscores, plus
+// slashes in reverse names. A leftmost "*" label is accepted for wildcard
+// names.
+func checkLabels(ascii string) error {
+	if ascii == "" {
+		return fmt.Errorf("the name is empty")
+	}
+
+	if len(ascii) > maxNameLength {
+		return fmt.Errorf("the name is %d characters long, over the limit of %d", len(ascii), maxNameLength)
+	}
+
+	// RFC 2317 classless delegations put a slash in reverse zone labels
+	reverse := isReverseName(ascii)
+
+	for i, label := range strings.Split(ascii, ".") {
+		if label == "" {
+			return fmt.Errorf("the name has an empty label")
+		}
+		if len(label) > maxLabelLength {
+			return fmt.Errorf("the label %q is %d characters long, over the limit of %d", label, len(label), maxLabelLength)
+		}
+		if i == 0 && label == "*" {
+			continue
+		}
+
+		for _, r := range label {
+			if !isLabelRune(r) && (r != '/' || !reverse) {
+				return fmt.Errorf("the label %q has the character %q, which isn't allowed in domain names", label, r)
+			}
+		}
+	}
+
+	return nil
+}
+
+// FQDN returns the ASCII form of the name with a trailing dot, as used in DNS
+// messages.
+func (n *Name) FQDN() string {
+	return n.ASCII + "."
+}
+
+// isLabelRune reports whether a character may appear in the ASCII form of a
+// label.
+func isLabelRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_'
+}
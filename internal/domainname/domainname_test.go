@@ -0,0 +1,127 @@
+package domainname
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("internationalized names", func(t *testing.T) {
+		for _, input := range []string{"Bücher.de", "bücher.de.", "xn--bcher-kva.de", "BÜCHER.DE"} {
+			name, err := Parse(input)
+			require.NoError(t, err, input)
+			assert.Equal(t, input, name.Input)
+			assert.Equal(t, "xn--bcher-kva.de", name.ASCII)
+			assert.Equal(t, "bücher.de", name.Unicode)
+			assert.Equal(t, "xn--bcher-kva.de.", name.FQDN())
+			assert.Empty(t, name.Notes)
+		}
+
+		// Ideographic full stops separate labels too
+		name, err := Parse("食狮.中国。")
+		require.NoError(t, err)
+		assert.Equal(t, "xn--85x722f.xn--fiqs8s", name.ASCII)
+		assert.Equal(t, "食狮.中国", name.Unicode)
+	})
+
+	t.Run("pasted input", func(t *testing.T) {
+		tests := []struct {
+			input string
+			ascii string
+			note  string
+		}{
+			{"https://www.bücher.de:8443/path?q=1", "www.xn--bcher-kva.de", `used the host name of the URL "https://www.bücher.de:8443/path?q=1"`},
+			{"user@Example.COM", "example.com", `used the domain of the email address "user@Example.COM"`},
+			{"mailto:postmaster@example.com", "example.com", `used the domain of the email address "mailto:postmaster@example.com"`},
+			{"example.com:443", "example.com", `removed the port 443 from "example.com:443"`},
+			{"example.com/docs", "example.com", `removed the path from "example.com/docs"`},
+			{"example.com/index.html", "example.com", `removed the path from "example.com/index.html"`},
+			{"www.bücher.de/v1.2/index", "www.xn--bcher-kva.de", `removed the path from "www.bücher.de/v1.2/index"`},
+		}
+
+		for _, tt := range tests {
+			name, err := Parse(tt.input)
+			require.NoError(t, err, tt.input)
+			assert.Equal(t, tt.ascii, name.ASCII, tt.input)
+			assert.Equal(t, []string{tt.note}, name.Notes, tt.input)
+		}
+	})
+
+	t.Run("classless reverse names", func(t *testing.T) {
+		name, err := Parse("0/25.2.0.192.in-addr.arpa")
+		require.NoError(t, err)
+		assert.Equal(t, "0/25.2.0.192.in-addr.arpa", name.ASCII)
+		assert.Empty(t, name.Notes)
+
+		// Outside reverse zones a slash is still not allowed
+		_, err = Parse("0/25.example.com")
+		assert.ErrorContains(t, err, `the label "0/25" has the character '/'`)
+	})
+
+	t.Run("service and wildcard names", func(t *testing.T) {
+		for _, input := range []string{"_dmarc.example.com", "_25._tcp.mail.example.com", "*.example.com", "r3---sn-abc.googlevideo.com"} {
+			name, err := Parse(input)
+			require.NoError(t, err, input)
+			assert.Equal(t, input, name.ASCII)
+		}
+	})
+
+	t.Run("public suffix", func(t *testing.T) {
+		name, err := Parse("www.bbc.co.uk")
+		require.NoError(t, err)
+		assert.Equal(t, "co.uk", name.PublicSuffix)
+		assert.Equal(t, "bbc.co.uk", name.RegistrableDomain)
+
+		name, err = Parse("www.bücher.de")
+		require.NoError(t, err)
+		assert.Equal(t, "de", name.PublicSuffix)
+		assert.Equal(t, "xn--bcher-kva.de", name.RegistrableDomain)
+
+		// A public suffix has no registrable domain
+		name, err = Parse("co.uk")
+		require.NoError(t, err)
+		assert.Equal(t, "co.uk", name.PublicSuffix)
+		assert.Empty(t, name.RegistrableDomain)
+	})
+
+	t.Run("invalid names", func(t *testing.T) {
+		tests := []struct {
+			input string
+			err   string
+		}{
+			{"", `invalid domain name "": the name is empty`},
+			{"   ", `invalid domain name "   ": the name is empty`},
+			{"a..example.com", `invalid domain name "a..example.com": the name has an empty label`},
+			{".example.com", `invalid domain name ".example.com": the name has an empty label`},
+			{"192.0.2.1", `invalid domain name "192.0.2.1": 192.0.2.1 is an IP address`},
+			{"[2001:db8::1]", `invalid domain name "[2001:db8::1]": 2001:db8::1 is an IP address`},
+			{"http://[::1]:80/", `invalid domain name "http://[::1]:80/": ::1 is an IP address`},
+			{"exa$mple.com", `invalid domain name "exa$mple.com": the label "exa$mple" has the character '$', which isn't allowed in domain names`},
+			{"www.*.example.com", `invalid domain name "www.*.example.com": the label "*" has the character '*', which isn't allowed in domain names`},
+		}
+
+		for _, tt := range tests {
+			_, err := Parse(tt.input)
+			assert.EqualError(t, err, tt.err, tt.input)
+		}
+
+		_, err := Parse("xn--zz.com")
+		assert.ErrorContains(t, err, `invalid domain name "xn--zz.com": idna:`)
+	})
+
+	t.Run("length limits", func(t *testing.T) {
+		label := strings.Repeat("a", maxLabelLength)
+		_, err := Parse(label + ".com")
+		require.NoError(t, err)
+
+		_, err = Parse(label + "a.com")
+		assert.ErrorContains(t, err, "is 64 characters long, over the limit of 63")
+
+		long := strings.Repeat(label+".", 4) + "com"
+		_, err = Parse(long)
+		assert.ErrorContains(t, err, "the name is 259 characters long, over the limit of 253")
+	})
+}
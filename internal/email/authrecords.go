@@ -0,0 +1,128 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/miekg/dns"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/domainname"
+	resp "github.com/patrickdappollonio/mcp-domaintools/internal/response"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/utils"
+	"golang.org/x/sync/errgroup"
+)
+
+// authRecordsConcurrency limits how many record lookups are in flight at once.
+const authRecordsConcurrency = 8
+
+// emailAuthRecordsParams represents the parameters for email authentication
+// record inspection.
+type emailAuthRecordsParams struct {
+	Domain    string   `json:"domain"`
+	Selectors []string `json:"selectors"`
+}
+
+// EmailAuthRecordsResponse represents the complete email authentication
+// record inspection response.
+type EmailAuthRecordsResponse struct {
+	Domain          string           `json:"domain"`
+	DomainName      *domainname.Name `json:"domain_name,omitempty"`
+	DMARC           *DMARCRecord     `json:"dmarc"`
+	DKIM            []*DKIMRecord    `json:"dkim"`
+	ProbedSelectors []string         `json:"probed_selectors,omitempty"`
+	BIMI            *BIMIRecord      `json:"bimi"`
+	Timestamp       string           `json:"timestamp"`
+}
+
+// HandleEmailAuthRecords fetches and parses the DMARC, DKIM and BIMI records
+// of a domain.
+func HandleEmailAuthRecords(ctx context.Context, request mcp.CallToolRequest, config *Config) (*mcp.CallToolResult, error) {
+	var params emailAuthRecordsParams
+	if err := request.BindArguments(&params); err != nil {
+		return nil, fmt.Errorf("failed to parse tool input: %w", utils.ParseJSONUnmarshalError(err))
+	}
+
+	// Validate required parameters
+	if params.Domain == "" {
+		return nil, fmt.Errorf("parameter \"domain\" is required")
+	}
+
+	// Validate and normalize the domain name
+	name, err := domainname.Parse(params.Domain)
+	if err != nil {
+		return nil, err
+	}
+	domain := name.ASCII
+
+	var selectors []string
+	for _, selector := range params.Selectors {
+		selector = strings.Trim(strings.TrimSpace(selector), ".")
+		if selector == "" {
+			continue
+		}
+		if _, ok := dns.IsDomainName(selector); !ok {
+			return nil, fmt.Errorf("invalid DKIM selector %q", selector)
+		}
+		selectors = append(selectors, selector)
+	}
+
+	result := inspectAuthRecords(ctx, config.lookup(), domain, selectors)
+	result.DomainName = name
+	return resp.JSON(result)
+}
+
+// inspectAuthRecords looks up every record concurrently. When no selectors
+// are given, a list of common selectors is probed and only the ones that
+// exist are reported.
+func inspectAuthRecords(ctx context.Context, lookup lookupFunc, domain string, selectors []string) *EmailAuthRecordsResponse {
+	result := &EmailAuthRecordsResponse{
+		Domain:    domain,
+		DKIM:      make([]*DKIMRecord, 0),
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	probing := len(selectors) == 0
+	if probing {
+		selectors = defaultDKIMSelectors
+		result.ProbedSelectors = selectors
+	}
+
+	dkim := make([]*DKIMRecord, len(selectors))
+
+	var eg errgroup.Group
+	eg.SetLimit(authRecordsConcurrency)
+
+	eg.Go(func() error {
+		result.DMARC = fetchDMARC(ctx, lookup, domain)
+		if result.DMARC.Found {
+			verifyExternalDestinations(ctx, lookup, result.DMARC)
+		}
+		return nil
+	})
+
+	eg.Go(func() error {
+		result.BIMI = fetchBIMI(ctx, lookup, domain)
+		return nil
+	})
+
+	for i, selector := range selectors {
+		eg.Go(func() error {
+			dkim[i] = fetchDKIM(ctx, lookup, domain, selector)
+			return nil
+		})
+	}
+
+	_ = eg.Wait()
+
+	for _, record := range dkim {
+		if probing && !record.Found {
+			continue
+		}
+		result.DKIM = append(result.DKIM, record)
+	}
+
+	checkBIMIPolicy(result.BIMI, result.DMARC)
+	return result
+}
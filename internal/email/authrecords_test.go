@@ -0,0 +1,206 @@
+package email
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// txtRecord builds a zone file TXT record, splitting long values into
+// 255-byte character strings.
+func txtRecord(name, value string) string {
+	var parts []string
+	for len(value) > 255 {
+		parts = append(parts, fmt.Sprintf("%q", value[:255]))
+		value = value[255:]
+	}
+	parts = append(parts, fmt.Sprintf("%q", value))
+	return fmt.Sprintf("%s 300 IN TXT %s", name, strings.Join(parts, " "))
+}
+
+func TestHasVersionTag(t *testing.T) {
+	tests := []struct {
+		record  string
+		version string
+		want    bool
+	}{
+		{record: "v=DMARC1; p=reject", version: "DMARC1", want: true},
+		{record: "v=DMARC1", version: "DMARC1", want: true},
+		{record: " v = DMARC1 ;p=none", version: "DMARC1", want: true},
+		{record: "v=DMARC1; p", version: "DMARC1", want: true},
+		{record: "v=DMARC10; p=reject", version: "DMARC1", want: false},
+		{record: "v=DMARC1x", version: "DMARC1", want: false},
+		{record: "v=dmarc1; p=reject", version: "DMARC1", want: false},
+		{record: "p=reject; v=DMARC1", version: "DMARC1", want: false},
+		{record: "v=BIMI1; l=https://example.com/logo.svg", version: "BIMI1", want: true},
+		{record: "v=BIMI12; l=https://example.com/logo.svg", version: "BIMI1", want: false},
+		{record: "v=spf1 -all", version: "DMARC1", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.record, func(t *testing.T) {
+			assert.Equal(t, tt.want, hasVersionTag(tt.record, tt.version))
+		})
+	}
+}
+
+func TestParseDMARC(t *testing.T) {
+	t.Run("full record", func(t *testing.T) {
+		record := &DMARCRecord{
+			Record:       "v=DMARC1; p=reject; sp=quarantine; pct=50; adkim=s; rua=mailto:dmarc@example.com!10m,mailto:reports@example.net; ruf=mailto:forensic@example.com; fo=1:d; ri=3600",
+			policyDomain: "example.com",
+		}
+		parseDMARC(record)
+
+		assert.Empty(t, record.Errors)
+		assert.Equal(t, "reject", record.Policy)
+		assert.Equal(t, "quarantine", record.SubdomainPolicy)
+		assert.Equal(t, 50, record.Percentage)
+		assert.Equal(t, "strict", record.DKIMAlignment)
+		assert.Equal(t, "relaxed", record.SPFAlignment)
+		assert.Equal(t, []string{"1", "d"}, record.FailureOptions)
+		assert.Equal(t, 3600, record.ReportInterval)
+
+		require.Len(t, record.AggregateReports, 2)
+		assert.Equal(t, "dmarc@example.com", record.AggregateReports[0].Address)
+		assert.Equal(t, "10m", record.AggregateReports[0].MaxSize)
+		assert.False(t, record.AggregateReports[0].External)
+		assert.True(t, record.AggregateReports[1].External)
+		require.Len(t, record.FailureReports, 1)
+		assert.Contains(t, record.Warnings, "policy only applies to 50% of failing messages")
+	})
+
+	t.Run("invalid values", func(t *testing.T) {
+		record := &DMARCRecord{Record: "v=DMARC1; p=block; pct=150; aspf=x; rua=https://example.com/report", policyDomain: "example.com"}
+		parseDMARC(record)
+		assert.Len(t, record.Errors, 4)
+	})
+
+	t.Run("missing policy", func(t *testing.T) {
+		record := &DMARCRecord{Record: "v=DMARC1; rua=mailto:dmarc@example.com", policyDomain: "example.com"}
+		parseDMARC(record)
+		assert.Contains(t, record.Errors, "required tag \"p\" is missing")
+	})
+}
+
+func TestParseDKIM(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	rsaDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	require.NoError(t, err)
+
+	edKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	t.Run("rsa", func(t *testing.T) {
+		record := &DKIMRecord{Record: "v=DKIM1; k=rsa; h=sha256; p=" + base64.StdEncoding.EncodeToString(rsaDER)}
+		parseDKIM(record)
+		assert.Empty(t, record.Errors)
+		assert.Equal(t, "rsa", record.KeyType)
+		assert.Equal(t, 1024, record.KeySize)
+		assert.Len(t, record.Warnings, 1)
+	})
+
+	t.Run("pkcs1 rsa", func(t *testing.T) {
+		record := &DKIMRecord{Record: "p=" + base64.StdEncoding.EncodeToString(x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey))}
+		parseDKIM(record)
+		assert.Empty(t, record.Errors)
+		assert.Equal(t, 1024, record.KeySize)
+	})
+
+	t.Run("ed25519", func(t *testing.T) {
+		record := &DKIMRecord{Record: "v=DKIM1; k=ed25519; t=y:s; p=" + base64.StdEncoding.EncodeToString(edKey)}
+		parseDKIM(record)
+		assert.Empty(t, record.Errors)
+		assert.Equal(t, 256, record.KeySize)
+		assert.True(t, record.Testing)
+		assert.Equal(t, []string{"y", "s"}, record.Flags)
+	})
+
+	t.Run("revoked", func(t *testing.T) {
+		record := &DKIMRecord{Record: "v=DKIM1; p="}
+		parseDKIM(record)
+		assert.Empty(t, record.Errors)
+		assert.True(t, record.Revoked)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		record := &DKIMRecord{Record: "k=rsa; v=DKIM1; p=not-base64!"}
+		parseDKIM(record)
+		assert.Len(t, record.Errors, 2)
+	})
+}
+
+func TestInspectAuthRecords(t *testing.T) {
+	edKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	lookup := fakeZone(t,
+		`_dmarc.example.com. 300 IN TXT "v=DMARC1; p=reject; rua=mailto:dmarc@example.com,mailto:agg@reports.example.net,mailto:agg@unauthorized.example.org"`,
+		`example.com._report._dmarc.reports.example.net. 300 IN TXT "v=DMARC1"`,
+		txtRecord("mail._domainkey.example.com.", "v=DKIM1; k=ed25519; p="+base64.StdEncoding.EncodeToString(edKey)),
+		`default._bimi.example.com. 300 IN TXT "v=BIMI1; l=https://example.com/logo.svg; a=https://example.com/vmc.pem"`,
+		`_dmarc.example.org. 300 IN TXT "v=DMARC1; p=none"`,
+		`default._bimi.example.org. 300 IN TXT "v=BIMI1; l=http://example.org/logo.svg"`,
+		`_dmarc.example.net. 300 IN TXT "v=DMARC10; p=reject"`,
+		`default._bimi.example.net. 300 IN TXT "v=BIMI10; l=https://example.net/logo.svg"`,
+	)
+
+	t.Run("probes selectors and verifies destinations", func(t *testing.T) {
+		result := inspectAuthRecords(context.Background(), lookup, "example.com", nil)
+
+		require.True(t, result.DMARC.Found)
+		require.Len(t, result.DMARC.AggregateReports, 3)
+		assert.Nil(t, result.DMARC.AggregateReports[0].Authorized)
+		require.NotNil(t, result.DMARC.AggregateReports[1].Authorized)
+		assert.True(t, *result.DMARC.AggregateReports[1].Authorized)
+		require.NotNil(t, result.DMARC.AggregateReports[2].Authorized)
+		assert.False(t, *result.DMARC.AggregateReports[2].Authorized)
+		require.Len(t, result.DMARC.Errors, 1)
+		assert.Contains(t, result.DMARC.Errors[0], "unauthorized.example.org")
+
+		require.Len(t, result.DKIM, 1)
+		assert.Equal(t, "mail", result.DKIM[0].Selector)
+		assert.Equal(t, "ed25519", result.DKIM[0].KeyType)
+
+		assert.True(t, result.BIMI.Found)
+		assert.Empty(t, result.BIMI.Errors)
+	})
+
+	t.Run("inherits policy and reports missing selectors", func(t *testing.T) {
+		result := inspectAuthRecords(context.Background(), lookup, "mail.example.org", []string{"missing"})
+
+		assert.True(t, result.DMARC.Found)
+		assert.Equal(t, "example.org", result.DMARC.InheritedFrom)
+		assert.Equal(t, "none", result.DMARC.Policy)
+
+		require.Len(t, result.DKIM, 1)
+		assert.False(t, result.DKIM[0].Found)
+		assert.False(t, result.BIMI.Found)
+	})
+
+	t.Run("other versions are not records", func(t *testing.T) {
+		result := inspectAuthRecords(context.Background(), lookup, "example.net", []string{"mail"})
+
+		assert.False(t, result.DMARC.Found)
+		assert.Equal(t, []string{"no DMARC record found"}, result.DMARC.Errors)
+		assert.False(t, result.BIMI.Found)
+		assert.Empty(t, result.BIMI.Errors)
+	})
+
+	t.Run("bimi requires enforcement", func(t *testing.T) {
+		result := inspectAuthRecords(context.Background(), lookup, "example.org", []string{"mail"})
+
+		require.True(t, result.BIMI.Found)
+		assert.Len(t, result.BIMI.Errors, 2)
+	})
+}
@@ -0,0 +1,125 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// bimiVersion is the version tag that starts every BIMI record.
+const bimiVersion = "BIMI1"
+
+// BIMIRecord represents the parsed BIMI assertion record of a domain.
+type BIMIRecord struct {
+	Name         string            `json:"name"`
+	Found        bool              `json:"found"`
+	Record       string            `json:"record,omitempty"`
+	Tags         map[string]string `json:"tags,omitempty"`
+	LogoURL      string            `json:"logo_url,omitempty"`
+	AuthorityURL string            `json:"authority_url,omitempty"`
+	Declined     bool              `json:"declined,omitempty"`
+	Errors       []string          `json:"errors,omitempty"`
+	Warnings     []string          `json:"warnings,omitempty"`
+}
+
+// fetchBIMI fetches and parses the default BIMI assertion record of a domain.
+func fetchBIMI(ctx context.Context, lookup lookupFunc, domain string) *BIMIRecord {
+	result := &BIMIRecord{Name: "default._bimi." + domain}
+
+	txt, err := lookupTXT(ctx, lookup, result.Name)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+
+	var records []string
+	for _, record := range txt.Records {
+		if hasVersionTag(record, bimiVersion) {
+			records = append(records, record)
+		}
+	}
+
+	switch len(records) {
+	case 0:
+		return result
+	case 1:
+		result.Found = true
+		result.Record = records[0]
+		parseBIMI(result)
+	default:
+		result.Found = true
+		result.Errors = append(result.Errors, fmt.Sprintf("found %d BIMI records, but only one is allowed", len(records)))
+	}
+
+	return result
+}
+
+// parseBIMI parses the tags of a BIMI assertion record.
+func parseBIMI(result *BIMIRecord) {
+	tags, err := parseTagList(result.Record)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+	}
+	result.Tags = tagMap(tags)
+
+	if len(tags) == 0 || tags[0].Name != "v" || tags[0].Value != bimiVersion {
+		result.Errors = append(result.Errors, fmt.Sprintf("the record must start with \"v=%s\"", bimiVersion))
+	}
+
+	result.LogoURL = result.Tags["l"]
+	result.AuthorityURL = result.Tags["a"]
+
+	// An empty logo and authority declares that the domain opts out of BIMI
+	if result.LogoURL == "" && result.AuthorityURL == "" {
+		result.Declined = true
+		return
+	}
+
+	if result.LogoURL != "" {
+		if err := validateHTTPSURL(result.LogoURL); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("invalid logo URL: %v", err))
+		} else if !strings.HasSuffix(strings.ToLower(result.LogoURL), ".svg") {
+			result.Warnings = append(result.Warnings, "the logo should be an SVG Tiny PS document with an .svg extension")
+		}
+	}
+
+	if result.AuthorityURL == "" {
+		result.Warnings = append(result.Warnings, "no authority evidence (\"a\" tag): most mailbox providers require a Verified Mark Certificate to display the logo")
+	} else if err := validateHTTPSURL(result.AuthorityURL); err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("invalid authority URL: %v", err))
+	}
+}
+
+// checkBIMIPolicy reports whether the DMARC policy is strict enough for
+// BIMI, which requires enforcement on every message.
+func checkBIMIPolicy(result *BIMIRecord, dmarc *DMARCRecord) {
+	if !result.Found || result.Declined {
+		return
+	}
+
+	switch {
+	case !dmarc.Found:
+		result.Errors = append(result.Errors, "BIMI requires a DMARC policy, but none was found")
+	case dmarc.Policy != "quarantine" && dmarc.Policy != "reject":
+		result.Errors = append(result.Errors, fmt.Sprintf("BIMI requires a DMARC policy of quarantine or reject, but the policy is %q", dmarc.Policy))
+	case dmarc.Percentage < 100:
+		result.Errors = append(result.Errors, fmt.Sprintf("BIMI requires the DMARC policy to apply to all messages, but pct=%d", dmarc.Percentage))
+	case dmarc.SubdomainPolicy == "none":
+		result.Warnings = append(result.Warnings, "the DMARC subdomain policy is \"none\", which some mailbox providers reject for BIMI")
+	}
+}
+
+// validateHTTPSURL checks that a string is an absolute HTTPS URL.
+func validateHTTPSURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+
+	if u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("%q must be an absolute https:// URL", raw)
+	}
+
+	return nil
+}
@@ -0,0 +1,194 @@
+package email
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// dkimVersion is the only defined value of the DKIM "v" tag.
+const dkimVersion = "DKIM1"
+
+// Minimum RSA key sizes: RFC 8301 section 3.2 requires verifiers to reject
+// keys shorter than 1024 bits, and 2048 bits is the recommended size.
+const (
+	dkimMinimumRSABits     = 1024
+	dkimRecommendedRSABits = 2048
+)
+
+// defaultDKIMSelectors lists commonly used selectors that are probed when
+// no selectors are given.
+var defaultDKIMSelectors = []string{
+	"default", "dkim", "mail", "google", "selector1", "selector2",
+	"k1", "k2", "s1", "s2", "smtp", "mxvault",
+}
+
+// DKIMRecord represents the parsed DKIM public key record of a selector.
+type DKIMRecord struct {
+	Selector       string            `json:"selector"`
+	Name           string            `json:"name"`
+	Found          bool              `json:"found"`
+	Record         string            `json:"record,omitempty"`
+	Tags           map[string]string `json:"tags,omitempty"`
+	KeyType        string            `json:"key_type,omitempty"`
+	KeySize        int               `json:"key_size,omitempty"`
+	Revoked        bool              `json:"revoked,omitempty"`
+	HashAlgorithms []string          `json:"hash_algorithms,omitempty"`
+	ServiceTypes   []string          `json:"service_types,omitempty"`
+	Flags          []string          `json:"flags,omitempty"`
+	Testing        bool              `json:"testing,omitempty"`
+	Notes          string            `json:"notes,omitempty"`
+	Errors         []string          `json:"errors,omitempty"`
+	Warnings       []string          `json:"warnings,omitempty"`
+}
+
+// fetchDKIM fetches and parses the DKIM key record of a selector.
+func fetchDKIM(ctx context.Context, lookup lookupFunc, domain, selector string) *DKIMRecord {
+	result := &DKIMRecord{
+		Selector: selector,
+		Name:     fmt.Sprintf("%s._domainkey.%s", selector, domain),
+	}
+
+	txt, err := lookupTXT(ctx, lookup, result.Name)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+
+	switch len(txt.Records) {
+	case 0:
+		return result
+	case 1:
+		result.Found = true
+		result.Record = txt.Records[0]
+		parseDKIM(result)
+	default:
+		result.Found = true
+		result.Errors = append(result.Errors, fmt.Sprintf("found %d TXT records, but a selector must publish exactly one key record", len(txt.Records)))
+	}
+
+	return result
+}
+
+// parseDKIM parses the tags of a DKIM key record (RFC 6376 section 3.6.1)
+// and decodes its public key.
+func parseDKIM(result *DKIMRecord) {
+	tags, err := parseTagList(result.Record)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+	}
+	result.Tags = tagMap(tags)
+
+	if version, ok := result.Tags["v"]; ok {
+		if version != dkimVersion {
+			result.Errors = append(result.Errors, fmt.Sprintf("invalid version %q: must be %s", version, dkimVersion))
+		}
+		if tags[0].Name != "v" {
+			result.Errors = append(result.Errors, "the \"v\" tag must be the first tag of the record")
+		}
+	}
+
+	result.KeyType = "rsa"
+	if k, ok := result.Tags["k"]; ok {
+		result.KeyType = strings.ToLower(k)
+	}
+
+	result.HashAlgorithms = splitTagValues(result.Tags["h"], ":")
+	result.ServiceTypes = splitTagValues(result.Tags["s"], ":")
+	result.Flags = splitTagValues(result.Tags["t"], ":")
+	result.Notes = result.Tags["n"]
+
+	for _, flag := range result.Flags {
+		if flag == "y" {
+			result.Testing = true
+			result.Warnings = append(result.Warnings, "the key is in testing mode (t=y): verifiers may treat signed messages as unsigned")
+		}
+	}
+
+	// The public key may contain folding whitespace
+	publicKey, ok := result.Tags["p"]
+	if !ok {
+		result.Errors = append(result.Errors, "required tag \"p\" is missing")
+		return
+	}
+
+	publicKey = strings.Join(strings.Fields(publicKey), "")
+	if publicKey == "" {
+		result.Revoked = true
+		result.Warnings = append(result.Warnings, "the key has been revoked (empty \"p\" tag)")
+		return
+	}
+
+	der, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("public key is not valid base64: %v", err))
+		return
+	}
+
+	switch result.KeyType {
+	case "rsa":
+		size, err := rsaKeySize(der)
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			return
+		}
+		result.KeySize = size
+
+		switch {
+		case size < dkimMinimumRSABits:
+			result.Errors = append(result.Errors, fmt.Sprintf("RSA key is %d bits: verifiers must reject keys shorter than %d bits", size, dkimMinimumRSABits))
+		case size < dkimRecommendedRSABits:
+			result.Warnings = append(result.Warnings, fmt.Sprintf("RSA key is %d bits: %d bits or more is recommended", size, dkimRecommendedRSABits))
+		}
+
+	case "ed25519":
+		if len(der) != ed25519.PublicKeySize {
+			result.Errors = append(result.Errors, fmt.Sprintf("Ed25519 public key must be %d bytes, got %d", ed25519.PublicKeySize, len(der)))
+			return
+		}
+		result.KeySize = ed25519.PublicKeySize * 8
+
+	default:
+		result.Errors = append(result.Errors, fmt.Sprintf("unknown key type %q: must be rsa or ed25519", result.KeyType))
+	}
+
+	for _, algorithm := range result.HashAlgorithms {
+		if algorithm == "sha1" {
+			result.Warnings = append(result.Warnings, "the key allows SHA-1 signatures, which verifiers must no longer accept (RFC 8301)")
+		}
+	}
+}
+
+// rsaKeySize returns the modulus size of an RSA public key in either
+// SubjectPublicKeyInfo or PKCS#1 form.
+func rsaKeySize(der []byte) (int, error) {
+	if key, err := x509.ParsePKIXPublicKey(der); err == nil {
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return 0, fmt.Errorf("public key is a %T, not an RSA key", key)
+		}
+		return rsaKey.N.BitLen(), nil
+	}
+
+	key, err := x509.ParsePKCS1PublicKey(der)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse RSA public key: %w", err)
+	}
+	return key.N.BitLen(), nil
+}
+
+// splitTagValues splits a tag value on a separator, trimming whitespace and
+// dropping empty entries.
+func splitTagValues(value, sep string) []string {
+	var values []string
+	for _, v := range strings.Split(value, sep) {
+		if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
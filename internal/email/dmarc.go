@@ -0,0 +1,336 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// dmarcVersion is the version tag that starts every DMARC record.
+const dmarcVersion = "DMARC1"
+
+// dmarcPolicies lists the valid values of the "p", "sp" and "np" tags.
+var dmarcPolicies = []string{"none", "quarantine", "reject"}
+
+// dmarcFailureOptions lists the valid values of the "fo" tag.
+var dmarcFailureOptions = []string{"0", "1", "d", "s"}
+
+// dmarcKnownTags lists the tags defined by RFC 7489 and DMARCbis.
+var dmarcKnownTags = []string{"v", "p", "sp", "np", "pct", "rua", "ruf", "adkim", "aspf", "fo", "rf", "ri", "t", "psd"}
+
+// dmarcURISize matches the optional size limit suffix of a report URI,
+// such as "!10m" (RFC 7489 section 6.2).
+var dmarcURISize = regexp.MustCompile(`^(.*)!(\d+[kmgt]?)$`)
+
+// DMARCReportURI represents a single aggregate or failure report destination.
+type DMARCReportURI struct {
+	URI                 string `json:"uri"`
+	Address             string `json:"address,omitempty"`
+	MaxSize             string `json:"max_size,omitempty"`
+	External            bool   `json:"external"`
+	Authorized          *bool  `json:"authorized,omitempty"`
+	AuthorizationName   string `json:"authorization_name,omitempty"`
+	AuthorizationRecord string `json:"authorization_record,omitempty"`
+	Error               string `json:"error,omitempty"`
+}
+
+// DMARCRecord represents the parsed DMARC policy of a domain.
+type DMARCRecord struct {
+	Name             string            `json:"name"`
+	Found            bool              `json:"found"`
+	Record           string            `json:"record,omitempty"`
+	InheritedFrom    string            `json:"inherited_from,omitempty"`
+	Tags             map[string]string `json:"tags,omitempty"`
+	Policy           string            `json:"policy,omitempty"`
+	SubdomainPolicy  string            `json:"subdomain_policy,omitempty"`
+	Percentage       int               `json:"percentage"`
+	DKIMAlignment    string            `json:"dkim_alignment,omitempty"`
+	SPFAlignment     string            `json:"spf_alignment,omitempty"`
+	AggregateReports []DMARCReportURI  `json:"aggregate_reports,omitempty"`
+	FailureReports   []DMARCReportURI  `json:"failure_reports,omitempty"`
+	FailureOptions   []string          `json:"failure_options,omitempty"`
+	ReportFormat     string            `json:"report_format,omitempty"`
+	ReportInterval   int               `json:"report_interval,omitempty"`
+	Errors           []string          `json:"errors,omitempty"`
+	Warnings         []string          `json:"warnings,omitempty"`
+
+	// policyDomain is the domain the record was published for
+	policyDomain string
+}
+
+// fetchDMARC looks up the DMARC policy of a domain, falling back to the
+// organizational domain as described in RFC 7489 section 6.6.3.
+func fetchDMARC(ctx context.Context, lookup lookupFunc, domain string) *DMARCRecord {
+	result := lookupDMARC(ctx, lookup, domain)
+	if result.Found || len(result.Errors) > 0 {
+		return result
+	}
+
+	org := organizationalDomain(domain)
+	if org == domain {
+		result.Errors = append(result.Errors, "no DMARC record found")
+		return result
+	}
+
+	inherited := lookupDMARC(ctx, lookup, org)
+	if !inherited.Found {
+		result.Errors = append(result.Errors, fmt.Sprintf("no DMARC record found at %s or at the organizational domain %s", result.Name, org))
+		return result
+	}
+
+	inherited.InheritedFrom = org
+	return inherited
+}
+
+// lookupDMARC fetches and parses the DMARC record published for a domain.
+func lookupDMARC(ctx context.Context, lookup lookupFunc, domain string) *DMARCRecord {
+	result := &DMARCRecord{
+		Name:         "_dmarc." + domain,
+		policyDomain: domain,
+	}
+
+	txt, err := lookupTXT(ctx, lookup, result.Name)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+
+	var records []string
+	for _, record := range txt.Records {
+		if hasVersionTag(record, dmarcVersion) {
+			records = append(records, record)
+		}
+	}
+
+	switch len(records) {
+	case 0:
+		return result
+	case 1:
+		result.Found = true
+		result.Record = records[0]
+		parseDMARC(result)
+	default:
+		result.Found = true
+		result.Errors = append(result.Errors, fmt.Sprintf("found %d DMARC records, but only one is allowed; receivers will ignore all of them", len(records)))
+	}
+
+	return result
+}
+
+// parseDMARC parses the tags of a DMARC record and validates their values.
+func parseDMARC(result *DMARCRecord) {
+	tags, err := parseTagList(result.Record)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+	}
+	result.Tags = tagMap(tags)
+
+	if len(tags) == 0 || tags[0].Name != "v" || tags[0].Value != dmarcVersion {
+		result.Errors = append(result.Errors, fmt.Sprintf("the record must start with \"v=%s\"", dmarcVersion))
+	}
+
+	for _, t := range tags {
+		if !slices.Contains(dmarcKnownTags, t.Name) {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("unknown tag %q is ignored", t.Name))
+		}
+	}
+
+	// The policy tag is required and must follow the version tag
+	policy, ok := result.Tags["p"]
+	switch {
+	case !ok:
+		result.Errors = append(result.Errors, "required tag \"p\" is missing")
+	case !slices.Contains(dmarcPolicies, strings.ToLower(policy)):
+		result.Errors = append(result.Errors, fmt.Sprintf("invalid policy %q: must be none, quarantine or reject", policy))
+	default:
+		result.Policy = strings.ToLower(policy)
+		if len(tags) > 1 && tags[1].Name != "p" {
+			result.Warnings = append(result.Warnings, "the \"p\" tag should immediately follow the version tag")
+		}
+	}
+
+	result.SubdomainPolicy = result.Policy
+	if sp, ok := result.Tags["sp"]; ok {
+		if slices.Contains(dmarcPolicies, strings.ToLower(sp)) {
+			result.SubdomainPolicy = strings.ToLower(sp)
+		} else {
+			result.Errors = append(result.Errors, fmt.Sprintf("invalid subdomain policy %q: must be none, quarantine or reject", sp))
+		}
+	}
+
+	result.Percentage = 100
+	if pct, ok := result.Tags["pct"]; ok {
+		value, err := strconv.Atoi(pct)
+		if err != nil || value < 0 || value > 100 {
+			result.Errors = append(result.Errors, fmt.Sprintf("invalid percentage %q: must be an integer between 0 and 100", pct))
+		} else {
+			result.Percentage = value
+		}
+	}
+
+	result.DKIMAlignment = parseAlignment(result, "adkim")
+	result.SPFAlignment = parseAlignment(result, "aspf")
+
+	result.FailureOptions = []string{"0"}
+	if fo, ok := result.Tags["fo"]; ok {
+		result.FailureOptions = nil
+		for _, option := range strings.Split(fo, ":") {
+			option = strings.TrimSpace(option)
+			if !slices.Contains(dmarcFailureOptions, option) {
+				result.Errors = append(result.Errors, fmt.Sprintf("invalid failure reporting option %q: must be 0, 1, d or s", option))
+				continue
+			}
+			result.FailureOptions = append(result.FailureOptions, option)
+		}
+	}
+
+	result.ReportFormat = "afrf"
+	if rf, ok := result.Tags["rf"]; ok {
+		result.ReportFormat = strings.ToLower(rf)
+		if result.ReportFormat != "afrf" {
+			result.Errors = append(result.Errors, fmt.Sprintf("invalid report format %q: only afrf is defined", rf))
+		}
+	}
+
+	result.ReportInterval = 86400
+	if ri, ok := result.Tags["ri"]; ok {
+		value, err := strconv.ParseUint(ri, 10, 32)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("invalid report interval %q: must be a number of seconds", ri))
+		} else {
+			result.ReportInterval = int(value)
+		}
+	}
+
+	if rua, ok := result.Tags["rua"]; ok {
+		result.AggregateReports = parseReportURIs(result, "rua", rua)
+	}
+
+	if ruf, ok := result.Tags["ruf"]; ok {
+		result.FailureReports = parseReportURIs(result, "ruf", ruf)
+	}
+
+	// Point out configurations that provide little or no protection
+	if result.Policy == "none" {
+		result.Warnings = append(result.Warnings, "policy is \"none\": failing messages are only monitored, not rejected or quarantined")
+	}
+
+	if result.Percentage < 100 {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("policy only applies to %d%% of failing messages", result.Percentage))
+	}
+
+	if len(result.AggregateReports) == 0 {
+		result.Warnings = append(result.Warnings, "no aggregate report destination (\"rua\") is configured")
+	}
+}
+
+// parseAlignment validates an "adkim" or "aspf" tag and returns the
+// alignment mode it selects.
+func parseAlignment(result *DMARCRecord, name string) string {
+	value, ok := result.Tags[name]
+	if !ok {
+		return "relaxed"
+	}
+
+	switch strings.ToLower(value) {
+	case "r":
+		return "relaxed"
+	case "s":
+		return "strict"
+	}
+
+	result.Errors = append(result.Errors, fmt.Sprintf("invalid alignment mode %q for %q: must be r or s", value, name))
+	return ""
+}
+
+// parseReportURIs parses the comma-separated list of report URIs of a
+// "rua" or "ruf" tag.
+func parseReportURIs(result *DMARCRecord, name, value string) []DMARCReportURI {
+	var uris []DMARCReportURI
+
+	for _, raw := range strings.Split(value, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+
+		uri := DMARCReportURI{URI: raw}
+		if matches := dmarcURISize.FindStringSubmatch(raw); matches != nil {
+			raw = matches[1]
+			uri.MaxSize = matches[2]
+		}
+
+		parsed, err := url.Parse(raw)
+		switch {
+		case err != nil:
+			uri.Error = fmt.Sprintf("invalid URI: %v", err)
+		case !strings.EqualFold(parsed.Scheme, "mailto"):
+			uri.Error = fmt.Sprintf("unsupported URI scheme %q: only mailto is widely supported", parsed.Scheme)
+		default:
+			address, err := mail.ParseAddress(parsed.Opaque)
+			if err != nil {
+				uri.Error = fmt.Sprintf("invalid email address %q", parsed.Opaque)
+				break
+			}
+			uri.Address = address.Address
+
+			destination := strings.ToLower(address.Address[strings.LastIndexByte(address.Address, '@')+1:])
+			uri.External = organizationalDomain(destination) != organizationalDomain(result.policyDomain)
+		}
+
+		if uri.Error != "" {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %s", name, uri.Error))
+		}
+
+		uris = append(uris, uri)
+	}
+
+	return uris
+}
+
+// verifyExternalDestinations checks that every report destination outside
+// the policy's organizational domain has authorized receiving reports for
+// it (RFC 7489 section 7.1).
+func verifyExternalDestinations(ctx context.Context, lookup lookupFunc, result *DMARCRecord) {
+	verify := func(uris []DMARCReportURI) {
+		for i := range uris {
+			uri := &uris[i]
+			if !uri.External {
+				continue
+			}
+
+			destination := uri.Address[strings.LastIndexByte(uri.Address, '@')+1:]
+			uri.AuthorizationName = fmt.Sprintf("%s._report._dmarc.%s", result.policyDomain, strings.ToLower(destination))
+
+			authorized := false
+			uri.Authorized = &authorized
+
+			txt, err := lookupTXT(ctx, lookup, uri.AuthorizationName)
+			if err != nil {
+				uri.Error = err.Error()
+				result.Warnings = append(result.Warnings, fmt.Sprintf("could not verify that %s accepts reports: %v", destination, err))
+				continue
+			}
+
+			for _, record := range txt.Records {
+				if hasVersionTag(record, dmarcVersion) {
+					authorized = true
+					uri.AuthorizationRecord = record
+					break
+				}
+			}
+
+			if !authorized {
+				result.Errors = append(result.Errors, fmt.Sprintf("external report destination %s has not authorized reports for %s: no DMARC1 record at %s", destination, result.policyDomain, uri.AuthorizationName))
+			}
+		}
+	}
+
+	verify(result.AggregateReports)
+	verify(result.FailureReports)
+}
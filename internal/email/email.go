@@ -0,0 +1,158 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	internaldns "github.com/patrickdappollonio/mcp-domaintools/internal/dns"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/domainname"
+)
+
+// Config holds email authentication check configuration.
+type Config struct {
+	QueryConfig *internaldns.QueryConfig
+	HTTPTimeout time.Duration
+}
+
+// lookupFunc resolves a name and record type to a DNS response. It allows the
+// checks in this package to be exercised without a network.
+type lookupFunc func(ctx context.Context, name string, qtype uint16) (*dns.Msg, error)
+
+// lookup returns a lookupFunc that queries the OS-defined DNS servers.
+func (c *Config) lookup() lookupFunc {
+	return func(ctx context.Context, name string, qtype uint16) (*dns.Msg, error) {
+		return internaldns.Lookup(ctx, name, qtype, c.QueryConfig)
+	}
+}
+
+// dnsError reports a lookup that failed because of a DNS or network error,
+// as opposed to a name that simply has no records.
+type dnsError struct {
+	Name  string
+	Type  uint16
+	Rcode int
+	Err   error
+}
+
+func (e *dnsError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s query for %s failed: %v", dns.TypeToString[e.Type], e.Name, e.Err)
+	}
+	return fmt.Sprintf("%s query for %s failed with %s", dns.TypeToString[e.Type], e.Name, dns.RcodeToString[e.Rcode])
+}
+
+func (e *dnsError) Unwrap() error {
+	return e.Err
+}
+
+// query resolves a name and returns the response when it is either a success
+// or a non-existent domain. Any other outcome is returned as a *dnsError.
+func query(ctx context.Context, lookup lookupFunc, name string, qtype uint16) (*dns.Msg, error) {
+	response, err := lookup(ctx, name, qtype)
+	if err != nil {
+		return nil, &dnsError{Name: name, Type: qtype, Err: err}
+	}
+
+	if response.Rcode != dns.RcodeSuccess && response.Rcode != dns.RcodeNameError {
+		return nil, &dnsError{Name: name, Type: qtype, Rcode: response.Rcode}
+	}
+
+	return response, nil
+}
+
+// txtResult holds the TXT records found at a name.
+type txtResult struct {
+	Records []string
+	Rcode   int
+}
+
+// lookupTXT returns the TXT records at a name. The character strings of each
+// record are concatenated without separators, as RFC 7208 section 3.3 and
+// RFC 6376 section 3.6.2.2 require.
+func lookupTXT(ctx context.Context, lookup lookupFunc, name string) (*txtResult, error) {
+	response, err := query(ctx, lookup, name, dns.TypeTXT)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &txtResult{Rcode: response.Rcode}
+	for _, rr := range response.Answer {
+		if txt, ok := rr.(*dns.TXT); ok {
+			result.Records = append(result.Records, strings.Join(txt.Txt, ""))
+		}
+	}
+
+	return result, nil
+}
+
+// tag is a single name and value pair of a tag-value list.
+type tag struct {
+	Name  string
+	Value string
+}
+
+// parseTagList parses a semicolon-separated tag-value list, as used by DKIM
+// (RFC 6376 section 3.2), DMARC, BIMI, MTA-STS and TLS-RPT records. Tags are
+// returned in order, and a duplicate tag is an error.
+func parseTagList(record string) ([]tag, error) {
+	var tags []tag
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(record, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return tags, fmt.Errorf("malformed tag %q: expected name=value", part)
+		}
+
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return tags, fmt.Errorf("malformed tag %q: missing tag name", part)
+		}
+
+		if seen[name] {
+			return tags, fmt.Errorf("duplicate tag %q", name)
+		}
+		seen[name] = true
+
+		tags = append(tags, tag{Name: name, Value: strings.TrimSpace(value)})
+	}
+
+	return tags, nil
+}
+
+// tagMap converts a list of tags into a map of names to values.
+func tagMap(tags []tag) map[string]string {
+	m := make(map[string]string, len(tags))
+	for _, t := range tags {
+		m[t.Name] = t.Value
+	}
+	return m
+}
+
+// hasVersionTag reports whether the first tag of a tag-value list is a "v"
+// tag with exactly the given version. It's how a DMARC, BIMI, MTA-STS or
+// TLS-RPT record is told apart from the other TXT records at its name, so
+// "v=DMARC10" isn't mistaken for a DMARC1 record.
+func hasVersionTag(record, version string) bool {
+	first, _, _ := strings.Cut(record, ";")
+	tags, err := parseTagList(first)
+	return err == nil && len(tags) == 1 && tags[0].Name == "v" && tags[0].Value == version
+}
+
+// organizationalDomain returns the registrable domain of a name using the
+// public suffix list, or the name itself when it cannot be determined.
+func organizationalDomain(domain string) string {
+	name, err := domainname.Parse(domain)
+	if err != nil || name.RegistrableDomain == "" {
+		return domain
+	}
+	return name.RegistrableDomain
+}
@@ -0,0 +1,481 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/miekg/dns"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/domainname"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/http_ping"
+	resp "github.com/patrickdappollonio/mcp-domaintools/internal/response"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/utils"
+)
+
+// Version tags of MTA-STS (RFC 8461) and TLS-RPT (RFC 8460) records.
+const (
+	mtaSTSVersion = "STSv1"
+	tlsRPTVersion = "TLSRPTv1"
+)
+
+// mtaSTSMaxPolicySize is the largest policy body that is read, as RFC 8461
+// section 3.3 allows senders to reject policies larger than 64 KiB.
+const mtaSTSMaxPolicySize = 64 * 1024
+
+// mtaSTSMaxAge is the largest allowed "max_age" value, about one year.
+const mtaSTSMaxAge = 31557600
+
+// mtaSTSPolicyID matches a valid "id" value of an MTA-STS TXT record.
+var mtaSTSPolicyID = regexp.MustCompile(`^[a-zA-Z0-9]{1,32}$`)
+
+// mtaSTSModes lists the valid values of the "mode" policy key.
+var mtaSTSModes = []string{"enforce", "testing", "none"}
+
+// mtaSTSParams represents the parameters for MTA-STS verification.
+type mtaSTSParams struct {
+	Domain string `json:"domain"`
+}
+
+// MTASTSRecord represents the parsed "_mta-sts" TXT record of a domain.
+type MTASTSRecord struct {
+	Name     string   `json:"name"`
+	Found    bool     `json:"found"`
+	Record   string   `json:"record,omitempty"`
+	ID       string   `json:"id,omitempty"`
+	Errors   []string `json:"errors,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// MTASTSPolicy represents the fetched and parsed MTA-STS policy file.
+type MTASTSPolicy struct {
+	URL         string   `json:"url"`
+	Fetched     bool     `json:"fetched"`
+	StatusCode  int      `json:"status_code,omitempty"`
+	ContentType string   `json:"content_type,omitempty"`
+	Body        string   `json:"body,omitempty"`
+	Version     string   `json:"version,omitempty"`
+	Mode        string   `json:"mode,omitempty"`
+	MaxAge      int      `json:"max_age,omitempty"`
+	MX          []string `json:"mx,omitempty"`
+	Errors      []string `json:"errors,omitempty"`
+	Warnings    []string `json:"warnings,omitempty"`
+}
+
+// MTASTSHostMatch reports whether an MX host is covered by the policy.
+type MTASTSHostMatch struct {
+	Host       string `json:"host"`
+	Preference uint16 `json:"preference"`
+	Matched    bool   `json:"matched"`
+	Pattern    string `json:"pattern,omitempty"`
+}
+
+// TLSRPTRecord represents the parsed "_smtp._tls" TLS-RPT record of a domain.
+type TLSRPTRecord struct {
+	Name       string   `json:"name"`
+	Found      bool     `json:"found"`
+	Record     string   `json:"record,omitempty"`
+	ReportURIs []string `json:"report_uris,omitempty"`
+	Errors     []string `json:"errors,omitempty"`
+}
+
+// MTASTSResponse represents the complete MTA-STS and TLS-RPT response.
+type MTASTSResponse struct {
+	Domain     string            `json:"domain"`
+	DomainName *domainname.Name  `json:"domain_name,omitempty"`
+	Valid      bool              `json:"valid"`
+	Mode       string            `json:"mode,omitempty"`
+	Record     *MTASTSRecord     `json:"record"`
+	Policy     *MTASTSPolicy     `json:"policy,omitempty"`
+	MXHosts    []MTASTSHostMatch `json:"mx_hosts"`
+	MXErrors   []string          `json:"mx_errors,omitempty"`
+	TLSRPT     *TLSRPTRecord     `json:"tls_rpt"`
+	Timestamp  string            `json:"timestamp"`
+}
+
+// HandleMTASTS checks the MTA-STS record and policy of a domain, confirms
+// that its MX hosts are covered by the policy, and validates its TLS-RPT
+// record.
+func HandleMTASTS(ctx context.Context, request mcp.CallToolRequest, config *Config) (*mcp.CallToolResult, error) {
+	var params mtaSTSParams
+	if err := request.BindArguments(&params); err != nil {
+		return nil, fmt.Errorf("failed to parse tool input: %w", utils.ParseJSONUnmarshalError(err))
+	}
+
+	// Validate required parameters
+	if params.Domain == "" {
+		return nil, fmt.Errorf("parameter \"domain\" is required")
+	}
+
+	// Validate and normalize the domain name
+	name, err := domainname.Parse(params.Domain)
+	if err != nil {
+		return nil, err
+	}
+	domain := name.ASCII
+
+	result := checkMTASTS(ctx, config.lookup(), http_ping.NewClient(config.HTTPTimeout), domain)
+	result.DomainName = name
+	return resp.JSON(result)
+}
+
+// checkMTASTS runs every MTA-STS and TLS-RPT check for a domain.
+func checkMTASTS(ctx context.Context, lookup lookupFunc, client *http.Client, domain string) *MTASTSResponse {
+	result := &MTASTSResponse{
+		Domain:    domain,
+		MXHosts:   make([]MTASTSHostMatch, 0),
+		Record:    fetchMTASTSRecord(ctx, lookup, domain),
+		TLSRPT:    fetchTLSRPT(ctx, lookup, domain),
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	// The policy is only fetched when the TXT record announces one
+	if !result.Record.Found {
+		return result
+	}
+
+	result.Policy = fetchMTASTSPolicy(ctx, client, domain)
+	result.Mode = result.Policy.Mode
+
+	hosts, err := lookupMXHosts(ctx, lookup, domain)
+	if err != nil {
+		result.MXErrors = append(result.MXErrors, err.Error())
+	}
+
+	for _, host := range hosts {
+		match := MTASTSHostMatch{Host: host.Mx, Preference: host.Preference}
+		match.Pattern, match.Matched = matchMXPattern(host.Mx, result.Policy.MX)
+		if !match.Matched && result.Policy.Fetched {
+			result.MXErrors = append(result.MXErrors, fmt.Sprintf("MX host %s does not match any \"mx\" pattern in the policy", strings.TrimSuffix(host.Mx, ".")))
+		}
+		result.MXHosts = append(result.MXHosts, match)
+	}
+
+	if err == nil && len(hosts) == 0 {
+		result.MXErrors = append(result.MXErrors, "the domain has no MX records")
+	}
+
+	result.Valid = len(result.Record.Errors) == 0 &&
+		result.Policy.Fetched && len(result.Policy.Errors) == 0 &&
+		len(result.MXErrors) == 0
+	return result
+}
+
+// fetchMTASTSRecord fetches and parses the "_mta-sts" TXT record of a domain.
+func fetchMTASTSRecord(ctx context.Context, lookup lookupFunc, domain string) *MTASTSRecord {
+	result := &MTASTSRecord{Name: "_mta-sts." + domain}
+
+	txt, err := lookupTXT(ctx, lookup, result.Name)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+
+	var records []string
+	for _, record := range txt.Records {
+		if hasVersionTag(record, mtaSTSVersion) {
+			records = append(records, record)
+		}
+	}
+
+	switch len(records) {
+	case 0:
+		result.Errors = append(result.Errors, "no MTA-STS record found")
+		return result
+	case 1:
+		result.Found = true
+		result.Record = records[0]
+	default:
+		result.Found = true
+		result.Errors = append(result.Errors, fmt.Sprintf("found %d MTA-STS records, but only one is allowed; senders will treat the domain as not implementing MTA-STS", len(records)))
+		return result
+	}
+
+	tags, err := parseTagList(result.Record)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+	}
+	values := tagMap(tags)
+
+	if len(tags) == 0 || tags[0].Name != "v" || tags[0].Value != mtaSTSVersion {
+		result.Errors = append(result.Errors, fmt.Sprintf("the record must start with \"v=%s\"", mtaSTSVersion))
+	}
+
+	result.ID = values["id"]
+	switch {
+	case result.ID == "":
+		result.Errors = append(result.Errors, "required tag \"id\" is missing")
+	case !mtaSTSPolicyID.MatchString(result.ID):
+		result.Errors = append(result.Errors, fmt.Sprintf("invalid id %q: must be 1 to 32 letters or digits", result.ID))
+	}
+
+	return result
+}
+
+// fetchMTASTSPolicy downloads and parses the policy file of a domain. As
+// RFC 8461 section 3.3 requires, redirects are not followed and the server
+// must present a valid certificate.
+func fetchMTASTSPolicy(ctx context.Context, client *http.Client, domain string) *MTASTSPolicy {
+	result := &MTASTSPolicy{
+		URL: (&url.URL{Scheme: "https", Host: "mta-sts." + domain, Path: "/.well-known/mta-sts.txt"}).String(),
+	}
+
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, result.URL, nil)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("failed to create request: %v", err))
+		return result
+	}
+	req.Header.Set("User-Agent", http_ping.UserAgent)
+
+	res, err := client.Do(req)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("failed to fetch policy: %v", err))
+		return result
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	result.StatusCode = res.StatusCode
+	result.ContentType = res.Header.Get("Content-Type")
+
+	if res.StatusCode != http.StatusOK {
+		if location := res.Header.Get("Location"); location != "" {
+			result.Errors = append(result.Errors, fmt.Sprintf("policy URL redirects to %s, but redirects must not be followed", location))
+		} else {
+			result.Errors = append(result.Errors, fmt.Sprintf("policy URL returned HTTP %d, expected 200", res.StatusCode))
+		}
+		return result
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(result.ContentType); err != nil || mediaType != "text/plain" {
+		result.Errors = append(result.Errors, fmt.Sprintf("policy is served as %q, but must be text/plain", result.ContentType))
+	}
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, mtaSTSMaxPolicySize+1))
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("failed to read policy: %v", err))
+		return result
+	}
+
+	if len(body) > mtaSTSMaxPolicySize {
+		result.Errors = append(result.Errors, fmt.Sprintf("policy is larger than %d bytes", mtaSTSMaxPolicySize))
+		return result
+	}
+
+	result.Fetched = true
+	result.Body = string(body)
+	parseMTASTSPolicy(result)
+	return result
+}
+
+// parseMTASTSPolicy parses the key/value lines of a policy file
+// (RFC 8461 section 3.2).
+func parseMTASTSPolicy(result *MTASTSPolicy) {
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(strings.NewReader(result.Body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			result.Errors = append(result.Errors, fmt.Sprintf("malformed line %q: expected key: value", line))
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		// Only the "mx" key may be repeated
+		if seen[key] && key != "mx" {
+			result.Errors = append(result.Errors, fmt.Sprintf("duplicate key %q", key))
+			continue
+		}
+		seen[key] = true
+
+		switch key {
+		case "version":
+			result.Version = value
+		case "mode":
+			result.Mode = value
+		case "max_age":
+			maxAge, err := strconv.Atoi(value)
+			if err != nil || maxAge < 0 || maxAge > mtaSTSMaxAge {
+				result.Errors = append(result.Errors, fmt.Sprintf("invalid max_age %q: must be between 0 and %d seconds", value, mtaSTSMaxAge))
+				continue
+			}
+			result.MaxAge = maxAge
+		case "mx":
+			if _, ok := dns.IsDomainName(strings.TrimPrefix(value, "*.")); !ok || value == "" {
+				result.Errors = append(result.Errors, fmt.Sprintf("invalid mx pattern %q", value))
+				continue
+			}
+			result.MX = append(result.MX, strings.ToLower(value))
+		default:
+			result.Warnings = append(result.Warnings, fmt.Sprintf("unknown key %q is ignored", key))
+		}
+	}
+
+	if result.Version != mtaSTSVersion {
+		result.Errors = append(result.Errors, fmt.Sprintf("the policy must contain \"version: %s\"", mtaSTSVersion))
+	}
+
+	switch {
+	case result.Mode == "":
+		result.Errors = append(result.Errors, "required key \"mode\" is missing")
+	case !slices.Contains(mtaSTSModes, result.Mode):
+		result.Errors = append(result.Errors, fmt.Sprintf("invalid mode %q: must be enforce, testing or none", result.Mode))
+	case result.Mode == "testing":
+		result.Warnings = append(result.Warnings, "the policy is in testing mode: failures are reported but delivery is not blocked")
+	}
+
+	if !seen["max_age"] {
+		result.Errors = append(result.Errors, "required key \"max_age\" is missing")
+	} else if result.MaxAge < 86400 && result.Mode == "enforce" {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("max_age of %d seconds is short: weeks or longer is recommended once the policy is stable", result.MaxAge))
+	}
+
+	if len(result.MX) == 0 && result.Mode != "none" {
+		result.Errors = append(result.Errors, "the policy must list at least one \"mx\" pattern")
+	}
+}
+
+// lookupMXHosts returns the MX records of a domain.
+func lookupMXHosts(ctx context.Context, lookup lookupFunc, domain string) ([]*dns.MX, error) {
+	response, err := query(ctx, lookup, domain, dns.TypeMX)
+	if err != nil {
+		return nil, err
+	}
+
+	var hosts []*dns.MX
+	for _, rr := range response.Answer {
+		if mx, ok := rr.(*dns.MX); ok {
+			hosts = append(hosts, mx)
+		}
+	}
+
+	slices.SortFunc(hosts, func(a, b *dns.MX) int {
+		return int(a.Preference) - int(b.Preference)
+	})
+
+	return hosts, nil
+}
+
+// matchMXPattern returns the first policy pattern that matches an MX host.
+// A wildcard pattern such as "*.example.com" matches exactly one additional
+// label (RFC 8461 section 4.1).
+func matchMXPattern(host string, patterns []string) (string, bool) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	for _, pattern := range patterns {
+		pattern = strings.TrimSuffix(pattern, ".")
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			label, rest, found := strings.Cut(host, ".")
+			if found && label != "" && rest == suffix {
+				return pattern, true
+			}
+			continue
+		}
+
+		if host == pattern {
+			return pattern, true
+		}
+	}
+
+	return "", false
+}
+
+// fetchTLSRPT fetches and parses the "_smtp._tls" TLS-RPT record of a domain.
+func fetchTLSRPT(ctx context.Context, lookup lookupFunc, domain string) *TLSRPTRecord {
+	result := &TLSRPTRecord{Name: "_smtp._tls." + domain}
+
+	txt, err := lookupTXT(ctx, lookup, result.Name)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+
+	var records []string
+	for _, record := range txt.Records {
+		if hasVersionTag(record, tlsRPTVersion) {
+			records = append(records, record)
+		}
+	}
+
+	switch len(records) {
+	case 0:
+		result.Errors = append(result.Errors, "no TLS-RPT record found")
+		return result
+	case 1:
+		result.Found = true
+		result.Record = records[0]
+	default:
+		result.Found = true
+		result.Errors = append(result.Errors, fmt.Sprintf("found %d TLS-RPT records, but only one is allowed", len(records)))
+		return result
+	}
+
+	tags, err := parseTagList(result.Record)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+	}
+
+	if len(tags) == 0 || tags[0].Name != "v" || tags[0].Value != tlsRPTVersion {
+		result.Errors = append(result.Errors, fmt.Sprintf("the record must start with \"v=%s\"", tlsRPTVersion))
+	}
+
+	rua, ok := tagMap(tags)["rua"]
+	if !ok || rua == "" {
+		result.Errors = append(result.Errors, "required tag \"rua\" is missing")
+		return result
+	}
+
+	for _, raw := range strings.Split(rua, ",") {
+		raw = strings.TrimSpace(raw)
+		if err := validateReportURI(raw); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("invalid report URI %q: %v", raw, err))
+			continue
+		}
+		result.ReportURIs = append(result.ReportURIs, raw)
+	}
+
+	return result
+}
+
+// validateReportURI checks that a TLS-RPT report URI is a mailto: address
+// or an https: URL (RFC 8460 section 3).
+func validateReportURI(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "mailto":
+		if !strings.Contains(u.Opaque, "@") {
+			return errors.New("mailto: URI must contain an email address")
+		}
+		return nil
+	case "https":
+		return validateHTTPSURL(raw)
+	}
+
+	return fmt.Errorf("unsupported scheme %q: must be mailto or https", u.Scheme)
+}
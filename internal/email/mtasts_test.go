@@ -0,0 +1,147 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// policyServer starts an HTTPS server that serves the given handler and
+// returns a client that sends every request to it, regardless of host.
+func policyServer(t *testing.T, handler http.HandlerFunc) *http.Client {
+	t.Helper()
+
+	ts := httptest.NewTLSServer(handler)
+	t.Cleanup(ts.Close)
+
+	transport := ts.Client().Transport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, network, ts.Listener.Addr().String())
+	}
+
+	// The test certificate is only valid for example.com
+	transport.TLSClientConfig.ServerName = "example.com"
+
+	return &http.Client{Transport: transport}
+}
+
+func TestMatchMXPattern(t *testing.T) {
+	patterns := []string{"mail.example.com", "*.mx.example.net"}
+
+	tests := []struct {
+		host    string
+		pattern string
+		matched bool
+	}{
+		{"mail.example.com.", "mail.example.com", true},
+		{"MAIL.example.com", "mail.example.com", true},
+		{"a.mx.example.net.", "*.mx.example.net", true},
+		{"mx.example.net.", "", false},
+		{"a.b.mx.example.net.", "", false},
+		{"other.example.com.", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			pattern, matched := matchMXPattern(tt.host, patterns)
+			assert.Equal(t, tt.matched, matched)
+			assert.Equal(t, tt.pattern, pattern)
+		})
+	}
+}
+
+func TestParseMTASTSPolicy(t *testing.T) {
+	t.Run("valid policy", func(t *testing.T) {
+		policy := &MTASTSPolicy{Body: "version: STSv1\r\nmode: enforce\r\nmx: mail.example.com\r\nmx: *.example.net\r\nmax_age: 604800\r\n"}
+		parseMTASTSPolicy(policy)
+
+		assert.Empty(t, policy.Errors)
+		assert.Equal(t, "enforce", policy.Mode)
+		assert.Equal(t, 604800, policy.MaxAge)
+		assert.Equal(t, []string{"mail.example.com", "*.example.net"}, policy.MX)
+	})
+
+	t.Run("invalid policy", func(t *testing.T) {
+		policy := &MTASTSPolicy{Body: "version: STSv2\nmode: strict\nmode: enforce\nmax_age: forever\n"}
+		parseMTASTSPolicy(policy)
+
+		assert.Len(t, policy.Errors, 5)
+	})
+}
+
+func TestCheckMTASTS(t *testing.T) {
+	lookup := fakeZone(t,
+		`_mta-sts.example.com. 300 IN TXT "v=STSv1; id=20240101T000000"`,
+		`example.com. 300 IN MX 10 mail.example.com.`,
+		`example.com. 300 IN MX 20 backup.example.org.`,
+		`_smtp._tls.example.com. 300 IN TXT "v=TLSRPTv1; rua=mailto:tls@example.com,https://reports.example.com/tls"`,
+		`_mta-sts.example.org. 300 IN TXT "v=STSv1; id=abc"`,
+		`example.org. 300 IN MX 10 mail.example.org.`,
+		`_smtp._tls.example.org. 300 IN TXT "v=TLSRPTv1; rua=ftp://example.org"`,
+		`_mta-sts.example.net. 300 IN TXT "v=STSv10; id=abc"`,
+		`_smtp._tls.example.net. 300 IN TXT "v=TLSRPTv10; rua=mailto:tls@example.net"`,
+	)
+
+	policy := "version: STSv1\nmode: enforce\nmx: mail.example.com\nmx: *.example.org\nmax_age: 604800\n"
+
+	t.Run("valid configuration", func(t *testing.T) {
+		client := policyServer(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "mta-sts.example.com", r.Host)
+			assert.Equal(t, "/.well-known/mta-sts.txt", r.URL.Path)
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			fmt.Fprint(w, policy)
+		})
+
+		result := checkMTASTS(context.Background(), lookup, client, "example.com")
+		assert.True(t, result.Valid, result)
+		assert.Equal(t, "enforce", result.Mode)
+		assert.Equal(t, "20240101T000000", result.Record.ID)
+
+		require.Len(t, result.MXHosts, 2)
+		assert.Equal(t, "mail.example.com", result.MXHosts[0].Pattern)
+		assert.Equal(t, "*.example.org", result.MXHosts[1].Pattern)
+
+		assert.True(t, result.TLSRPT.Found)
+		assert.Empty(t, result.TLSRPT.Errors)
+		assert.Len(t, result.TLSRPT.ReportURIs, 2)
+	})
+
+	t.Run("uncovered mx host and redirect", func(t *testing.T) {
+		client := policyServer(t, func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "https://example.com/policy.txt", http.StatusFound)
+		})
+
+		result := checkMTASTS(context.Background(), lookup, client, "example.org")
+		assert.False(t, result.Valid)
+		assert.False(t, result.Policy.Fetched)
+		assert.Contains(t, result.Policy.Errors[0], "redirects must not be followed")
+		assert.NotEmpty(t, result.TLSRPT.Errors)
+	})
+
+	t.Run("wrong content type", func(t *testing.T) {
+		client := policyServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, policy)
+		})
+
+		result := checkMTASTS(context.Background(), lookup, client, "example.com")
+		assert.False(t, result.Valid)
+		require.Len(t, result.Policy.Errors, 1)
+		assert.Contains(t, result.Policy.Errors[0], "text/plain")
+	})
+
+	t.Run("no record", func(t *testing.T) {
+		result := checkMTASTS(context.Background(), lookup, http.DefaultClient, "example.net")
+		assert.False(t, result.Valid)
+		assert.False(t, result.Record.Found)
+		assert.Nil(t, result.Policy)
+		assert.False(t, result.TLSRPT.Found)
+	})
+}
@@ -0,0 +1,725 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/miekg/dns"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/domainname"
+	resp "github.com/patrickdappollonio/mcp-domaintools/internal/response"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/utils"
+)
+
+// Limits defined by RFC 7208 section 4.6.4.
+const (
+	spfLookupLimit     = 10
+	spfVoidLookupLimit = 2
+	spfMXHostLimit     = 10
+)
+
+// spfAnalyzeLookupBudget bounds the total number of DNS lookups performed
+// while expanding a record, so that badly broken records still produce a
+// report instead of querying indefinitely.
+const spfAnalyzeLookupBudget = 50
+
+// spfVersion is the version tag that starts every SPF record.
+const spfVersion = "v=spf1"
+
+// spfDualCIDR matches an optional IPv4 and IPv6 prefix length at the end of
+// an "a" or "mx" mechanism, such as "/24//64".
+var spfDualCIDR = regexp.MustCompile(`^(.*?)(?:/(\d+))?(?://(\d+))?$`)
+
+// spfModifierName matches a valid modifier name (RFC 7208 section 12).
+var spfModifierName = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9\-_.]*$`)
+
+// SPF evaluation results (RFC 7208 section 2.6).
+const (
+	spfResultNone      = "none"
+	spfResultNeutral   = "neutral"
+	spfResultPass      = "pass"
+	spfResultFail      = "fail"
+	spfResultSoftFail  = "softfail"
+	spfResultTempError = "temperror"
+	spfResultPermError = "permerror"
+)
+
+// spfQualifiers maps SPF qualifiers to the result they produce on a match.
+var spfQualifiers = map[byte]string{
+	'+': spfResultPass,
+	'-': spfResultFail,
+	'~': spfResultSoftFail,
+	'?': spfResultNeutral,
+}
+
+// Errors returned when a domain does not publish an SPF record.
+var (
+	errSPFDomainNotFound = errors.New("domain does not exist")
+	errSPFRecordNotFound = errors.New("no SPF record found")
+)
+
+// spfAnalyzeParams represents the parameters for SPF analysis.
+type spfAnalyzeParams struct {
+	Domain string `json:"domain"`
+}
+
+// SPFTerm represents a single mechanism or modifier of an SPF record.
+type SPFTerm struct {
+	Term      string   `json:"term"`
+	Kind      string   `json:"kind"`
+	Qualifier string   `json:"qualifier,omitempty"`
+	Name      string   `json:"name"`
+	Value     string   `json:"value,omitempty"`
+	CIDR4     *int     `json:"cidr4,omitempty"`
+	CIDR6     *int     `json:"cidr6,omitempty"`
+	Lookups   int      `json:"lookups,omitempty"`
+	Void      bool     `json:"void,omitempty"`
+	Resolved  []string `json:"resolved,omitempty"`
+	Child     *SPFNode `json:"child,omitempty"`
+	Note      string   `json:"note,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// SPFNode represents the SPF record of one domain in the expansion tree.
+type SPFNode struct {
+	Domain string    `json:"domain"`
+	Record string    `json:"record,omitempty"`
+	Terms  []SPFTerm `json:"terms,omitempty"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// SPFAnalysisResponse represents the complete SPF analysis response.
+type SPFAnalysisResponse struct {
+	Domain             string           `json:"domain"`
+	DomainName         *domainname.Name `json:"domain_name,omitempty"`
+	Record             string           `json:"record,omitempty"`
+	Valid              bool             `json:"valid"`
+	LookupCount        int              `json:"lookup_count"`
+	LookupLimit        int              `json:"lookup_limit"`
+	ExceedsLookupLimit bool             `json:"exceeds_lookup_limit"`
+	VoidLookupCount    int              `json:"void_lookup_count"`
+	VoidLookupLimit    int              `json:"void_lookup_limit"`
+	AuthorizedRanges   []string         `json:"authorized_ranges"`
+	Tree               *SPFNode         `json:"tree"`
+	Errors             []string         `json:"errors,omitempty"`
+	Warnings           []string         `json:"warnings,omitempty"`
+	Timestamp          string           `json:"timestamp"`
+}
+
+// HandleSPFAnalysis fetches a domain's SPF record, expands every mechanism
+// that requires DNS lookups and reports the lookup count, errors and the
+// flattened list of authorized IP ranges.
+func HandleSPFAnalysis(ctx context.Context, request mcp.CallToolRequest, config *Config) (*mcp.CallToolResult, error) {
+	var params spfAnalyzeParams
+	if err := request.BindArguments(&params); err != nil {
+		return nil, fmt.Errorf("failed to parse tool input: %w", utils.ParseJSONUnmarshalError(err))
+	}
+
+	// Validate required parameters
+	if params.Domain == "" {
+		return nil, fmt.Errorf("parameter \"domain\" is required")
+	}
+
+	// Validate and normalize the domain name
+	name, err := domainname.Parse(params.Domain)
+	if err != nil {
+		return nil, err
+	}
+	domain := name.ASCII
+
+	result := analyzeSPF(ctx, config.lookup(), domain)
+	result.DomainName = name
+	return resp.JSON(result)
+}
+
+// spfAnalyzer holds the state shared across a recursive SPF expansion.
+type spfAnalyzer struct {
+	lookup      lookupFunc
+	lookups     int
+	voidLookups int
+	ranges      []string
+	errors      []string
+	warnings    []string
+}
+
+// analyzeSPF expands the SPF record of a domain and summarizes the result.
+func analyzeSPF(ctx context.Context, lookup lookupFunc, domain string) *SPFAnalysisResponse {
+	a := &spfAnalyzer{lookup: lookup}
+	tree := a.expand(ctx, domain, nil, true)
+
+	result := &SPFAnalysisResponse{
+		Domain:             domain,
+		Record:             tree.Record,
+		LookupCount:        a.lookups,
+		LookupLimit:        spfLookupLimit,
+		ExceedsLookupLimit: a.lookups > spfLookupLimit,
+		VoidLookupCount:    a.voidLookups,
+		VoidLookupLimit:    spfVoidLookupLimit,
+		AuthorizedRanges:   compactRanges(a.ranges),
+		Tree:               tree,
+		Errors:             a.errors,
+		Warnings:           a.warnings,
+		Timestamp:          time.Now().Format(time.RFC3339),
+	}
+
+	if result.ExceedsLookupLimit {
+		result.Errors = append(result.Errors, fmt.Sprintf("record requires %d DNS lookups, exceeding the limit of %d (permerror)", a.lookups, spfLookupLimit))
+	}
+
+	if a.voidLookups > spfVoidLookupLimit {
+		result.Errors = append(result.Errors, fmt.Sprintf("record causes %d void lookups, exceeding the limit of %d (permerror)", a.voidLookups, spfVoidLookupLimit))
+	}
+
+	result.Valid = len(result.Errors) == 0
+	return result
+}
+
+// expand fetches and parses the SPF record of a domain and recursively
+// expands its terms. The chain holds the domains currently being expanded
+// to detect include loops, and authorizing reports whether "pass" results
+// in this record authorize senders for the original domain.
+func (a *spfAnalyzer) expand(ctx context.Context, domain string, chain []string, authorizing bool) *SPFNode {
+	node := &SPFNode{Domain: domain}
+
+	record, err := fetchSPFRecord(ctx, a.lookup, domain)
+	if err != nil {
+		node.Error = err.Error()
+		a.errors = append(a.errors, fmt.Sprintf("%s: %v", domain, err))
+		return node
+	}
+	node.Record = record
+
+	terms, syntaxErrors := parseSPFRecord(record)
+	for _, syntaxErr := range syntaxErrors {
+		a.errors = append(a.errors, fmt.Sprintf("%s: %s", domain, syntaxErr))
+	}
+
+	chain = append(chain, strings.ToLower(domain))
+	hasAll := false
+	var redirect *SPFTerm
+
+	for i := range terms {
+		term := &terms[i]
+		if term.Error != "" {
+			continue
+		}
+
+		if hasAll && term.Kind == "mechanism" {
+			term.Note = "ignored: appears after the \"all\" mechanism"
+			a.warnings = append(a.warnings, fmt.Sprintf("%s: mechanism %q after \"all\" is never evaluated", domain, term.Term))
+			continue
+		}
+
+		switch term.Name {
+		case "all":
+			hasAll = true
+
+		case "ip4", "ip6":
+			if authorizing && term.Qualifier == spfResultPass {
+				a.ranges = append(a.ranges, term.Value)
+			}
+
+		case "a":
+			a.expandAddresses(ctx, domain, term, authorizing)
+
+		case "mx":
+			a.expandMX(ctx, domain, term, authorizing)
+
+		case "ptr":
+			a.countLookup(term)
+			term.Note = "cannot be evaluated without a client IP"
+			a.warnings = append(a.warnings, fmt.Sprintf("%s: the \"ptr\" mechanism is deprecated and should not be used (RFC 7208 section 5.5)", domain))
+
+		case "exists":
+			a.expandExists(ctx, term)
+
+		case "include":
+			a.expandInclude(ctx, domain, term, chain, authorizing)
+
+		case "redirect":
+			if redirect != nil {
+				term.Error = "duplicate \"redirect\" modifier"
+				a.errors = append(a.errors, fmt.Sprintf("%s: %s", domain, term.Error))
+				continue
+			}
+			redirect = term
+		}
+	}
+
+	// The redirect modifier only applies when no "all" mechanism is present
+	if redirect != nil {
+		if hasAll {
+			redirect.Note = "ignored: the record contains an \"all\" mechanism"
+		} else {
+			a.expandInclude(ctx, domain, redirect, chain, authorizing)
+		}
+	}
+
+	node.Terms = terms
+	return node
+}
+
+// countLookup records a DNS lookup performed by a term and reports whether
+// the lookup budget still allows it to be performed.
+func (a *spfAnalyzer) countLookup(term *SPFTerm) bool {
+	a.lookups++
+	term.Lookups++
+	if a.lookups > spfAnalyzeLookupBudget {
+		term.Note = "not expanded: lookup budget exhausted"
+		return false
+	}
+	return true
+}
+
+// countVoid records a lookup that returned no records.
+func (a *spfAnalyzer) countVoid(term *SPFTerm) {
+	a.voidLookups++
+	term.Void = true
+}
+
+// expandAddresses resolves the target of an "a" mechanism.
+func (a *spfAnalyzer) expandAddresses(ctx context.Context, domain string, term *SPFTerm, authorizing bool) {
+	if !a.countLookup(term) {
+		return
+	}
+
+	target := domain
+	if term.Value != "" {
+		target = term.Value
+	}
+
+	if hasMacros(target) {
+		term.Note = "contains macros that depend on the sender and cannot be expanded without a client IP"
+		return
+	}
+
+	addresses, err := resolveAddresses(ctx, a.lookup, target)
+	if err != nil {
+		term.Error = err.Error()
+		return
+	}
+
+	if len(addresses) == 0 {
+		a.countVoid(term)
+		return
+	}
+
+	for _, addr := range addresses {
+		prefix := addressPrefix(addr, term.CIDR4, term.CIDR6)
+		term.Resolved = append(term.Resolved, prefix)
+		if authorizing && term.Qualifier == spfResultPass {
+			a.ranges = append(a.ranges, prefix)
+		}
+	}
+}
+
+// expandMX resolves the mail exchangers of an "mx" mechanism and their addresses.
+func (a *spfAnalyzer) expandMX(ctx context.Context, domain string, term *SPFTerm, authorizing bool) {
+	if !a.countLookup(term) {
+		return
+	}
+
+	target := domain
+	if term.Value != "" {
+		target = term.Value
+	}
+
+	if hasMacros(target) {
+		term.Note = "contains macros that depend on the sender and cannot be expanded without a client IP"
+		return
+	}
+
+	hosts, err := resolveMX(ctx, a.lookup, target)
+	if err != nil {
+		term.Error = err.Error()
+		return
+	}
+
+	if len(hosts) == 0 {
+		a.countVoid(term)
+		return
+	}
+
+	if len(hosts) > spfMXHostLimit {
+		term.Error = fmt.Sprintf("%s has %d MX records, exceeding the limit of %d (permerror)", target, len(hosts), spfMXHostLimit)
+		a.errors = append(a.errors, fmt.Sprintf("%s: %s", domain, term.Error))
+		return
+	}
+
+	for _, host := range hosts {
+		addresses, err := resolveAddresses(ctx, a.lookup, host)
+		if err != nil {
+			continue
+		}
+
+		for _, addr := range addresses {
+			prefix := addressPrefix(addr, term.CIDR4, term.CIDR6)
+			term.Resolved = append(term.Resolved, prefix)
+			if authorizing && term.Qualifier == spfResultPass {
+				a.ranges = append(a.ranges, prefix)
+			}
+		}
+	}
+}
+
+// expandExists checks whether the target of an "exists" mechanism resolves.
+func (a *spfAnalyzer) expandExists(ctx context.Context, term *SPFTerm) {
+	if !a.countLookup(term) {
+		return
+	}
+
+	if hasMacros(term.Value) {
+		term.Note = "contains macros that depend on the sender and cannot be expanded without a client IP"
+		return
+	}
+
+	response, err := query(ctx, a.lookup, term.Value, dns.TypeA)
+	if err != nil {
+		term.Error = err.Error()
+		return
+	}
+
+	if len(response.Answer) == 0 {
+		a.countVoid(term)
+	}
+}
+
+// expandInclude expands the record referenced by an "include" mechanism or
+// a "redirect" modifier.
+func (a *spfAnalyzer) expandInclude(ctx context.Context, domain string, term *SPFTerm, chain []string, authorizing bool) {
+	if !a.countLookup(term) {
+		return
+	}
+
+	if hasMacros(term.Value) {
+		term.Note = "contains macros that depend on the sender and cannot be expanded without a client IP"
+		return
+	}
+
+	if slices.Contains(chain, strings.ToLower(term.Value)) {
+		term.Error = fmt.Sprintf("loop detected: %s is already being evaluated", term.Value)
+		a.errors = append(a.errors, fmt.Sprintf("%s: %s", domain, term.Error))
+		return
+	}
+
+	// An included record only authorizes senders when the include itself is a pass
+	childAuthorizing := authorizing
+	if term.Name == "include" && term.Qualifier != spfResultPass {
+		childAuthorizing = false
+	}
+
+	term.Child = a.expand(ctx, term.Value, chain, childAuthorizing)
+	if term.Child.Record == "" {
+		term.Error = fmt.Sprintf("%s does not publish a usable SPF record (permerror)", term.Value)
+	}
+}
+
+// fetchSPFRecord returns the single SPF record published at a domain.
+func fetchSPFRecord(ctx context.Context, lookup lookupFunc, domain string) (string, error) {
+	txt, err := lookupTXT(ctx, lookup, domain)
+	if err != nil {
+		return "", err
+	}
+
+	if txt.Rcode == dns.RcodeNameError {
+		return "", errSPFDomainNotFound
+	}
+
+	var records []string
+	for _, record := range txt.Records {
+		if isSPFRecord(record) {
+			records = append(records, record)
+		}
+	}
+
+	switch len(records) {
+	case 0:
+		return "", errSPFRecordNotFound
+	case 1:
+		return records[0], nil
+	default:
+		return "", fmt.Errorf("found %d SPF records, but only one is allowed (permerror): %q", len(records), records)
+	}
+}
+
+// isSPFRecord reports whether a TXT record is an SPF version 1 record.
+func isSPFRecord(record string) bool {
+	if len(record) < len(spfVersion) || !strings.EqualFold(record[:len(spfVersion)], spfVersion) {
+		return false
+	}
+	return len(record) == len(spfVersion) || record[len(spfVersion)] == ' '
+}
+
+// parseSPFRecord splits an SPF record into its terms. Terms with syntax
+// errors have their Error field set, and the errors are also returned.
+func parseSPFRecord(record string) ([]SPFTerm, []string) {
+	fields := strings.Fields(record)
+	terms := make([]SPFTerm, 0, len(fields))
+	var syntaxErrors []string
+
+	for _, field := range fields[1:] {
+		term := parseSPFTerm(field)
+		if term.Error != "" {
+			syntaxErrors = append(syntaxErrors, fmt.Sprintf("invalid term %q: %s (permerror)", field, term.Error))
+		}
+		terms = append(terms, term)
+	}
+
+	return terms, syntaxErrors
+}
+
+// parseSPFTerm parses a single SPF mechanism or modifier.
+func parseSPFTerm(raw string) SPFTerm {
+	term := SPFTerm{Term: raw}
+
+	// Modifiers are name=value pairs where the name has no ':' or '/'
+	if idx := strings.IndexByte(raw, '='); idx > 0 && !strings.ContainsAny(raw[:idx], ":/") {
+		term.Kind = "modifier"
+		term.Name = strings.ToLower(raw[:idx])
+		term.Value = raw[idx+1:]
+
+		switch {
+		case !spfModifierName.MatchString(raw[:idx]):
+			term.Error = "invalid modifier name"
+		case (term.Name == "redirect" || term.Name == "exp") && !isValidDomainSpec(term.Value):
+			term.Error = "invalid domain specification"
+		}
+		return term
+	}
+
+	term.Kind = "mechanism"
+	term.Qualifier = spfResultPass
+	if qualifier, ok := spfQualifiers[raw[0]]; ok {
+		term.Qualifier = qualifier
+		raw = raw[1:]
+	}
+
+	// Split the mechanism name from its arguments
+	nameEnd := strings.IndexAny(raw, ":/")
+	if nameEnd == -1 {
+		nameEnd = len(raw)
+	}
+	term.Name = strings.ToLower(raw[:nameEnd])
+	rest := raw[nameEnd:]
+
+	switch term.Name {
+	case "all":
+		if rest != "" {
+			term.Error = "the \"all\" mechanism takes no arguments"
+		}
+
+	case "include", "exists":
+		if !strings.HasPrefix(rest, ":") || !isValidDomainSpec(rest[1:]) {
+			term.Error = "a valid domain specification is required"
+			return term
+		}
+		term.Value = rest[1:]
+
+	case "ip4", "ip6":
+		if !strings.HasPrefix(rest, ":") {
+			term.Error = "an IP address is required"
+			return term
+		}
+		prefix, err := parseSPFNetwork(term.Name, rest[1:])
+		if err != nil {
+			term.Error = err.Error()
+			return term
+		}
+		term.Value = prefix
+
+	case "a", "mx", "ptr":
+		matches := spfDualCIDR.FindStringSubmatch(rest)
+		domainSpec := matches[1]
+		if domainSpec != "" {
+			if !strings.HasPrefix(domainSpec, ":") || !isValidDomainSpec(domainSpec[1:]) {
+				term.Error = "invalid domain specification"
+				return term
+			}
+			term.Value = domainSpec[1:]
+		}
+
+		if term.Name == "ptr" && (matches[2] != "" || matches[3] != "") {
+			term.Error = "the \"ptr\" mechanism does not accept a prefix length"
+			return term
+		}
+
+		if matches[2] != "" {
+			cidr, err := strconv.Atoi(matches[2])
+			if err != nil || cidr > 32 {
+				term.Error = "invalid IPv4 prefix length"
+				return term
+			}
+			term.CIDR4 = &cidr
+		}
+
+		if matches[3] != "" {
+			cidr, err := strconv.Atoi(matches[3])
+			if err != nil || cidr > 128 {
+				term.Error = "invalid IPv6 prefix length"
+				return term
+			}
+			term.CIDR6 = &cidr
+		}
+
+	default:
+		term.Error = "unknown mechanism"
+	}
+
+	return term
+}
+
+// parseSPFNetwork validates the argument of an ip4 or ip6 mechanism and
+// returns it in CIDR notation.
+func parseSPFNetwork(mechanism, value string) (string, error) {
+	if !strings.Contains(value, "/") {
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid IP address")
+		}
+		if (mechanism == "ip4") != addr.Is4() {
+			return "", fmt.Errorf("address family does not match the %q mechanism", mechanism)
+		}
+		return netip.PrefixFrom(addr, addr.BitLen()).String(), nil
+	}
+
+	prefix, err := netip.ParsePrefix(value)
+	if err != nil {
+		return "", fmt.Errorf("invalid network")
+	}
+	if (mechanism == "ip4") != prefix.Addr().Is4() {
+		return "", fmt.Errorf("address family does not match the %q mechanism", mechanism)
+	}
+	return prefix.Masked().String(), nil
+}
+
+// isValidDomainSpec performs a basic validation of an SPF domain
+// specification, which may contain macros.
+func isValidDomainSpec(spec string) bool {
+	if spec == "" {
+		return false
+	}
+
+	if hasMacros(spec) {
+		return isValidMacroString(spec)
+	}
+
+	_, ok := dns.IsDomainName(spec)
+	return ok && strings.Contains(strings.TrimSuffix(spec, "."), ".")
+}
+
+// spfMacro matches a single macro expansion (RFC 7208 section 7.1).
+var spfMacro = regexp.MustCompile(`^%\{([slodiphcrtvSLODIPHCRTV])(\d*)([rR]?)([.\-+,/_=]*)\}`)
+
+// isValidMacroString validates the macro syntax of a domain specification.
+func isValidMacroString(spec string) bool {
+	for i := 0; i < len(spec); {
+		if spec[i] != '%' {
+			i++
+			continue
+		}
+
+		rest := spec[i:]
+		switch {
+		case strings.HasPrefix(rest, "%%"), strings.HasPrefix(rest, "%_"), strings.HasPrefix(rest, "%-"):
+			i += 2
+		default:
+			match := spfMacro.FindString(rest)
+			if match == "" {
+				return false
+			}
+			i += len(match)
+		}
+	}
+	return true
+}
+
+// hasMacros reports whether a domain specification contains macros.
+func hasMacros(spec string) bool {
+	return strings.Contains(spec, "%")
+}
+
+// resolveAddresses returns the IPv4 and IPv6 addresses of a host.
+func resolveAddresses(ctx context.Context, lookup lookupFunc, host string) ([]netip.Addr, error) {
+	var addresses []netip.Addr
+
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		response, err := query(ctx, lookup, host, qtype)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, rr := range response.Answer {
+			switch rec := rr.(type) {
+			case *dns.A:
+				if addr, ok := netip.AddrFromSlice(rec.A.To4()); ok {
+					addresses = append(addresses, addr)
+				}
+			case *dns.AAAA:
+				if addr, ok := netip.AddrFromSlice(rec.AAAA); ok {
+					addresses = append(addresses, addr)
+				}
+			}
+		}
+	}
+
+	return addresses, nil
+}
+
+// resolveMX returns the mail exchanger hostnames of a domain.
+func resolveMX(ctx context.Context, lookup lookupFunc, domain string) ([]string, error) {
+	response, err := query(ctx, lookup, domain, dns.TypeMX)
+	if err != nil {
+		return nil, err
+	}
+
+	var hosts []string
+	for _, rr := range response.Answer {
+		if mx, ok := rr.(*dns.MX); ok {
+			hosts = append(hosts, mx.Mx)
+		}
+	}
+
+	return hosts, nil
+}
+
+// addressPrefix converts an address into a prefix using the mechanism's
+// IPv4 or IPv6 prefix length, if any.
+func addressPrefix(addr netip.Addr, cidr4, cidr6 *int) string {
+	return mechanismPrefix(addr, cidr4, cidr6).String()
+}
+
+// mechanismPrefix returns the network an address covers given the IPv4 or
+// IPv6 prefix length of an "a" or "mx" mechanism.
+func mechanismPrefix(addr netip.Addr, cidr4, cidr6 *int) netip.Prefix {
+	bits := addr.BitLen()
+	if addr.Is4() && cidr4 != nil {
+		bits = *cidr4
+	}
+	if addr.Is6() && cidr6 != nil {
+		bits = *cidr6
+	}
+
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return netip.PrefixFrom(addr, addr.BitLen())
+	}
+	return prefix
+}
+
+// compactRanges sorts and deduplicates a list of ranges.
+func compactRanges(ranges []string) []string {
+	result := slices.Clone(ranges)
+	slices.Sort(result)
+	result = slices.Compact(result)
+	if result == nil {
+		result = make([]string, 0)
+	}
+	return result
+}
@@ -0,0 +1,203 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeZone returns a lookupFunc that answers from a list of zone file
+// records. Names without any records produce NXDOMAIN.
+func fakeZone(t *testing.T, records ...string) lookupFunc {
+	t.Helper()
+
+	var rrs []dns.RR
+	for _, record := range records {
+		rr, err := dns.NewRR(record)
+		require.NoError(t, err)
+		rrs = append(rrs, rr)
+	}
+
+	return func(_ context.Context, name string, qtype uint16) (*dns.Msg, error) {
+		m := new(dns.Msg)
+		m.SetQuestion(dns.Fqdn(name), qtype)
+		m.Rcode = dns.RcodeNameError
+
+		for _, rr := range rrs {
+			if !strings.EqualFold(rr.Header().Name, dns.Fqdn(name)) {
+				continue
+			}
+			m.Rcode = dns.RcodeSuccess
+			if rr.Header().Rrtype == qtype {
+				m.Answer = append(m.Answer, rr)
+			}
+		}
+
+		return m, nil
+	}
+}
+
+func TestParseSPFTerm(t *testing.T) {
+	tests := []struct {
+		raw       string
+		name      string
+		qualifier string
+		value     string
+		cidr4     int
+		cidr6     int
+		wantErr   bool
+	}{
+		{raw: "-all", name: "all", qualifier: "fail"},
+		{raw: "include:_spf.example.com", name: "include", qualifier: "pass", value: "_spf.example.com"},
+		{raw: "ip4:192.0.2.1", name: "ip4", qualifier: "pass", value: "192.0.2.1/32"},
+		{raw: "~ip6:2001:db8::/32", name: "ip6", qualifier: "softfail", value: "2001:db8::/32"},
+		{raw: "a/24//64", name: "a", qualifier: "pass", cidr4: 24, cidr6: 64},
+		{raw: "mx:mail.example.com/28", name: "mx", qualifier: "pass", value: "mail.example.com", cidr4: 28},
+		{raw: "exists:%{i}._spf.example.com", name: "exists", qualifier: "pass", value: "%{i}._spf.example.com"},
+		{raw: "redirect=_spf.example.com", name: "redirect", value: "_spf.example.com"},
+		{raw: "ip4:192.0.2.1/33", name: "ip4", wantErr: true},
+		{raw: "ip4:2001:db8::1", name: "ip4", wantErr: true},
+		{raw: "include", name: "include", wantErr: true},
+		{raw: "a/33", name: "a", wantErr: true},
+		{raw: "ptr/24", name: "ptr", wantErr: true},
+		{raw: "exists:%{z}.example.com", name: "exists", wantErr: true},
+		{raw: "foo:bar", name: "foo", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			term := parseSPFTerm(tt.raw)
+			assert.Equal(t, tt.name, term.Name)
+
+			if tt.wantErr {
+				assert.NotEmpty(t, term.Error)
+				return
+			}
+
+			require.Empty(t, term.Error)
+			if tt.qualifier != "" {
+				assert.Equal(t, tt.qualifier, term.Qualifier)
+			}
+			assert.Equal(t, tt.value, term.Value)
+
+			if tt.cidr4 != 0 {
+				require.NotNil(t, term.CIDR4)
+				assert.Equal(t, tt.cidr4, *term.CIDR4)
+			}
+			if tt.cidr6 != 0 {
+				require.NotNil(t, term.CIDR6)
+				assert.Equal(t, tt.cidr6, *term.CIDR6)
+			}
+		})
+	}
+}
+
+func TestAnalyzeSPF(t *testing.T) {
+	t.Run("expands includes and mechanisms", func(t *testing.T) {
+		lookup := fakeZone(t,
+			`example.com. 300 IN TXT "v=spf1 ip4:192.0.2.0/24 a mx include:_spf.example.net ~all"`,
+			`example.com. 300 IN A 198.51.100.1`,
+			`example.com. 300 IN MX 10 mail.example.com.`,
+			`mail.example.com. 300 IN A 198.51.100.2`,
+			`mail.example.com. 300 IN AAAA 2001:db8::25`,
+			`_spf.example.net. 300 IN TXT "v=spf1 ip6:2001:db8:1::/48 " "-ip4:203.0.113.0/24 -all"`,
+		)
+
+		result := analyzeSPF(context.Background(), lookup, "example.com")
+		assert.True(t, result.Valid)
+		assert.Empty(t, result.Errors)
+		assert.Equal(t, 3, result.LookupCount)
+		assert.Equal(t, 0, result.VoidLookupCount)
+		assert.Equal(t, []string{
+			"192.0.2.0/24",
+			"198.51.100.1/32",
+			"198.51.100.2/32",
+			"2001:db8:1::/48",
+			"2001:db8::25/128",
+		}, result.AuthorizedRanges)
+
+		include := result.Tree.Terms[3]
+		require.NotNil(t, include.Child)
+		assert.Equal(t, "v=spf1 ip6:2001:db8:1::/48 -ip4:203.0.113.0/24 -all", include.Child.Record)
+	})
+
+	t.Run("counts lookups against the limit", func(t *testing.T) {
+		records := []string{}
+		var includes []string
+		for i := range 11 {
+			includes = append(includes, fmt.Sprintf("include:s%d.example.com", i))
+			records = append(records, fmt.Sprintf(`s%d.example.com. 300 IN TXT "v=spf1 ip4:192.0.2.%d -all"`, i, i))
+		}
+		records = append(records, fmt.Sprintf(`example.com. 300 IN TXT "v=spf1 %s -all"`, strings.Join(includes, " ")))
+
+		result := analyzeSPF(context.Background(), fakeZone(t, records...), "example.com")
+		assert.False(t, result.Valid)
+		assert.True(t, result.ExceedsLookupLimit)
+		assert.Equal(t, 11, result.LookupCount)
+		assert.Len(t, result.AuthorizedRanges, 11)
+	})
+
+	t.Run("flags void lookups", func(t *testing.T) {
+		lookup := fakeZone(t,
+			`example.com. 300 IN TXT "v=spf1 a:gone1.example.com a:gone2.example.com mx:gone3.example.com -all"`,
+		)
+
+		result := analyzeSPF(context.Background(), lookup, "example.com")
+		assert.False(t, result.Valid)
+		assert.Equal(t, 3, result.VoidLookupCount)
+		assert.True(t, result.Tree.Terms[0].Void)
+	})
+
+	t.Run("rejects duplicate records", func(t *testing.T) {
+		lookup := fakeZone(t,
+			`example.com. 300 IN TXT "v=spf1 -all"`,
+			`example.com. 300 IN TXT "v=spf1 ip4:192.0.2.1 -all"`,
+			`example.com. 300 IN TXT "google-site-verification=abc"`,
+		)
+
+		result := analyzeSPF(context.Background(), lookup, "example.com")
+		assert.False(t, result.Valid)
+		require.Len(t, result.Errors, 1)
+		assert.Contains(t, result.Errors[0], "found 2 SPF records")
+	})
+
+	t.Run("detects loops and missing includes", func(t *testing.T) {
+		lookup := fakeZone(t,
+			`example.com. 300 IN TXT "v=spf1 include:a.example.com include:missing.example.com -all"`,
+			`a.example.com. 300 IN TXT "v=spf1 redirect=example.com"`,
+		)
+
+		result := analyzeSPF(context.Background(), lookup, "example.com")
+		assert.False(t, result.Valid)
+		require.Len(t, result.Errors, 2)
+		assert.Contains(t, result.Errors[0], "loop detected")
+		assert.Contains(t, result.Errors[1], "missing.example.com: domain does not exist")
+		assert.NotEmpty(t, result.Tree.Terms[1].Error)
+	})
+
+	t.Run("ignores redirect when all is present", func(t *testing.T) {
+		lookup := fakeZone(t,
+			`example.com. 300 IN TXT "v=spf1 -all redirect=other.example.com"`,
+		)
+
+		result := analyzeSPF(context.Background(), lookup, "example.com")
+		assert.True(t, result.Valid)
+		assert.Equal(t, 0, result.LookupCount)
+		assert.Contains(t, result.Tree.Terms[1].Note, "ignored")
+	})
+
+	t.Run("does not authorize ranges from failing includes", func(t *testing.T) {
+		lookup := fakeZone(t,
+			`example.com. 300 IN TXT "v=spf1 -include:bad.example.com ip4:192.0.2.1 -all"`,
+			`bad.example.com. 300 IN TXT "v=spf1 ip4:203.0.113.0/24 -all"`,
+		)
+
+		result := analyzeSPF(context.Background(), lookup, "example.com")
+		assert.Equal(t, []string{"192.0.2.1/32"}, result.AuthorizedRanges)
+	})
+}
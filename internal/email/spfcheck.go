@@ -0,0 +1,846 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/miekg/dns"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/domainname"
+	resp "github.com/patrickdappollonio/mcp-domaintools/internal/response"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/utils"
+)
+
+// spfPTRNameLimit is the maximum number of PTR names checked when validating
+// a client IP for the "ptr" mechanism and the "p" macro (RFC 7208 section 4.6.4).
+const spfPTRNameLimit = 10
+
+// spfMaxDomainLength is the maximum length of an expanded domain-spec
+// (RFC 7208 section 7.3).
+const spfMaxDomainLength = 253
+
+// spfCheckParams represents the parameters for an SPF check_host evaluation.
+type spfCheckParams struct {
+	IP       string `json:"ip"`
+	MailFrom string `json:"mail_from"`
+	HELO     string `json:"helo"`
+}
+
+// SPFCheckStep represents one evaluated term during an SPF check.
+type SPFCheckStep struct {
+	Domain  string `json:"domain"`
+	Term    string `json:"term"`
+	Matched bool   `json:"matched"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+// SPFCheckResponse represents the complete SPF check_host response.
+type SPFCheckResponse struct {
+	IP              string           `json:"ip"`
+	MailFrom        string           `json:"mail_from,omitempty"`
+	HELO            string           `json:"helo,omitempty"`
+	Identity        string           `json:"identity"`
+	Domain          string           `json:"domain"`
+	DomainName      *domainname.Name `json:"domain_name,omitempty"`
+	Result          string           `json:"result"`
+	Mechanism       string           `json:"mechanism,omitempty"`
+	MatchedDomain   string           `json:"matched_domain,omitempty"`
+	Record          string           `json:"record,omitempty"`
+	Reason          string           `json:"reason"`
+	Explanation     string           `json:"explanation,omitempty"`
+	LookupCount     int              `json:"lookup_count"`
+	VoidLookupCount int              `json:"void_lookup_count"`
+	Steps           []SPFCheckStep   `json:"steps"`
+	Timestamp       string           `json:"timestamp"`
+}
+
+// HandleSPFCheck runs the RFC 7208 check_host() function for a client IP,
+// MAIL FROM address and HELO name, and reports the result and the
+// mechanism that produced it.
+func HandleSPFCheck(ctx context.Context, request mcp.CallToolRequest, config *Config) (*mcp.CallToolResult, error) {
+	var params spfCheckParams
+	if err := request.BindArguments(&params); err != nil {
+		return nil, fmt.Errorf("failed to parse tool input: %w", utils.ParseJSONUnmarshalError(err))
+	}
+
+	// Validate required parameters
+	if params.IP == "" {
+		return nil, fmt.Errorf("parameter \"ip\" is required")
+	}
+
+	if params.MailFrom == "" && params.HELO == "" {
+		return nil, fmt.Errorf("at least one of the parameters \"mail_from\" or \"helo\" is required")
+	}
+
+	ip, err := netip.ParseAddr(strings.TrimSpace(params.IP))
+	if err != nil {
+		return nil, fmt.Errorf("invalid IP address %q: %w", params.IP, err)
+	}
+
+	// Validate and normalize the domains of both identities
+	mailFrom, mailFromName, err := normalizeIdentity(params.MailFrom)
+	if err != nil {
+		return nil, err
+	}
+
+	helo, heloName, err := normalizeIdentity(params.HELO)
+	if err != nil {
+		return nil, err
+	}
+
+	result := checkSPF(ctx, config.lookup(), ip.Unmap(), mailFrom, helo)
+	result.DomainName = heloName
+	if mailFrom != "" {
+		result.DomainName = mailFromName
+	}
+	return resp.JSON(result)
+}
+
+// normalizeIdentity converts the domain of an SPF identity, either a MAIL
+// FROM address or a HELO name, to its ASCII form. The local part of an
+// address is kept as given, since macros expand it.
+func normalizeIdentity(identity string) (string, *domainname.Name, error) {
+	identity = strings.TrimSpace(identity)
+	if identity == "" {
+		return "", nil, nil
+	}
+
+	localPart, domain := "", identity
+	if at := strings.LastIndexByte(identity, '@'); at >= 0 {
+		localPart, domain = identity[:at+1], identity[at+1:]
+	}
+
+	name, err := domainname.Parse(domain)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return localPart + name.ASCII, name, nil
+}
+
+// checkSPF evaluates the SPF policy for the MAIL FROM identity or, when the
+// reverse-path is empty, for the HELO identity (RFC 7208 section 2.4).
+func checkSPF(ctx context.Context, lookup lookupFunc, ip netip.Addr, mailFrom, helo string) *SPFCheckResponse {
+	result := &SPFCheckResponse{
+		IP:        ip.String(),
+		MailFrom:  mailFrom,
+		HELO:      helo,
+		Identity:  "mail_from",
+		Steps:     make([]SPFCheckStep, 0),
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	// Split the sender into its local part and domain, defaulting the local
+	// part to "postmaster" as RFC 7208 section 4.3 requires
+	localPart, domain := "postmaster", strings.TrimSuffix(helo, ".")
+	if mailFrom != "" {
+		if at := strings.LastIndexByte(mailFrom, '@'); at >= 0 {
+			if mailFrom[:at] != "" {
+				localPart = mailFrom[:at]
+			}
+			domain = mailFrom[at+1:]
+		} else {
+			domain = mailFrom
+		}
+		domain = strings.TrimSuffix(domain, ".")
+	} else {
+		result.Identity = "helo"
+	}
+
+	e := &spfEvaluator{
+		lookup:       lookup,
+		ip:           ip,
+		sender:       localPart + "@" + domain,
+		localPart:    localPart,
+		senderDomain: domain,
+		helo:         helo,
+	}
+
+	outcome := e.checkHost(ctx, domain)
+
+	result.Domain = domain
+	result.Result = outcome.Result
+	result.Mechanism = outcome.Mechanism
+	if outcome.Mechanism != "" {
+		result.MatchedDomain = outcome.Domain
+	}
+	result.Record = outcome.Record
+	result.Reason = outcome.Reason
+	result.Explanation = outcome.Explanation
+	result.LookupCount = e.lookups
+	result.VoidLookupCount = e.voidLookups
+	result.Steps = append(result.Steps, e.steps...)
+
+	return result
+}
+
+// spfOutcome is the result of evaluating the SPF record of one domain.
+type spfOutcome struct {
+	Result      string
+	Domain      string
+	Record      string
+	Mechanism   string
+	Reason      string
+	Explanation string
+}
+
+// spfEvalError aborts an evaluation with a temperror or permerror result.
+type spfEvalError struct {
+	Result string
+	Err    error
+}
+
+func (e *spfEvalError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *spfEvalError) Unwrap() error {
+	return e.Err
+}
+
+// permError returns an error that produces a permerror result.
+func permError(format string, args ...any) error {
+	return &spfEvalError{Result: spfResultPermError, Err: fmt.Errorf(format, args...)}
+}
+
+// evalError converts an error into one carrying an SPF result, treating DNS
+// failures as temperror and anything else as permerror.
+func evalError(err error) *spfEvalError {
+	var evalErr *spfEvalError
+	if errors.As(err, &evalErr) {
+		return evalErr
+	}
+
+	var dnsErr *dnsError
+	if errors.As(err, &dnsErr) {
+		return &spfEvalError{Result: spfResultTempError, Err: err}
+	}
+
+	return &spfEvalError{Result: spfResultPermError, Err: err}
+}
+
+// spfEvaluator holds the state shared across a check_host evaluation,
+// including the lookup counters that span every included record.
+type spfEvaluator struct {
+	lookup       lookupFunc
+	ip           netip.Addr
+	sender       string
+	localPart    string
+	senderDomain string
+	helo         string
+	lookups      int
+	voidLookups  int
+	steps        []SPFCheckStep
+
+	// validatedNames caches the validated PTR names of the client IP
+	validatedNames []string
+	validated      bool
+}
+
+// checkHost implements the check_host() function of RFC 7208 section 4 for
+// the given domain.
+func (e *spfEvaluator) checkHost(ctx context.Context, domain string) spfOutcome {
+	outcome := spfOutcome{Domain: domain}
+
+	if _, ok := dns.IsDomainName(domain); !ok || !strings.Contains(domain, ".") {
+		outcome.Result = spfResultNone
+		outcome.Reason = fmt.Sprintf("%q is not a valid domain name", domain)
+		return outcome
+	}
+
+	record, err := fetchSPFRecord(ctx, e.lookup, domain)
+	if err != nil {
+		switch {
+		case errors.Is(err, errSPFDomainNotFound), errors.Is(err, errSPFRecordNotFound):
+			outcome.Result = spfResultNone
+		default:
+			outcome.Result = evalError(err).Result
+		}
+		outcome.Reason = fmt.Sprintf("%s: %v", domain, err)
+		return outcome
+	}
+	outcome.Record = record
+
+	terms, syntaxErrors := parseSPFRecord(record)
+	if len(syntaxErrors) > 0 {
+		outcome.Result = spfResultPermError
+		outcome.Reason = fmt.Sprintf("%s: %s", domain, syntaxErrors[0])
+		return outcome
+	}
+
+	var redirect, exp *SPFTerm
+	for i := range terms {
+		term := &terms[i]
+		switch term.Name {
+		case "redirect", "exp":
+			if (term.Name == "redirect" && redirect != nil) || (term.Name == "exp" && exp != nil) {
+				outcome.Result = spfResultPermError
+				outcome.Reason = fmt.Sprintf("%s: duplicate %q modifier", domain, term.Name)
+				return outcome
+			}
+			if term.Name == "redirect" {
+				redirect = term
+			} else {
+				exp = term
+			}
+		}
+	}
+
+	for _, term := range terms {
+		if term.Kind != "mechanism" {
+			continue
+		}
+
+		matched, err := e.matchMechanism(ctx, domain, term)
+		step := SPFCheckStep{Domain: domain, Term: term.Term, Matched: matched}
+		if err != nil {
+			evalErr := evalError(err)
+			step.Detail = evalErr.Error()
+			e.steps = append(e.steps, step)
+
+			outcome.Result = evalErr.Result
+			outcome.Mechanism = term.Term
+			outcome.Reason = fmt.Sprintf("%s: evaluating %q: %v", domain, term.Term, evalErr)
+			return outcome
+		}
+		e.steps = append(e.steps, step)
+
+		if matched {
+			outcome.Result = term.Qualifier
+			outcome.Mechanism = term.Term
+			outcome.Reason = fmt.Sprintf("client IP %s matched %q in the SPF record of %s", e.ip, term.Term, domain)
+			if outcome.Result == spfResultFail && exp != nil {
+				outcome.Explanation = e.explanation(ctx, domain, exp.Value)
+			}
+			return outcome
+		}
+	}
+
+	if redirect != nil {
+		return e.followRedirect(ctx, domain, redirect)
+	}
+
+	// No mechanism matched and there is no redirect
+	outcome.Result = spfResultNeutral
+	outcome.Reason = fmt.Sprintf("no mechanism in the SPF record of %s matched client IP %s; the default result is neutral", domain, e.ip)
+	return outcome
+}
+
+// followRedirect evaluates the record referenced by a "redirect" modifier.
+func (e *spfEvaluator) followRedirect(ctx context.Context, domain string, redirect *SPFTerm) spfOutcome {
+	step := SPFCheckStep{Domain: domain, Term: redirect.Term}
+
+	target, err := e.redirectTarget(ctx, domain, redirect.Value)
+	if err != nil {
+		evalErr := evalError(err)
+		step.Detail = evalErr.Error()
+		e.steps = append(e.steps, step)
+
+		return spfOutcome{
+			Result:    evalErr.Result,
+			Domain:    domain,
+			Mechanism: redirect.Term,
+			Reason:    fmt.Sprintf("%s: evaluating %q: %v", domain, redirect.Term, evalErr),
+		}
+	}
+
+	step.Detail = "following redirect to " + target
+	e.steps = append(e.steps, step)
+
+	outcome := e.checkHost(ctx, target)
+	if outcome.Result == spfResultNone {
+		outcome.Result = spfResultPermError
+		outcome.Reason = fmt.Sprintf("%s: redirect target %s does not publish an SPF record: %s", domain, target, outcome.Reason)
+	}
+	return outcome
+}
+
+// redirectTarget counts the lookup of a "redirect" modifier and expands its
+// domain-spec.
+func (e *spfEvaluator) redirectTarget(ctx context.Context, domain, spec string) (string, error) {
+	if err := e.countLookup(); err != nil {
+		return "", err
+	}
+	return e.expandDomainSpec(ctx, spec, domain)
+}
+
+// countLookup records a term that causes DNS lookups and fails once the
+// limit of RFC 7208 section 4.6.4 is exceeded.
+func (e *spfEvaluator) countLookup() error {
+	e.lookups++
+	if e.lookups > spfLookupLimit {
+		return permError("more than %d DNS lookups are required", spfLookupLimit)
+	}
+	return nil
+}
+
+// countVoid records a lookup that returned no records and fails once the
+// void lookup limit is exceeded.
+func (e *spfEvaluator) countVoid() error {
+	e.voidLookups++
+	if e.voidLookups > spfVoidLookupLimit {
+		return permError("more than %d void DNS lookups", spfVoidLookupLimit)
+	}
+	return nil
+}
+
+// matchMechanism reports whether the client IP matches a mechanism.
+func (e *spfEvaluator) matchMechanism(ctx context.Context, domain string, term SPFTerm) (bool, error) {
+	switch term.Name {
+	case "all":
+		return true, nil
+
+	case "ip4", "ip6":
+		prefix, err := netip.ParsePrefix(term.Value)
+		if err != nil {
+			return false, permError("invalid network %q", term.Value)
+		}
+		return prefix.Contains(e.ip), nil
+
+	case "include":
+		if err := e.countLookup(); err != nil {
+			return false, err
+		}
+
+		target, err := e.expandDomainSpec(ctx, term.Value, domain)
+		if err != nil {
+			return false, err
+		}
+
+		// Map the included result as described in RFC 7208 section 5.2
+		outcome := e.checkHost(ctx, target)
+		switch outcome.Result {
+		case spfResultPass:
+			return true, nil
+		case spfResultFail, spfResultSoftFail, spfResultNeutral:
+			return false, nil
+		case spfResultTempError:
+			return false, &spfEvalError{Result: spfResultTempError, Err: errors.New(outcome.Reason)}
+		default:
+			return false, permError("included domain %s: %s", target, outcome.Reason)
+		}
+
+	case "a":
+		if err := e.countLookup(); err != nil {
+			return false, err
+		}
+
+		target, err := e.targetName(ctx, domain, term.Value)
+		if err != nil {
+			return false, err
+		}
+
+		addresses, err := e.addresses(ctx, target)
+		if err != nil {
+			return false, err
+		}
+
+		if len(addresses) == 0 {
+			return false, e.countVoid()
+		}
+
+		return e.containsIP(addresses, term), nil
+
+	case "mx":
+		if err := e.countLookup(); err != nil {
+			return false, err
+		}
+
+		target, err := e.targetName(ctx, domain, term.Value)
+		if err != nil {
+			return false, err
+		}
+
+		hosts, err := resolveMX(ctx, e.lookup, target)
+		if err != nil {
+			return false, err
+		}
+
+		if len(hosts) == 0 {
+			return false, e.countVoid()
+		}
+
+		if len(hosts) > spfMXHostLimit {
+			return false, permError("%s has %d MX records, exceeding the limit of %d", target, len(hosts), spfMXHostLimit)
+		}
+
+		for _, host := range hosts {
+			addresses, err := e.addresses(ctx, host)
+			if err != nil {
+				return false, err
+			}
+
+			if e.containsIP(addresses, term) {
+				return true, nil
+			}
+		}
+		return false, nil
+
+	case "ptr":
+		if err := e.countLookup(); err != nil {
+			return false, err
+		}
+
+		target, err := e.targetName(ctx, domain, term.Value)
+		if err != nil {
+			return false, err
+		}
+
+		target = strings.ToLower(target)
+		for _, name := range e.validatedPTRNames(ctx) {
+			if name == target || strings.HasSuffix(name, "."+target) {
+				return true, nil
+			}
+		}
+		return false, nil
+
+	case "exists":
+		if err := e.countLookup(); err != nil {
+			return false, err
+		}
+
+		target, err := e.expandDomainSpec(ctx, term.Value, domain)
+		if err != nil {
+			return false, err
+		}
+
+		// The "exists" mechanism always uses an A query, even for IPv6 clients
+		response, err := query(ctx, e.lookup, target, dns.TypeA)
+		if err != nil {
+			return false, err
+		}
+
+		if len(response.Answer) == 0 {
+			return false, e.countVoid()
+		}
+		return true, nil
+	}
+
+	return false, permError("unknown mechanism %q", term.Name)
+}
+
+// targetName returns the expanded domain-spec of a mechanism, or the current
+// domain when none is given.
+func (e *spfEvaluator) targetName(ctx context.Context, domain, spec string) (string, error) {
+	if spec == "" {
+		return domain, nil
+	}
+	return e.expandDomainSpec(ctx, spec, domain)
+}
+
+// addresses returns the addresses of a host in the client IP's family.
+func (e *spfEvaluator) addresses(ctx context.Context, host string) ([]netip.Addr, error) {
+	qtype := dns.TypeA
+	if e.ip.Is6() {
+		qtype = dns.TypeAAAA
+	}
+
+	response, err := query(ctx, e.lookup, host, qtype)
+	if err != nil {
+		return nil, err
+	}
+
+	var addresses []netip.Addr
+	for _, rr := range response.Answer {
+		switch rec := rr.(type) {
+		case *dns.A:
+			if addr, ok := netip.AddrFromSlice(rec.A.To4()); ok {
+				addresses = append(addresses, addr)
+			}
+		case *dns.AAAA:
+			if addr, ok := netip.AddrFromSlice(rec.AAAA); ok {
+				addresses = append(addresses, addr)
+			}
+		}
+	}
+
+	return addresses, nil
+}
+
+// containsIP reports whether the client IP falls within the network of any
+// address, using the prefix lengths of the mechanism.
+func (e *spfEvaluator) containsIP(addresses []netip.Addr, term SPFTerm) bool {
+	for _, addr := range addresses {
+		if mechanismPrefix(addr, term.CIDR4, term.CIDR6).Contains(e.ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// validatedPTRNames returns the PTR names of the client IP whose forward
+// lookups include the client IP (RFC 7208 section 5.5). DNS errors cause
+// the affected names, or the whole lookup, to be skipped.
+func (e *spfEvaluator) validatedPTRNames(ctx context.Context) []string {
+	if e.validated {
+		return e.validatedNames
+	}
+	e.validated = true
+
+	arpa, err := dns.ReverseAddr(e.ip.String())
+	if err != nil {
+		return nil
+	}
+
+	response, err := query(ctx, e.lookup, arpa, dns.TypePTR)
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, rr := range response.Answer {
+		if ptr, ok := rr.(*dns.PTR); ok {
+			names = append(names, ptr.Ptr)
+		}
+	}
+
+	if len(names) > spfPTRNameLimit {
+		names = names[:spfPTRNameLimit]
+	}
+
+	for _, name := range names {
+		addresses, err := e.addresses(ctx, name)
+		if err != nil {
+			continue
+		}
+
+		if slices.Contains(addresses, e.ip) {
+			e.validatedNames = append(e.validatedNames, strings.ToLower(strings.TrimSuffix(name, ".")))
+		}
+	}
+
+	return e.validatedNames
+}
+
+// explanation builds the explanation string of an "exp" modifier. Errors
+// are ignored, as RFC 7208 section 6.2 requires.
+func (e *spfEvaluator) explanation(ctx context.Context, domain, spec string) string {
+	target, err := e.expandDomainSpec(ctx, spec, domain)
+	if err != nil {
+		return ""
+	}
+
+	txt, err := lookupTXT(ctx, e.lookup, target)
+	if err != nil || len(txt.Records) != 1 {
+		return ""
+	}
+
+	text, err := e.expandMacros(ctx, txt.Records[0], domain, true)
+	if err != nil {
+		return ""
+	}
+	return text
+}
+
+// expandDomainSpec expands the macros of a domain-spec and shortens the
+// result to at most 253 characters by dropping labels from the left.
+func (e *spfEvaluator) expandDomainSpec(ctx context.Context, spec, domain string) (string, error) {
+	expanded, err := e.expandMacros(ctx, spec, domain, false)
+	if err != nil {
+		return "", err
+	}
+
+	expanded = strings.TrimSuffix(expanded, ".")
+	for len(expanded) > spfMaxDomainLength {
+		dot := strings.IndexByte(expanded, '.')
+		if dot == -1 {
+			return "", permError("expanded domain %q is too long", expanded)
+		}
+		expanded = expanded[dot+1:]
+	}
+
+	return expanded, nil
+}
+
+// expandMacros expands a macro-string as described in RFC 7208 section 7.
+// The "c", "r" and "t" macros are only allowed in explanation strings.
+func (e *spfEvaluator) expandMacros(ctx context.Context, spec, domain string, explanation bool) (string, error) {
+	var b strings.Builder
+
+	for i := 0; i < len(spec); {
+		if spec[i] != '%' {
+			b.WriteByte(spec[i])
+			i++
+			continue
+		}
+
+		rest := spec[i:]
+		switch {
+		case strings.HasPrefix(rest, "%%"):
+			b.WriteByte('%')
+			i += 2
+			continue
+		case strings.HasPrefix(rest, "%_"):
+			b.WriteByte(' ')
+			i += 2
+			continue
+		case strings.HasPrefix(rest, "%-"):
+			b.WriteString("%20")
+			i += 2
+			continue
+		}
+
+		match := spfMacro.FindStringSubmatch(rest)
+		if match == nil {
+			return "", permError("invalid macro in %q", spec)
+		}
+		i += len(match[0])
+
+		letter := strings.ToLower(match[1])
+		if !explanation && strings.Contains("crt", letter) {
+			return "", permError("macro %q is only allowed in explanation strings", match[0])
+		}
+
+		value := e.macroValue(ctx, letter, domain)
+
+		transformed, err := transformMacro(value, match[2], match[3] != "", match[4])
+		if err != nil {
+			return "", err
+		}
+
+		// Uppercase macro letters are URL escaped
+		if match[1] != letter {
+			transformed = urlEscape(transformed)
+		}
+
+		b.WriteString(transformed)
+	}
+
+	return b.String(), nil
+}
+
+// macroValue returns the unmodified value of a macro letter.
+func (e *spfEvaluator) macroValue(ctx context.Context, letter, domain string) string {
+	switch letter {
+	case "s":
+		return e.sender
+	case "l":
+		return e.localPart
+	case "o":
+		return e.senderDomain
+	case "d":
+		return domain
+	case "i":
+		return dottedIP(e.ip)
+	case "p":
+		return e.validatedDomain(ctx, domain)
+	case "v":
+		if e.ip.Is4() {
+			return "in-addr"
+		}
+		return "ip6"
+	case "h":
+		if e.helo == "" {
+			return e.senderDomain
+		}
+		return e.helo
+	case "c":
+		return e.ip.String()
+	case "r":
+		return "unknown"
+	case "t":
+		return strconv.FormatInt(time.Now().Unix(), 10)
+	}
+	return ""
+}
+
+// validatedDomain returns the value of the "p" macro: a validated PTR name
+// of the client IP, preferring the current domain and its subdomains.
+func (e *spfEvaluator) validatedDomain(ctx context.Context, domain string) string {
+	names := e.validatedPTRNames(ctx)
+	if len(names) == 0 {
+		return "unknown"
+	}
+
+	domain = strings.ToLower(domain)
+	if slices.Contains(names, domain) {
+		return domain
+	}
+
+	for _, name := range names {
+		if strings.HasSuffix(name, "."+domain) {
+			return name
+		}
+	}
+
+	return names[0]
+}
+
+// transformMacro splits a macro value on its delimiters, optionally reverses
+// the parts and keeps the rightmost ones, then joins them with dots.
+func transformMacro(value, digits string, reverse bool, delimiters string) (string, error) {
+	if delimiters == "" {
+		delimiters = "."
+	}
+
+	// Every delimiter splits, so consecutive ones leave empty parts behind
+	// (RFC 7208 section 7.3)
+	var parts []string
+	for {
+		i := strings.IndexAny(value, delimiters)
+		if i < 0 {
+			parts = append(parts, value)
+			break
+		}
+		parts = append(parts, value[:i])
+		value = value[i+1:]
+	}
+
+	if reverse {
+		slices.Reverse(parts)
+	}
+
+	if digits != "" {
+		keep, err := strconv.Atoi(digits)
+		if err != nil || keep == 0 {
+			return "", permError("invalid macro transformer %q", digits)
+		}
+		if keep < len(parts) {
+			parts = parts[len(parts)-keep:]
+		}
+	}
+
+	return strings.Join(parts, "."), nil
+}
+
+// dottedIP returns the "i" macro form of an address: dotted decimal for
+// IPv4 and dot-separated nibbles for IPv6.
+func dottedIP(ip netip.Addr) string {
+	if ip.Is4() {
+		return ip.String()
+	}
+
+	const hexDigits = "0123456789abcdef"
+	bytes := ip.As16()
+	nibbles := make([]string, 0, 32)
+	for _, b := range bytes {
+		nibbles = append(nibbles, string(hexDigits[b>>4]), string(hexDigits[b&0x0f]))
+	}
+	return strings.Join(nibbles, ".")
+}
+
+// urlEscape escapes every character outside the URI unreserved set.
+func urlEscape(value string) string {
+	const hexDigits = "0123456789ABCDEF"
+
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			c == '-', c == '.', c == '_', c == '~':
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hexDigits[c>>4])
+			b.WriteByte(hexDigits[c&0x0f])
+		}
+	}
+	return b.String()
+}
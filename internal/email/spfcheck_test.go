@@ -0,0 +1,196 @@
+package email
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandMacros(t *testing.T) {
+	// Examples from RFC 7208 section 7.4
+	e := &spfEvaluator{
+		ip:           netip.MustParseAddr("192.0.2.3"),
+		sender:       "strong-bad@email.example.com",
+		localPart:    "strong-bad",
+		senderDomain: "email.example.com",
+		validated:    true,
+	}
+
+	tests := []struct {
+		spec     string
+		expected string
+	}{
+		{"%{s}", "strong-bad@email.example.com"},
+		{"%{o}", "email.example.com"},
+		{"%{d}", "email.example.com"},
+		{"%{d4}", "email.example.com"},
+		{"%{d3}", "email.example.com"},
+		{"%{d2}", "example.com"},
+		{"%{d1}", "com"},
+		{"%{dr}", "com.example.email"},
+		{"%{d2r}", "example.email"},
+		{"%{l}", "strong-bad"},
+		{"%{l-}", "strong.bad"},
+		{"%{lr}", "strong-bad"},
+		{"%{lr-}", "bad.strong"},
+		{"%{l1r-}", "strong"},
+		{"%{ir}.%{v}._spf.%{d2}", "3.2.0.192.in-addr._spf.example.com"},
+		{"%{lr-}.lp._spf.%{d2}", "bad.strong.lp._spf.example.com"},
+		{"%{lr-}.lp.%{ir}.%{v}._spf.%{d2}", "bad.strong.lp.3.2.0.192.in-addr._spf.example.com"},
+		{"%{ir}.%{v}.%{l1r-}.lp._spf.%{d2}", "3.2.0.192.in-addr.strong.lp._spf.example.com"},
+		{"%{d2}.trusted-domains.example.net", "example.com.trusted-domains.example.net"},
+		{"%{p}.example.net", "unknown.example.net"},
+		{"%{S}", "strong-bad%40email.example.com"},
+		{"100%% %_%-", "100%  %20"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			expanded, err := e.expandMacros(context.Background(), tt.spec, "email.example.com", false)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, expanded)
+		})
+	}
+
+	t.Run("ipv6", func(t *testing.T) {
+		e := &spfEvaluator{ip: netip.MustParseAddr("2001:db8::cb01")}
+		expanded, err := e.expandMacros(context.Background(), "%{ir}.%{v}._spf.%{d2}", "email.example.com", false)
+		require.NoError(t, err)
+		assert.Equal(t, "1.0.b.c.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6._spf.example.com", expanded)
+	})
+
+	t.Run("explanation only macros", func(t *testing.T) {
+		_, err := e.expandMacros(context.Background(), "%{c}.example.com", "email.example.com", false)
+		require.Error(t, err)
+
+		expanded, err := e.expandMacros(context.Background(), "%{c} is not allowed", "email.example.com", true)
+		require.NoError(t, err)
+		assert.Equal(t, "192.0.2.3 is not allowed", expanded)
+	})
+
+	t.Run("zero transformer", func(t *testing.T) {
+		_, err := e.expandMacros(context.Background(), "%{d0}", "email.example.com", false)
+		require.Error(t, err)
+	})
+}
+
+func TestTransformMacro(t *testing.T) {
+	tests := []struct {
+		value      string
+		digits     string
+		reverse    bool
+		delimiters string
+		expected   string
+	}{
+		{value: "strong-bad", delimiters: "-", expected: "strong.bad"},
+		{value: "strong--bad", delimiters: "-", expected: "strong..bad"},
+		{value: "a.b+c", delimiters: "+", expected: "a.b.c"},
+		{value: "-strong-bad-", reverse: true, delimiters: "-", expected: ".bad.strong."},
+		{value: "strong--bad", digits: "2", delimiters: "-", expected: ".bad"},
+		{value: "email.example.com", digits: "2", reverse: true, expected: "example.email"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			transformed, err := transformMacro(tt.value, tt.digits, tt.reverse, tt.delimiters)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, transformed)
+		})
+	}
+}
+
+func TestCheckSPF(t *testing.T) {
+	lookup := fakeZone(t,
+		`example.com. 300 IN TXT "v=spf1 ip4:192.0.2.0/24 mx include:_spf.example.net exists:%{ir}.allow.example.com -all exp=explain.example.com"`,
+		`explain.example.com. 300 IN TXT "%{i} is not allowed to send mail for %{d}"`,
+		`example.com. 300 IN MX 10 mail.example.com.`,
+		`mail.example.com. 300 IN A 198.51.100.25`,
+		`mail.example.com. 300 IN AAAA 2001:db8::25`,
+		`_spf.example.net. 300 IN TXT "v=spf1 ip4:203.0.113.0/24 ?all"`,
+		`7.0.0.10.allow.example.com. 300 IN A 127.0.0.2`,
+		`redirected.example.com. 300 IN TXT "v=spf1 redirect=example.com"`,
+		`softfail.example.com. 300 IN TXT "v=spf1 a:mail.example.com ~all"`,
+		`broken.example.com. 300 IN TXT "v=spf1 ip4:192.0.2.300 -all"`,
+		`loop.example.com. 300 IN TXT "v=spf1 include:loop.example.com -all"`,
+		`nospf.example.com. 300 IN TXT "hello"`,
+		`noinclude.example.com. 300 IN TXT "v=spf1 include:nospf.example.com -all"`,
+	)
+
+	tests := []struct {
+		name      string
+		ip        string
+		mailFrom  string
+		helo      string
+		result    string
+		mechanism string
+	}{
+		{name: "ip4 match", ip: "192.0.2.10", mailFrom: "user@example.com", result: spfResultPass, mechanism: "ip4:192.0.2.0/24"},
+		{name: "mx match ipv6", ip: "2001:db8::25", mailFrom: "user@example.com", result: spfResultPass, mechanism: "mx"},
+		{name: "ipv4 mapped address", ip: "::ffff:198.51.100.25", mailFrom: "user@example.com", result: spfResultPass, mechanism: "mx"},
+		{name: "include match", ip: "203.0.113.7", mailFrom: "user@example.com", result: spfResultPass, mechanism: "include:_spf.example.net"},
+		{name: "exists match", ip: "10.0.0.7", mailFrom: "user@example.com", result: spfResultPass, mechanism: "exists:%{ir}.allow.example.com"},
+		{name: "fail", ip: "198.51.100.99", mailFrom: "user@example.com", result: spfResultFail, mechanism: "-all"},
+		{name: "redirect", ip: "198.51.100.99", mailFrom: "user@redirected.example.com", result: spfResultFail, mechanism: "-all"},
+		{name: "softfail", ip: "198.51.100.99", mailFrom: "user@softfail.example.com", result: spfResultSoftFail, mechanism: "~all"},
+		{name: "helo identity", ip: "198.51.100.25", helo: "softfail.example.com", result: spfResultPass, mechanism: "a:mail.example.com"},
+		{name: "no record", ip: "192.0.2.1", mailFrom: "user@nospf.example.com", result: spfResultNone},
+		{name: "nonexistent domain", ip: "192.0.2.1", mailFrom: "user@missing.example.com", result: spfResultNone},
+		{name: "syntax error", ip: "192.0.2.1", mailFrom: "user@broken.example.com", result: spfResultPermError},
+		{name: "include loop", ip: "192.0.2.1", mailFrom: "user@loop.example.com", result: spfResultPermError, mechanism: "include:loop.example.com"},
+		{name: "include without record", ip: "192.0.2.1", mailFrom: "user@noinclude.example.com", result: spfResultPermError, mechanism: "include:nospf.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ip := netip.MustParseAddr(tt.ip).Unmap()
+			result := checkSPF(context.Background(), lookup, ip, tt.mailFrom, tt.helo)
+			assert.Equal(t, tt.result, result.Result, result.Reason)
+			assert.Equal(t, tt.mechanism, result.Mechanism)
+		})
+	}
+
+	t.Run("explanation", func(t *testing.T) {
+		result := checkSPF(context.Background(), lookup, netip.MustParseAddr("198.51.100.99"), "user@example.com", "")
+		assert.Equal(t, "198.51.100.99 is not allowed to send mail for example.com", result.Explanation)
+		assert.Equal(t, 3, result.LookupCount)
+	})
+
+	t.Run("temporary dns error", func(t *testing.T) {
+		failing := func(context.Context, string, uint16) (*dns.Msg, error) {
+			return nil, assert.AnError
+		}
+		result := checkSPF(context.Background(), failing, netip.MustParseAddr("192.0.2.1"), "user@example.com", "")
+		assert.Equal(t, spfResultTempError, result.Result)
+	})
+}
+
+func TestNormalizeIdentity(t *testing.T) {
+	tests := []struct {
+		identity string
+		want     string
+		unicode  string
+	}{
+		{identity: "user@Bücher.DE", want: "user@xn--bcher-kva.de", unicode: "bücher.de"},
+		{identity: " mail.example.com. ", want: "mail.example.com", unicode: "mail.example.com"},
+		{identity: "@example.com", want: "@example.com", unicode: "example.com"},
+	}
+
+	for _, tt := range tests {
+		got, name, err := normalizeIdentity(tt.identity)
+		require.NoError(t, err, tt.identity)
+		assert.Equal(t, tt.want, got)
+		assert.Equal(t, tt.unicode, name.Unicode)
+	}
+
+	got, name, err := normalizeIdentity("")
+	require.NoError(t, err)
+	assert.Empty(t, got)
+	assert.Nil(t, name)
+
+	_, _, err = normalizeIdentity("user@example..com")
+	assert.Error(t, err)
+}
@@ -4,17 +4,23 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptrace"
 	"net/url"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/domainname"
 	resp "github.com/patrickdappollonio/mcp-domaintools/internal/response"
 	"github.com/patrickdappollonio/mcp-domaintools/internal/utils"
 )
 
+// UserAgent is the User-Agent header sent with every request.
+const UserAgent = "mcp-domaintools/http_ping"
+
 // Config holds HTTP ping configuration.
 type Config struct {
 	Timeout time.Duration
@@ -48,6 +54,7 @@ type HTTPPingResult struct {
 // HTTPPingResponse represents the complete HTTP ping response.
 type HTTPPingResponse struct {
 	Target       string           `json:"target"`
+	DomainName   *domainname.Name `json:"domain_name,omitempty"`
 	Method       string           `json:"method"`
 	RequestsSent int              `json:"requests_sent"`
 	SuccessCount int              `json:"success_count"`
@@ -89,9 +96,12 @@ func HandleHTTPPing(ctx context.Context, request mcp.CallToolRequest, config *Co
 		return nil, fmt.Errorf("invalid URL: %w", err)
 	}
 
-	// Ensure we have a scheme
+	// Ensure we have a scheme. Without one, the host is parsed as part of the
+	// path, so the URL is parsed again as an HTTPS URL.
 	if parsedURL.Scheme == "" {
-		parsedURL.Scheme = "https"
+		if parsedURL, err = url.Parse("https://" + params.URL); err != nil {
+			return nil, fmt.Errorf("invalid URL: %w", err)
+		}
 	}
 
 	// Validate scheme
@@ -99,6 +109,19 @@ func HandleHTTPPing(ctx context.Context, request mcp.CallToolRequest, config *Co
 		return nil, fmt.Errorf("unsupported URL scheme: %s", parsedURL.Scheme)
 	}
 
+	// Validate and normalize the host name, unless it's an IP address
+	var name *domainname.Name
+	if host := parsedURL.Hostname(); net.ParseIP(host) == nil {
+		if name, err = domainname.Parse(host); err != nil {
+			return nil, err
+		}
+
+		parsedURL.Host = name.ASCII
+		if port := parsedURL.Port(); port != "" {
+			parsedURL.Host = net.JoinHostPort(name.ASCII, port)
+		}
+	}
+
 	// Set default method if not provided
 	method := strings.ToUpper(params.Method)
 	if method == "" {
@@ -125,6 +148,7 @@ func HandleHTTPPing(ctx context.Context, request mcp.CallToolRequest, config *Co
 	if err != nil {
 		return nil, fmt.Errorf("HTTP ping failed: %w", err)
 	}
+	httpResponse.DomainName = name
 
 	// Use the response package to handle JSON encoding and MCP tool result creation
 	return resp.JSON(httpResponse)
@@ -227,13 +251,8 @@ func performSingleHTTPPing(ctx context.Context, method string, parsedURL *url.UR
 		},
 	}
 
-	// Create HTTP client with trace
-	client := &http.Client{
-		Timeout: timeout,
-		Transport: &http.Transport{
-			DisableKeepAlives: true, // Ensure fresh connections for accurate timing
-		},
-	}
+	// Create HTTP client
+	client := NewClient(timeout)
 
 	// Create request
 	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), method, parsedURL.String(), nil)
@@ -244,7 +263,7 @@ func performSingleHTTPPing(ctx context.Context, method string, parsedURL *url.UR
 	}
 
 	// Set User-Agent
-	req.Header.Set("User-Agent", "mcp-domaintools/http_ping")
+	req.Header.Set("User-Agent", UserAgent)
 
 	// Record start time
 	startTime := time.Now()
@@ -299,6 +318,73 @@ func performSingleHTTPPing(ctx context.Context, method string, parsedURL *url.UR
 	return result
 }
 
+// ProbeResult is the outcome of a single HTTP request made to inspect an
+// origin rather than to measure it.
+type ProbeResult struct {
+	StatusCode int    `json:"status_code"`
+	Protocol   string `json:"protocol"`
+	AltSvc     string `json:"alt_svc,omitempty"`
+}
+
+// Probe sends a single GET request to the URL and reports the HTTP version
+// used and the Alt-Svc header (RFC 7838) the origin advertises.
+func Probe(ctx context.Context, rawURL string, timeout time.Duration) (*ProbeResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", UserAgent)
+
+	resp, err := NewClient(timeout).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	return &ProbeResult{
+		StatusCode: resp.StatusCode,
+		Protocol:   resp.Proto,
+		AltSvc:     strings.Join(resp.Header.Values("Alt-Svc"), ", "),
+	}, nil
+}
+
+// AltSvcProtocols returns the protocol IDs advertised in an Alt-Svc header
+// value, such as "h3" in `h3=":443"; ma=86400`. The "clear" value, which
+// invalidates the alternatives, yields no protocols.
+func AltSvcProtocols(header string) []string {
+	var protocols []string
+	for _, alternative := range strings.Split(header, ",") {
+		protocol, _, found := strings.Cut(strings.TrimSpace(alternative), "=")
+		if !found || protocol == "" {
+			continue
+		}
+
+		// Protocol IDs are percent-encoded (RFC 7838 section 3)
+		if unescaped, err := url.PathUnescape(protocol); err == nil {
+			protocol = unescaped
+		}
+
+		if !slices.Contains(protocols, protocol) {
+			protocols = append(protocols, protocol)
+		}
+	}
+	return protocols
+}
+
+// NewClient returns the HTTP client used for HTTP pings. Keep-alives are
+// disabled so every request uses a fresh connection and reports accurate
+// timings.
+func NewClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DisableKeepAlives: true,
+		},
+	}
+}
+
 // updateTimingStats updates the timing statistics with a successful result.
 func updateTimingStats(stats *timingStats, result HTTPPingResult) {
 	stats.totalDNS += result.DNSTime
@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"strings"
 	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/domainname"
 	resp "github.com/patrickdappollonio/mcp-domaintools/internal/response"
 	"github.com/patrickdappollonio/mcp-domaintools/internal/utils"
 	"golang.org/x/net/icmp"
@@ -37,16 +39,17 @@ type PingResult struct {
 
 // PingResponse represents the complete ping response.
 type PingResponse struct {
-	Target          string       `json:"target"`
-	ResolvedIP      string       `json:"resolved_ip"`
-	PacketsSent     int          `json:"packets_sent"`
-	PacketsReceived int          `json:"packets_received"`
-	PacketLoss      float64      `json:"packet_loss_percent"`
-	Results         []PingResult `json:"results"`
-	MinRTT          float64      `json:"min_rtt_ms"`
-	MaxRTT          float64      `json:"max_rtt_ms"`
-	AvgRTT          float64      `json:"avg_rtt_ms"`
-	Timestamp       string       `json:"timestamp"`
+	Target          string           `json:"target"`
+	DomainName      *domainname.Name `json:"domain_name,omitempty"`
+	ResolvedIP      string           `json:"resolved_ip"`
+	PacketsSent     int              `json:"packets_sent"`
+	PacketsReceived int              `json:"packets_received"`
+	PacketLoss      float64          `json:"packet_loss_percent"`
+	Results         []PingResult     `json:"results"`
+	MinRTT          float64          `json:"min_rtt_ms"`
+	MaxRTT          float64          `json:"max_rtt_ms"`
+	AvgRTT          float64          `json:"avg_rtt_ms"`
+	Timestamp       string           `json:"timestamp"`
 }
 
 // rttStats holds statistics for round-trip time calculations.
@@ -69,6 +72,19 @@ func HandlePing(ctx context.Context, request mcp.CallToolRequest, config *Config
 		return nil, fmt.Errorf("parameter \"target\" is required")
 	}
 
+	// Validate and normalize the host name, unless it's an IP address
+	var name *domainname.Name
+	target := strings.TrimSpace(params.Target)
+	if ip := net.ParseIP(strings.Trim(target, "[]")); ip != nil {
+		target = ip.String()
+	} else {
+		var err error
+		if name, err = domainname.Parse(target); err != nil {
+			return nil, err
+		}
+		target = name.ASCII
+	}
+
 	// Set default count if not provided
 	count := config.Count
 	if params.Count != nil && *params.Count > 0 {
@@ -80,13 +96,13 @@ func HandlePing(ctx context.Context, request mcp.CallToolRequest, config *Config
 	defer cancel()
 
 	// Resolve the target to an IP address
-	resolvedIP, err := net.DefaultResolver.LookupHost(ctxWithTimeout, params.Target)
+	resolvedIP, err := net.DefaultResolver.LookupHost(ctxWithTimeout, target)
 	if err != nil {
-		return nil, fmt.Errorf("failed to resolve target %s: %w", params.Target, err)
+		return nil, fmt.Errorf("failed to resolve target %s: %w", target, err)
 	}
 
 	if len(resolvedIP) == 0 {
-		return nil, fmt.Errorf("no IP addresses found for target %s", params.Target)
+		return nil, fmt.Errorf("no IP addresses found for target %s", target)
 	}
 
 	// Use the first resolved IP
@@ -97,10 +113,11 @@ func HandlePing(ctx context.Context, request mcp.CallToolRequest, config *Config
 	}
 
 	// Perform ping
-	pingResponse, err := performPing(ctxWithTimeout, params.Target, parsedIP, count, config.Timeout)
+	pingResponse, err := performPing(ctxWithTimeout, target, parsedIP, count, config.Timeout)
 	if err != nil {
 		return nil, fmt.Errorf("ping failed: %w", err)
 	}
+	pingResponse.DomainName = name
 
 	// Use the response package to handle JSON encoding and MCP tool result creation
 	return resp.JSON(pingResponse)
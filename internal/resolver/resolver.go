@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/domainname"
 	resp "github.com/patrickdappollonio/mcp-domaintools/internal/response"
 	"github.com/patrickdappollonio/mcp-domaintools/internal/utils"
 )
@@ -35,6 +36,16 @@ func HandleHostnameResolution(ctx context.Context, request mcp.CallToolRequest,
 		return nil, fmt.Errorf("parameter \"hostname\" is required")
 	}
 
+	// Validate and normalize the hostname, unless it's an IP address
+	var name *domainname.Name
+	if net.ParseIP(params.Hostname) == nil {
+		var err error
+		if name, err = domainname.Parse(params.Hostname); err != nil {
+			return nil, err
+		}
+		params.Hostname = name.ASCII
+	}
+
 	// Set default IP version if not provided
 	if params.IPVersion == "" {
 		params.IPVersion = "ipv4"
@@ -51,6 +62,9 @@ func HandleHostnameResolution(ctx context.Context, request mcp.CallToolRequest,
 		"ip_version": params.IPVersion,
 		"failed":     false,
 	}
+	if name != nil {
+		responseData["domain_name"] = name
+	}
 
 	// Resolve based on IP version
 	switch params.IPVersion {
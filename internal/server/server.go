@@ -9,6 +9,7 @@ import (
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/miekg/dns"
 	internaldns "github.com/patrickdappollonio/mcp-domaintools/internal/dns"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/email"
 	"github.com/patrickdappollonio/mcp-domaintools/internal/http_ping"
 	"github.com/patrickdappollonio/mcp-domaintools/internal/ping"
 	"github.com/patrickdappollonio/mcp-domaintools/internal/resolver"
@@ -24,6 +25,7 @@ type DomainToolsConfig struct {
 	PingConfig     *ping.Config
 	HTTPPingConfig *http_ping.Config
 	TLSConfig      *tls.Config
+	EmailConfig    *email.Config
 	Version        string
 }
 
@@ -37,6 +39,35 @@ func getDNSRecordTypes() []string {
 	return recordTypes
 }
 
+// queryToolOptions returns the parameters shared by the DNS query tools: the
+// EDNS options sent with the query and the alternative representations of the
+// response message.
+func queryToolOptions() []mcp.ToolOption {
+	return []mcp.ToolOption{
+		mcp.WithBoolean("dnssec_ok",
+			mcp.Description("Set the DNSSEC OK (DO) bit to request DNSSEC records in the response"),
+		),
+		mcp.WithNumber("udp_size",
+			mcp.Description("EDNS UDP buffer size advertised to the server, between 512 and 65535; defaults to 4096 when any EDNS option is set"),
+		),
+		mcp.WithString("client_subnet",
+			mcp.Description("EDNS Client Subnet to send, as a CIDR prefix (e.g., 203.0.113.0/24) or an address, which uses a /24 for IPv4 and a /56 for IPv6; useful to debug GeoDNS answers"),
+		),
+		mcp.WithBoolean("nsid",
+			mcp.Description("Request the server's name server identifier (NSID), which identifies the anycast instance that answered"),
+		),
+		mcp.WithBoolean("cookie",
+			mcp.Description("Send a DNS client cookie; the server cookie is returned in the decoded EDNS options"),
+		),
+		mcp.WithBoolean("include_text",
+			mcp.Description("Include the full response message in dig-style presentation format"),
+		),
+		mcp.WithBoolean("include_wire",
+			mcp.Description("Include the response message bytes as received, in DNS wire format encoded in base64"),
+		),
+	}
+}
+
 // SetupTools creates and configures the domain query tools.
 func SetupTools(config *DomainToolsConfig) (*server.MCPServer, error) {
 	// Create a new MCP server
@@ -80,9 +111,17 @@ func SetupTools(config *DomainToolsConfig) (*server.MCPServer, error) {
 		}
 	}
 
+	// Initialize email config if not provided
+	if config.EmailConfig == nil {
+		config.EmailConfig = &email.Config{
+			QueryConfig: config.QueryConfig,
+			HTTPTimeout: 10 * time.Second,
+		}
+	}
+
 	// Add local DNS query tool
-	localQueryTool := mcp.NewTool("local_dns_query",
-		mcp.WithDescription("Perform DNS queries using local OS-defined DNS servers"),
+	localQueryTool := mcp.NewTool("local_dns_query", append([]mcp.ToolOption{
+		mcp.WithDescription("Perform DNS queries using local OS-defined DNS servers, or send the query straight to a specific nameserver (like dig @server) to check an authoritative server before delegation or an internal split-horizon resolver"),
 		mcp.WithString("domain",
 			mcp.Required(),
 			mcp.Description("The domain name to query (e.g., example.com)"),
@@ -93,11 +132,18 @@ func SetupTools(config *DomainToolsConfig) (*server.MCPServer, error) {
 			mcp.Enum(dnsRecordTypes...),
 			mcp.DefaultString("A"),
 		),
-	)
+		mcp.WithString("server",
+			mcp.Description("Nameserver to query directly instead of the OS-defined DNS servers: an IP, a hostname or a host:port (port defaults to 53); the response then includes the server, RTT and message size"),
+		),
+		mcp.WithString("protocol",
+			mcp.Description("Protocol used to reach the server; defaults to udp"),
+			mcp.Enum("udp", "tcp"),
+		),
+	}, queryToolOptions()...)...)
 
 	// Add remote DNS query tool
-	remoteQueryTool := mcp.NewTool("remote_dns_query",
-		mcp.WithDescription("Perform DNS queries using remote DNS-over-HTTPS servers (Google and Cloudflare)"),
+	remoteQueryTool := mcp.NewTool("remote_dns_query", append([]mcp.ToolOption{
+		mcp.WithDescription("Perform DNS queries using remote encrypted DNS servers: DNS-over-HTTPS (Cloudflare with a Google fallback by default), DNS-over-TLS or DNS-over-QUIC; the response includes the transport and server that answered"),
 		mcp.WithString("domain",
 			mcp.Required(),
 			mcp.Description("The domain name to query (e.g., example.com)"),
@@ -108,6 +154,277 @@ func SetupTools(config *DomainToolsConfig) (*server.MCPServer, error) {
 			mcp.Enum(dnsRecordTypes...),
 			mcp.DefaultString("A"),
 		),
+		mcp.WithString("server",
+			mcp.Description("Remote server to query, overriding the configured one; the scheme selects the transport: https:// for DoH (e.g., https://dns.quad9.net/dns-query), tls:// for DoT (e.g., tls://dns.quad9.net) or quic:// for DoQ (e.g., quic://dns.adguard-dns.com); DoT and DoQ default to port 853"),
+		),
+	}, queryToolOptions()...)...)
+
+	// Add DNS trace tool
+	traceTool := mcp.NewTool("dns_trace",
+		mcp.WithDescription("Follow the delegation chain for a domain from the root servers down to its authoritative servers, similar to \"dig +trace\""),
+		mcp.WithString("domain",
+			mcp.Required(),
+			mcp.Description("The domain name to trace (e.g., example.com)"),
+		),
+		mcp.WithString("record_type",
+			mcp.Description("The type of DNS record to query at the authoritative servers; defaults to A"),
+			mcp.Enum(dnsRecordTypes...),
+			mcp.DefaultString("A"),
+		),
+	)
+
+	// Add DNSSEC validation tool
+	dnssecTool := mcp.NewTool("dnssec_validate",
+		mcp.WithDescription("Validate the DNSSEC chain of trust from the root zone down to a domain, reporting per-zone status, keys, DS digests and signature expiry"),
+		mcp.WithString("domain",
+			mcp.Required(),
+			mcp.Description("The domain name to validate (e.g., example.com)"),
+		),
+		mcp.WithString("record_type",
+			mcp.Description("The type of DNS record whose signatures should be validated; defaults to A"),
+			mcp.Enum(dnsRecordTypes...),
+			mcp.DefaultString("A"),
+		),
+	)
+
+	// Add DNS propagation check tool
+	propagationTool := mcp.NewTool("dns_propagation_check",
+		mcp.WithDescription("Send the same DNS query to several resolvers at once (system, plain UDP/TCP, or DNS-over-HTTPS) and report which resolvers agree on the answer"),
+		mcp.WithString("domain",
+			mcp.Required(),
+			mcp.Description("The domain name to query (e.g., example.com)"),
+		),
+		mcp.WithString("record_type",
+			mcp.Description("The type of DNS record to query (supports all standard DNS record types); defaults to A"),
+			mcp.Enum(dnsRecordTypes...),
+			mcp.DefaultString("A"),
+		),
+		mcp.WithArray("resolvers",
+			mcp.Description("Resolvers to query: \"system\" for the OS-defined servers, an IP or host:port for UDP, tcp://host[:port], udp://host[:port], tls://host[:port] for DNS-over-TLS, quic://host[:port] for DNS-over-QUIC, or an https:// DNS-over-HTTPS URL; defaults to the system resolvers plus Google, Cloudflare, Quad9 and OpenDNS"),
+			mcp.WithStringItems(),
+		),
+	)
+
+	// Add authoritative nameserver audit tool
+	nameserverAuditTool := mcp.NewTool("nameserver_audit",
+		mcp.WithDescription("Query every authoritative nameserver of a zone directly over IPv4 and IPv6 and compare SOA serials, NS sets and a chosen record type, flagging servers that are out of sync, lame, unreachable or not authoritative"),
+		mcp.WithString("domain",
+			mcp.Required(),
+			mcp.Description("The zone or domain name to audit (e.g., example.com)"),
+		),
+		mcp.WithString("record_type",
+			mcp.Description("The type of DNS record to compare across the authoritative servers; defaults to A"),
+			mcp.Enum(dnsRecordTypes...),
+			mcp.DefaultString("A"),
+		),
+	)
+
+	// Add zone transfer tool
+	zoneTransferTool := mcp.NewTool("dns_zone_transfer",
+		mcp.WithDescription("Request a full (AXFR) or incremental (IXFR) zone transfer from a primary nameserver, optionally signed with a TSIG key, and return the transferred records"),
+		mcp.WithString("zone",
+			mcp.Required(),
+			mcp.Description("The zone to transfer (e.g., example.com)"),
+		),
+		mcp.WithString("server",
+			mcp.Required(),
+			mcp.Description("The primary nameserver to transfer from, as a hostname, IP address, or host:port (e.g., ns1.example.com or 192.0.2.53:53)"),
+		),
+		mcp.WithString("transfer_type",
+			mcp.Description("The type of transfer to request; defaults to AXFR"),
+			mcp.Enum("AXFR", "IXFR"),
+			mcp.DefaultString("AXFR"),
+		),
+		mcp.WithNumber("serial",
+			mcp.Description("The SOA serial the secondary already has; required for IXFR"),
+		),
+		mcp.WithNumber("max_records",
+			mcp.Description("Maximum number of records to return; defaults to 10000"),
+			mcp.DefaultNumber(10000),
+		),
+		mcp.WithString("tsig_key_name",
+			mcp.Description("Name of the TSIG key used to sign the transfer request"),
+		),
+		mcp.WithString("tsig_algorithm",
+			mcp.Description("TSIG algorithm; defaults to hmac-sha256"),
+			mcp.Enum("hmac-sha1", "hmac-sha224", "hmac-sha256", "hmac-sha384", "hmac-sha512"),
+			mcp.DefaultString("hmac-sha256"),
+		),
+		mcp.WithString("tsig_secret",
+			mcp.Description("Base64-encoded TSIG secret; required when tsig_key_name is set"),
+		),
+	)
+
+	// Add reverse DNS lookup tool
+	reverseLookupTool := mcp.NewTool("reverse_dns_lookup",
+		mcp.WithDescription("Look up the PTR records for an IPv4 or IPv6 address, or for every address in a small CIDR range, building the in-addr.arpa or ip6.arpa names automatically"),
+		mcp.WithString("target",
+			mcp.Required(),
+			mcp.Description("An IP address or CIDR range of at most 256 addresses (e.g., 192.0.2.1, 2001:db8::1, or 192.0.2.0/24)"),
+		),
+	)
+
+	// Add SPF analysis tool
+	spfAnalyzeTool := mcp.NewTool("spf_analyze",
+		mcp.WithDescription("Fetch a domain's SPF record and recursively expand every include, redirect, a, mx, ptr and exists mechanism, counting DNS lookups against the RFC 7208 limit of 10, flagging void lookups, duplicate records and syntax errors, and returning the full tree and the flattened list of authorized IP ranges"),
+		mcp.WithString("domain",
+			mcp.Required(),
+			mcp.Description("The domain whose SPF record to analyze (e.g., example.com)"),
+		),
+	)
+
+	// Add SPF check tool
+	spfCheckTool := mcp.NewTool("spf_check",
+		mcp.WithDescription("Run the RFC 7208 check_host() algorithm for a client IP, MAIL FROM address and HELO name, returning pass, fail, softfail, neutral, none, permerror or temperror along with the mechanism that matched and every evaluated step"),
+		mcp.WithString("ip",
+			mcp.Required(),
+			mcp.Description("The IPv4 or IPv6 address of the SMTP client that sent the message"),
+		),
+		mcp.WithString("mail_from",
+			mcp.Description("The MAIL FROM (envelope sender) address or domain (e.g., bounces@example.com); leave empty for null reverse-paths to check the HELO identity"),
+		),
+		mcp.WithString("helo",
+			mcp.Description("The HELO or EHLO name presented by the client; required when mail_from is empty"),
+		),
+	)
+
+	// Add email authentication records tool
+	emailAuthRecordsTool := mcp.NewTool("email_auth_records",
+		mcp.WithDescription("Fetch and parse the DMARC, DKIM and BIMI records of a domain, returning the policy, percentage, report destinations, alignment modes, key types and key sizes with validation errors, and checking that external DMARC report destinations have authorized receiving reports"),
+		mcp.WithString("domain",
+			mcp.Required(),
+			mcp.Description("The domain whose records to inspect (e.g., example.com)"),
+		),
+		mcp.WithArray("selectors",
+			mcp.Description("DKIM selectors to look up (e.g., [\"google\", \"selector1\"]); when omitted, a list of common selectors is probed and only those found are returned"),
+			mcp.WithStringItems(),
+		),
+	)
+
+	// Add MTA-STS check tool
+	mtaSTSTool := mcp.NewTool("mta_sts_check",
+		mcp.WithDescription("Check a domain's MTA-STS deployment: validate the _mta-sts TXT record, fetch and parse the policy from https://mta-sts.<domain>/.well-known/mta-sts.txt, confirm that every MX host matches the policy's mx patterns, and validate the _smtp._tls TLS-RPT record"),
+		mcp.WithString("domain",
+			mcp.Required(),
+			mcp.Description("The mail domain to check (e.g., example.com)"),
+		),
+	)
+
+	// Add DNS snapshot tool
+	dnsSnapshotTool := mcp.NewTool("dns_snapshot",
+		mcp.WithDescription("Take a snapshot of a domain by querying it concurrently for A, AAAA, CNAME, MX, NS, SOA, TXT, CAA, SRV, HTTPS, SVCB, DS and DNSKEY records, plus A, AAAA, CNAME, MX, TXT and HTTPS records of well-known subdomains, returning one merged report and optionally a zone file fragment"),
+		mcp.WithString("domain",
+			mcp.Required(),
+			mcp.Description("The domain name to snapshot (e.g., example.com)"),
+		),
+		mcp.WithArray("subdomains",
+			mcp.Description("Subdomain labels to include, relative to the domain (e.g., www, _dmarc); defaults to www, mail, _dmarc, _mta-sts, mta-sts and _smtp._tls, and an empty list skips them"),
+			mcp.WithStringItems(),
+		),
+		mcp.WithBoolean("zone_file",
+			mcp.Description("Also render the records found as a zone file fragment"),
+		),
+	)
+
+	// Add CNAME chain tool
+	cnameChainTool := mcp.NewTool("cname_chain",
+		mcp.WithDescription("Follow a name through every CNAME and DNAME hop to its final A and AAAA records, showing each hop's TTL as cached by the recursive resolver that answered (not the zone's own TTL), the lowest TTL limiting the chain, and flagging loops, chains that are too long, dangling CNAMEs, CNAMEs at the zone apex and CNAMEs that coexist with other data"),
+		mcp.WithString("domain",
+			mcp.Required(),
+			mcp.Description("The name to follow (e.g., www.example.com)"),
+		),
+	)
+
+	// Add delegation check tool
+	delegationCheckTool := mcp.NewTool("delegation_check",
+		mcp.WithDescription("Compare the NS records and glue served by the parent zone's servers (e.g., the TLD) with the NS records served by the child zone's own authoritative servers, flagging NS set mismatches, missing or stale glue, in-bailiwick nameservers without glue, and servers that don't respond or don't answer authoritatively"),
+		mcp.WithString("domain",
+			mcp.Required(),
+			mcp.Description("The zone whose delegation to check (e.g., example.com)"),
+		),
+	)
+
+	// Add zone lint tool
+	zoneLintTool := mcp.NewTool("zone_lint",
+		mcp.WithDescription("Parse zone file text (RFC 1035 master file format) and report syntax errors with line numbers, plus semantic problems such as CNAME conflicts, missing SOA or NS records, out-of-zone data, MX, NS or SRV targets that are CNAMEs, missing glue and TTL outliers; the parsed records are returned in the same format as the DNS query tools"),
+		mcp.WithString("zone",
+			mcp.Required(),
+			mcp.Description("The zone file contents to lint"),
+		),
+		mcp.WithString("origin",
+			mcp.Description("The zone origin (e.g., example.com), used for relative names when the zone file has no $ORIGIN directive; defaults to the owner of the SOA record"),
+		),
+	)
+
+	// Add zone compare tool
+	zoneCompareTool := mcp.NewTool("zone_compare",
+		mcp.WithDescription("Compare live DNS against an intended zone file: query every RRset in the zone file and report missing, extra and different records, including TTL differences, on each authoritative server separately, to verify a deployment against the zone kept in source control"),
+		mcp.WithString("zone",
+			mcp.Required(),
+			mcp.Description("The zone file contents with the intended records"),
+		),
+		mcp.WithString("origin",
+			mcp.Description("The zone origin (e.g., example.com), used for relative names when the zone file has no $ORIGIN directive; defaults to the owner of the SOA record"),
+		),
+		mcp.WithString("server",
+			mcp.Description("Where to query the records: 'authoritative' for the zone's own nameservers, 'system' for the local resolvers, a server IP or host with an optional port (e.g., 192.0.2.53), or a resolver URL such as https://cloudflare-dns.com/dns-query (DoH), tls:// (DoT) or quic:// (DoQ); defaults to 'authoritative'"),
+			mcp.DefaultString("authoritative"),
+		),
+	)
+
+	// Add resolver config tool
+	resolverConfigTool := mcp.NewTool("resolver_config",
+		mcp.WithDescription("Report what the host uses for name resolution: nameservers, search domains, ndots, timeout and attempts options from resolv.conf, the nsswitch hosts order, /etc/hosts entries matching a name, and which DNS servers the other tools pick when querying the system resolvers and why. Useful when 'DNS is broken' turns out to be a search domain or hosts file problem"),
+		mcp.WithString("name",
+			mcp.Description("Optional hostname to look up in /etc/hosts and to expand with the search domains, showing the names the system resolver queries in order (e.g., db or api.example.com)"),
+		),
+	)
+
+	// Add CAA check tool
+	caaCheckTool := mcp.NewTool("caa_check",
+		mcp.WithDescription("Evaluate the CAA policy of a name the way a CA must before issuing: walk from the name up through its parents to the closest CAA records, parse the issue, issuewild and iodef properties with their parameters, and report which CAs may issue regular and wildcard certificates. Optionally connects to the server to check that the issuer of the certificate currently served is allowed"),
+		mcp.WithString("domain",
+			mcp.Required(),
+			mcp.Description("The name to check (e.g., www.example.com); prefix it with *. to check wildcard issuance (e.g., *.example.com)"),
+		),
+		mcp.WithBoolean("check_certificate",
+			mcp.Description("Whether to connect to the server and check the issuer of its current certificate against the CAA policy; defaults to false"),
+		),
+		mcp.WithNumber("port",
+			mcp.Description("Port to connect to when checking the certificate; defaults to 443"),
+			mcp.DefaultNumber(443),
+		),
+	)
+
+	// Add DANE check tool
+	daneCheckTool := mcp.NewTool("dane_check",
+		mcp.WithDescription("Check DANE for a TLS service: fetch the TLSA records at _port._tcp.host, validate them with DNSSEC, connect to the server (using STARTTLS for SMTP) and check every TLSA record's usage, selector and matching type against the certificate chain the server presents. Reports which records match and suggests records for the current certificate, to confirm TLSA records still match after a certificate rotation"),
+		mcp.WithString("host",
+			mcp.Required(),
+			mcp.Description("The host name of the service (e.g., mail.example.com)"),
+		),
+		mcp.WithNumber("port",
+			mcp.Description("The TCP port of the service (e.g., 25 for SMTP); defaults to 443"),
+			mcp.DefaultNumber(443),
+		),
+		mcp.WithString("starttls",
+			mcp.Description("Whether to upgrade a plain text connection with STARTTLS: 'auto' uses SMTP STARTTLS on ports 25 and 587, 'smtp' always does and 'none' starts with a TLS handshake; defaults to 'auto'"),
+			mcp.Enum("auto", "smtp", "none"),
+			mcp.DefaultString("auto"),
+		),
+	)
+
+	// Add HTTPS record check tool
+	httpsRecordCheckTool := mcp.NewTool("https_record_check",
+		mcp.WithDescription("Decode the HTTPS records (RFC 9460) of an origin, following AliasMode records, including priority, target, alpn, port, address hints and the ECH configs (public name, KEM, KDF and AEAD suites, config ID). Then connect to each endpoint to check that every advertised protocol is negotiated (h3 over QUIC), that the address hints serve the origin and that the ECH configs are accepted, and compare the advertised HTTP/3 support with the origin's Alt-Svc header"),
+		mcp.WithString("domain",
+			mcp.Required(),
+			mcp.Description("The origin host name to check (e.g., example.com)"),
+		),
+		mcp.WithNumber("port",
+			mcp.Description("The HTTPS port of the origin; ports other than 443 are looked up at _port._https.domain. Defaults to 443"),
+			mcp.DefaultNumber(443),
+		),
 	)
 
 	// Add WHOIS query tool
@@ -195,6 +512,82 @@ func SetupTools(config *DomainToolsConfig) (*server.MCPServer, error) {
 		return internaldns.HandleRemoteDNSQuery(ctx, request, config.QueryConfig)
 	}
 
+	traceHandler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return internaldns.HandleDNSTrace(ctx, request, config.QueryConfig)
+	}
+
+	dnssecHandler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return internaldns.HandleDNSSECValidation(ctx, request, config.QueryConfig)
+	}
+
+	propagationHandler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return internaldns.HandlePropagationCheck(ctx, request, config.QueryConfig)
+	}
+
+	nameserverAuditHandler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return internaldns.HandleNameserverAudit(ctx, request, config.QueryConfig)
+	}
+
+	zoneTransferHandler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return internaldns.HandleZoneTransfer(ctx, request, config.QueryConfig)
+	}
+
+	reverseLookupHandler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return internaldns.HandleReverseLookup(ctx, request, config.QueryConfig)
+	}
+
+	spfAnalyzeHandler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return email.HandleSPFAnalysis(ctx, request, config.EmailConfig)
+	}
+
+	spfCheckHandler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return email.HandleSPFCheck(ctx, request, config.EmailConfig)
+	}
+
+	emailAuthRecordsHandler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return email.HandleEmailAuthRecords(ctx, request, config.EmailConfig)
+	}
+
+	mtaSTSHandler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return email.HandleMTASTS(ctx, request, config.EmailConfig)
+	}
+
+	dnsSnapshotHandler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return internaldns.HandleDNSSnapshot(ctx, request, config.QueryConfig)
+	}
+
+	cnameChainHandler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return internaldns.HandleCNAMEChain(ctx, request, config.QueryConfig)
+	}
+
+	delegationCheckHandler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return internaldns.HandleDelegationCheck(ctx, request, config.QueryConfig)
+	}
+
+	zoneLintHandler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return internaldns.HandleZoneLint(ctx, request, config.QueryConfig)
+	}
+
+	zoneCompareHandler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return internaldns.HandleZoneCompare(ctx, request, config.QueryConfig)
+	}
+
+	resolverConfigHandler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return internaldns.HandleResolverConfig(ctx, request, config.QueryConfig)
+	}
+
+	caaCheckHandler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return internaldns.HandleCAACheck(ctx, request, config.QueryConfig)
+	}
+
+	daneCheckHandler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return internaldns.HandleDANECheck(ctx, request, config.QueryConfig)
+	}
+
+	httpsRecordCheckHandler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return internaldns.HandleHTTPSRecordCheck(ctx, request, config.QueryConfig)
+	}
+
 	whoisHandler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return whois.HandleWhoisQuery(ctx, request, config.WhoisConfig)
 	}
@@ -218,6 +611,25 @@ func SetupTools(config *DomainToolsConfig) (*server.MCPServer, error) {
 	// Add handlers for the tools
 	s.AddTool(localQueryTool, localDNSHandler)
 	s.AddTool(remoteQueryTool, remoteDNSHandler)
+	s.AddTool(traceTool, traceHandler)
+	s.AddTool(dnssecTool, dnssecHandler)
+	s.AddTool(propagationTool, propagationHandler)
+	s.AddTool(nameserverAuditTool, nameserverAuditHandler)
+	s.AddTool(zoneTransferTool, zoneTransferHandler)
+	s.AddTool(reverseLookupTool, reverseLookupHandler)
+	s.AddTool(spfAnalyzeTool, spfAnalyzeHandler)
+	s.AddTool(spfCheckTool, spfCheckHandler)
+	s.AddTool(emailAuthRecordsTool, emailAuthRecordsHandler)
+	s.AddTool(mtaSTSTool, mtaSTSHandler)
+	s.AddTool(dnsSnapshotTool, dnsSnapshotHandler)
+	s.AddTool(cnameChainTool, cnameChainHandler)
+	s.AddTool(delegationCheckTool, delegationCheckHandler)
+	s.AddTool(zoneLintTool, zoneLintHandler)
+	s.AddTool(zoneCompareTool, zoneCompareHandler)
+	s.AddTool(resolverConfigTool, resolverConfigHandler)
+	s.AddTool(caaCheckTool, caaCheckHandler)
+	s.AddTool(daneCheckTool, daneCheckHandler)
+	s.AddTool(httpsRecordCheckTool, httpsRecordCheckHandler)
 	s.AddTool(whoisQueryTool, whoisHandler)
 	s.AddTool(resolveHostTool, resolveHostHandler)
 	s.AddTool(pingTool, pingHandler)
@@ -8,13 +8,16 @@ import (
 	"crypto/x509"
 	"fmt"
 	"net"
+	"net/smtp"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/domainname"
 	resp "github.com/patrickdappollonio/mcp-domaintools/internal/response"
 	"github.com/patrickdappollonio/mcp-domaintools/internal/utils"
+	"github.com/quic-go/quic-go"
 )
 
 // Config holds TLS certificate checking configuration.
@@ -59,6 +62,7 @@ type CertificateInfo struct {
 // CheckResult represents the result of a TLS certificate check.
 type CheckResult struct {
 	Domain           string            `json:"domain"`
+	DomainName       *domainname.Name  `json:"domain_name,omitempty"`
 	Port             int               `json:"port"`
 	ServerName       string            `json:"server_name"`
 	TLSVersion       string            `json:"tls_version"`
@@ -83,6 +87,19 @@ func HandleTLSCheck(_ context.Context, request mcp.CallToolRequest, config *Conf
 		return nil, fmt.Errorf("parameter \"domain\" is required")
 	}
 
+	// Validate and normalize the domain name, unless it's an IP address
+	var name *domainname.Name
+	domain := strings.TrimSpace(params.Domain)
+	if ip := net.ParseIP(strings.Trim(domain, "[]")); ip != nil {
+		domain = ip.String()
+	} else {
+		var err error
+		if name, err = domainname.Parse(domain); err != nil {
+			return nil, err
+		}
+		domain = name.ASCII
+	}
+
 	// Set default port if not specified
 	port := params.Port
 	if port == 0 {
@@ -92,7 +109,7 @@ func HandleTLSCheck(_ context.Context, request mcp.CallToolRequest, config *Conf
 	// Use domain as server name if not specified
 	serverName := params.ServerName
 	if serverName == "" {
-		serverName = params.Domain
+		serverName = domain
 	}
 
 	// Set default values for optional parameters
@@ -107,16 +124,12 @@ func HandleTLSCheck(_ context.Context, request mcp.CallToolRequest, config *Conf
 		params.CheckExpiry = &checkExpiry
 	}
 
-	// Validate domain format
-	if strings.Contains(params.Domain, "..") || strings.HasPrefix(params.Domain, ".") {
-		return nil, fmt.Errorf("invalid domain format: %q", params.Domain)
-	}
-
 	// Perform the TLS check
-	result, err := checkTLSCertificate(params.Domain, port, serverName, config, params)
+	result, err := checkTLSCertificate(domain, port, serverName, config, params)
 	if err != nil {
 		return nil, fmt.Errorf("TLS check failed: %w", err)
 	}
+	result.DomainName = name
 
 	return resp.JSON(result)
 }
@@ -147,22 +160,10 @@ func checkTLSCertificate(domain string, port int, serverName string, config *Con
 
 // attemptTLSConnection attempts to connect and retrieve certificate information.
 func attemptTLSConnection(domain string, port int, serverName string, config *Config, params tlsCheckParams, forceSkipVerify bool) (*CheckResult, error) {
-	// Create TLS configuration
-	tlsConfig := &tls.Config{
-		ServerName:         serverName,
-		InsecureSkipVerify: forceSkipVerify,
-	}
-
-	// Create a dialer with the context
-	dialer := &net.Dialer{
-		Timeout: config.Timeout,
-	}
-
 	// Connect to the server
-	address := net.JoinHostPort(domain, strconv.Itoa(port))
-	conn, err := tls.DialWithDialer(dialer, "tcp", address, tlsConfig)
+	conn, err := dialTLS(context.Background(), domain, port, serverName, config.Timeout, forceSkipVerify)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to %s: %w", address, err)
+		return nil, err
 	}
 	defer func() {
 		_ = conn.Close()
@@ -279,6 +280,169 @@ func attemptTLSConnection(domain string, port int, serverName string, config *Co
 	return result, nil
 }
 
+// FetchCertificates connects to a TLS server and returns the certificates it
+// presents, starting with the server certificate. The certificates are not
+// verified, so expired or untrusted ones can be inspected too.
+func FetchCertificates(ctx context.Context, host string, port int, serverName string, timeout time.Duration) ([]*x509.Certificate, error) {
+	conn, err := dialTLS(ctx, host, port, serverName, timeout, true)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	certificates := conn.ConnectionState().PeerCertificates
+	if len(certificates) == 0 {
+		return nil, fmt.Errorf("server %s presented no certificates", net.JoinHostPort(host, strconv.Itoa(port)))
+	}
+
+	return certificates, nil
+}
+
+// FetchSMTPCertificates connects to an SMTP server, upgrades the connection
+// with STARTTLS (RFC 3207) and returns the certificates the server presents,
+// starting with the server certificate. The certificates are not verified.
+func FetchSMTPCertificates(ctx context.Context, host string, port int, serverName string, timeout time.Duration) ([]*x509.Certificate, error) {
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", address, err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	// The SMTP client has no timeouts of its own, so bound the whole exchange
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+
+	client, err := smtp.NewClient(conn, serverName)
+	if err != nil {
+		return nil, fmt.Errorf("SMTP greeting from %s failed: %w", address, err)
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+
+	if ok, _ := client.Extension("STARTTLS"); !ok {
+		return nil, fmt.Errorf("SMTP server %s does not offer STARTTLS", address)
+	}
+
+	if err := client.StartTLS(&tls.Config{ServerName: serverName, InsecureSkipVerify: true}); err != nil {
+		return nil, fmt.Errorf("STARTTLS with %s failed: %w", address, err)
+	}
+
+	state, _ := client.TLSConnectionState()
+	_ = client.Quit()
+
+	if len(state.PeerCertificates) == 0 {
+		return nil, fmt.Errorf("server %s presented no certificates", address)
+	}
+
+	return state.PeerCertificates, nil
+}
+
+// HandshakeResult describes the parameters negotiated in a TLS handshake.
+type HandshakeResult struct {
+	Version            string `json:"version"`
+	NegotiatedProtocol string `json:"negotiated_protocol,omitempty"`
+	ECHAccepted        bool   `json:"ech_accepted"`
+}
+
+// Handshake connects to a TLS server offering the given application protocols
+// (ALPN) and reports what the server negotiated. When an ECHConfigList is
+// given, the handshake uses Encrypted Client Hello and fails with a
+// *tls.ECHRejectionError if the server rejects it. Certificates are not
+// verified.
+func Handshake(ctx context.Context, host string, port int, serverName string, protocols []string, echConfigList []byte, timeout time.Duration) (*HandshakeResult, error) {
+	tlsConfig := &tls.Config{
+		ServerName:                     serverName,
+		NextProtos:                     protocols,
+		EncryptedClientHelloConfigList: echConfigList,
+		InsecureSkipVerify:             true,
+	}
+
+	// ECH is only defined for TLS 1.3
+	if echConfigList != nil {
+		tlsConfig.MinVersion = tls.VersionTLS13
+	}
+
+	conn, err := dialWithConfig(ctx, host, port, tlsConfig, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	return handshakeResult(conn.ConnectionState()), nil
+}
+
+// QUICHandshake performs a QUIC handshake with the server offering the given
+// application protocols, the way HTTP/3 clients connect, and reports what the
+// server negotiated. Certificates are not verified.
+func QUICHandshake(ctx context.Context, host string, port int, serverName string, protocols []string, timeout time.Duration) (*HandshakeResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	tlsConfig := &tls.Config{
+		ServerName:         serverName,
+		NextProtos:         protocols,
+		InsecureSkipVerify: true,
+	}
+
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	conn, err := quic.DialAddr(ctx, address, tlsConfig, &quic.Config{HandshakeIdleTimeout: timeout})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s over QUIC: %w", address, err)
+	}
+	defer func() {
+		_ = conn.CloseWithError(0, "")
+	}()
+
+	return handshakeResult(conn.ConnectionState().TLS), nil
+}
+
+// handshakeResult extracts the negotiated parameters from a connection state.
+func handshakeResult(state tls.ConnectionState) *HandshakeResult {
+	return &HandshakeResult{
+		Version:            getTLSVersion(state.Version),
+		NegotiatedProtocol: state.NegotiatedProtocol,
+		ECHAccepted:        state.ECHAccepted,
+	}
+}
+
+// dialTLS opens a TLS connection to the host and port, using the server name for SNI.
+func dialTLS(ctx context.Context, host string, port int, serverName string, timeout time.Duration, skipVerify bool) (*tls.Conn, error) {
+	// Create TLS configuration
+	tlsConfig := &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: skipVerify,
+	}
+
+	return dialWithConfig(ctx, host, port, tlsConfig, timeout)
+}
+
+// dialWithConfig opens a TLS connection to the host and port using the given
+// TLS configuration.
+func dialWithConfig(ctx context.Context, host string, port int, tlsConfig *tls.Config, timeout time.Duration) (*tls.Conn, error) {
+	// Create a dialer with the timeout
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: timeout},
+		Config:    tlsConfig,
+	}
+
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", address, err)
+	}
+
+	return conn.(*tls.Conn), nil
+}
+
 // isCertificateValidationError checks if the error is related to certificate validation.
 func isCertificateValidationError(err error) bool {
 	errStr := err.Error()
@@ -3,10 +3,10 @@ package whois
 import (
 	"context"
 	"fmt"
-	"strings"
 
 	"github.com/likexian/whois"
 	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/domainname"
 	resp "github.com/patrickdappollonio/mcp-domaintools/internal/response"
 	"github.com/patrickdappollonio/mcp-domaintools/internal/utils"
 )
@@ -33,14 +33,14 @@ func HandleWhoisQuery(ctx context.Context, request mcp.CallToolRequest, config *
 		return nil, fmt.Errorf("parameter \"domain\" is required")
 	}
 
-	// Clean and validate domain format
-	domain := strings.TrimSpace(params.Domain)
-	if strings.Contains(domain, "..") || strings.HasPrefix(domain, ".") {
-		return nil, fmt.Errorf("invalid domain format: %q", domain)
+	// Validate and normalize the domain name
+	name, err := domainname.Parse(params.Domain)
+	if err != nil {
+		return nil, err
 	}
+	domain := name.ASCII
 
 	var result string
-	var err error
 
 	// Use custom server if provided, otherwise use default
 	if config.CustomServer != "" {
@@ -55,8 +55,9 @@ func HandleWhoisQuery(ctx context.Context, request mcp.CallToolRequest, config *
 
 	// Format response as JSON using the response package
 	responseData := map[string]interface{}{
-		"domain": domain,
-		"result": result,
+		"domain":      domain,
+		"domain_name": name,
+		"result":      result,
 	}
 
 	return resp.JSON(responseData)
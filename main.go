@@ -12,6 +12,7 @@ import (
 
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/patrickdappollonio/mcp-domaintools/internal/dns"
+	"github.com/patrickdappollonio/mcp-domaintools/internal/email"
 	"github.com/patrickdappollonio/mcp-domaintools/internal/http_ping"
 	"github.com/patrickdappollonio/mcp-domaintools/internal/ping"
 	internalServer "github.com/patrickdappollonio/mcp-domaintools/internal/server"
@@ -42,7 +43,7 @@ func main() {
 }
 
 func run() error {
-	flag.StringVar(&remoteServerAddress, "remote-server-address", "", "Custom DNS-over-HTTPS server address")
+	flag.StringVar(&remoteServerAddress, "remote-server-address", "", "Custom remote DNS server: https:// (DoH), tls:// (DoT) or quic:// (DoQ)")
 	flag.StringVar(&customWhoisServer, "custom-whois-server", "", "Custom WHOIS server address")
 	flag.BoolVar(&enableSSEServer, "sse", false, "Enable SSE server mode")
 	flag.IntVar(&sseServerPort, "sse-port", 3000, "SSE server port (if SSE server mode is enabled)")
@@ -84,6 +85,12 @@ func run() error {
 		Port:    443,
 	}
 
+	// Create email authentication configuration
+	emailConfig := &email.Config{
+		QueryConfig: queryConfig,
+		HTTPTimeout: httpPingTimeout,
+	}
+
 	// Setup domain tools
 	s, err := internalServer.SetupTools(&internalServer.DomainToolsConfig{
 		QueryConfig:    queryConfig,
@@ -91,6 +98,7 @@ func run() error {
 		PingConfig:     pingConfig,
 		HTTPPingConfig: httpPingConfig,
 		TLSConfig:      tlsConfig,
+		EmailConfig:    emailConfig,
 		Version:        version,
 	})
 	if err != nil {